@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+var (
+	REPackageDecl = regexp.MustCompile(`^package\s+([\w.]+)\s*;`)
+	REImportDecl  = regexp.MustCompile(
+		`^import\s+(static\s+)?([\w.]+?)(\.\*)?\s*;`)
+)
+
+// SourceFile is a parsed Java source file
+type SourceFile struct {
+	Path    string
+	Package string
+	Imports []index.JavaClass // wildcard imports name a package member "*"
+}
+
+// Class is the fully qualified class name derived from the file name
+func (a SourceFile) Class() string {
+	n := strings.TrimSuffix(filepath.Base(a.Path), ".java")
+	if a.Package == "" {
+		return n
+	}
+	return a.Package + "." + n
+}
+
+// parse package and import declarations of a Java source file
+func parseSource(filename string) (SourceFile, error) {
+	sf := SourceFile{Path: filename}
+	f, err := os.Open(filename)
+	if err != nil {
+		return sf, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if matches := REPackageDecl.FindStringSubmatch(line); matches != nil {
+			sf.Package = matches[1]
+		} else if matches := REImportDecl.FindStringSubmatch(line); matches != nil {
+			name := matches[2]
+			if matches[1] != "" && matches[3] == "" {
+				// Convert Java member to class
+				name = index.StripLast(name)
+			}
+			if matches[3] != "" {
+				name += ".*"
+			}
+			sf.Imports = append(sf.Imports, index.JavaClass{Name: name})
+		} else if strings.HasPrefix(line, "public ") ||
+			strings.HasPrefix(line, "class ") {
+			// imports precede type declarations
+			break
+		}
+	}
+	return sf, scanner.Err()
+}
+
+// label to depend on for a dependency
+func depLabel(d index.Dependency) string {
+	switch {
+	case d.Kind.External():
+		return thirdParty(d).Actual
+	case strings.HasPrefix(d.Name, "//") || strings.HasPrefix(d.Name, "@"):
+		return d.Name
+	}
+	return "//:" + d.Name
+}
+
+// resolve imports against the index, skipping the JDK and classes of the
+// given own packages
+func resolveImports(imports []index.JavaClass, own map[string]bool,
+	deps []index.Dependency) []string {
+	labels := make(map[string]bool)
+	for _, j := range imports {
+		if strings.HasPrefix(j.Name, "java.") || own[j.Package()] {
+			continue
+		}
+		// wildcard imports resolve on package level
+		d, _ := index.FindClass(j, deps)
+		if d == nil {
+			log.Printf("cannot resolve import %s\n", j.Name)
+			continue
+		}
+		labels[depLabel(*d)] = true
+	}
+	var ls []string
+	for l := range labels {
+		ls = append(ls, l)
+	}
+	sort.Strings(ls)
+	return ls
+}
+
+func parseSources(dir string) []SourceFile {
+	var sfs []SourceFile
+	for _, f := range index.Scan(dir, ".java") {
+		sf, err := parseSource(f)
+		if err != nil {
+			log.Printf("skipping %s: %v\n", f, err)
+			continue
+		}
+		sfs = append(sfs, sf)
+	}
+	return sfs
+}
+
+func starlarkList(indent string, items []string) string {
+	if len(items) == 0 {
+		return "[]"
+	}
+	var sb strings.Builder
+	sb.WriteString("[\n")
+	for _, i := range items {
+		fmt.Fprintf(&sb, "%s    %q,\n", indent, i)
+	}
+	sb.WriteString(indent + "]")
+	return sb.String()
+}
+
+// generate a BUILD.bazel file for a Maven style module without one: a library
+// of src/main/java and src/main/resources, and a java_test per test class of
+// src/test/java
+func adopt(dir string, deps []index.Dependency) string {
+	lib := filepath.Base(dir)
+	mains := parseSources(filepath.Join(dir, "src", "main", "java"))
+	tests := parseSources(filepath.Join(dir, "src", "test", "java"))
+	own := make(map[string]bool)
+	for _, sf := range append(append([]SourceFile{}, mains...), tests...) {
+		own[sf.Package] = true
+	}
+
+	var imports []index.JavaClass
+	for _, sf := range mains {
+		imports = append(imports, sf.Imports...)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `java_library(
+    name = %q,
+    srcs = glob(["src/main/java/**/*.java"]),
+`, lib)
+	if canRead(filepath.Join(dir, "src", "main", "resources")) {
+		sb.WriteString(
+			"    resources = glob([\"src/main/resources/**\"]),\n")
+	}
+	fmt.Fprintf(&sb, `    deps = %s,
+    visibility = ["//visibility:public"],
+)
+`, starlarkList("    ", resolveImports(imports, own, deps)))
+
+	sort.Slice(tests, func(i, j int) bool {
+		return tests[i].Path < tests[j].Path
+	})
+	for _, sf := range tests {
+		if !strings.HasSuffix(sf.Class(), "Test") {
+			continue
+		}
+		rel, _ := filepath.Rel(dir, sf.Path)
+		labels := append([]string{":" + lib},
+			resolveImports(sf.Imports, own, deps)...)
+		fmt.Fprintf(&sb, `
+java_test(
+    name = %q,
+    srcs = [%q],
+    test_class = %q,
+    deps = %s,
+)
+`, strings.TrimSuffix(filepath.Base(sf.Path), ".java"),
+			filepath.ToSlash(rel), sf.Class(),
+			starlarkList("    ", labels))
+	}
+	return sb.String()
+}
+
+// write the generated BUILD.bazel, never overwriting an existing BUILD file
+func adoptDir(dir string, deps []index.Dependency) error {
+	for _, f := range []string{"BUILD", "BUILD.bazel"} {
+		if canRead(filepath.Join(dir, f)) {
+			return fmt.Errorf("%s already has a %s file", dir, f)
+		}
+	}
+	filename := filepath.Join(dir, "BUILD.bazel")
+	err := ioutil.WriteFile(filename, []byte(adopt(dir, deps)), 0644)
+	if err != nil {
+		return err
+	}
+	log.Printf("generated %s, please review\n", filename)
+	return own(filename)
+}
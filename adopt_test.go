@@ -0,0 +1,73 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+func TestAdopt(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "billing")
+	fixtureFiles(t, dir, map[string]string{
+		"src/main/java/com/acme/billing/Invoice.java": `package com.acme.billing;
+
+import java.util.List;
+import com.google.common.base.Optional;
+import static com.acme.billing.Money.ZERO;
+import org.company.framework.*;
+
+public class Invoice {}
+`,
+		"src/main/java/com/acme/billing/Money.java": `package com.acme.billing;
+public class Money {}
+`,
+		"src/main/resources/billing.properties": "",
+		"src/test/java/com/acme/billing/InvoiceTest.java": `package com.acme.billing;
+
+import org.junit.Test;
+
+public class InvoiceTest {}
+`,
+	})
+	deps := []index.Dependency{
+		{Name: "//external:junit", Resources: classes("org.junit.Test"),
+			Kind: index.MavenJar},
+		{Name: "//external:guava",
+			Resources: classes("com.google.common.base.Optional"),
+			Kind:      index.MavenJar},
+		{Name: "framework", Resources: classes("org.company.framework.A"),
+			Kind: index.Source},
+	}
+	want := `java_library(
+    name = "billing",
+    srcs = glob(["src/main/java/**/*.java"]),
+    resources = glob(["src/main/resources/**"]),
+    deps = [
+        "//:framework",
+        "@guava//jar",
+    ],
+    visibility = ["//visibility:public"],
+)
+
+java_test(
+    name = "InvoiceTest",
+    srcs = ["src/test/java/com/acme/billing/InvoiceTest.java"],
+    test_class = "com.acme.billing.InvoiceTest",
+    deps = [
+        ":billing",
+        "@junit//jar",
+    ],
+)
+`
+	got := adopt(dir, deps)
+	if want != got {
+		t.Fatalf("want\n%s\nbut got\n%s\n", want, got)
+	}
+	if err := adoptDir(dir, deps); err != nil {
+		t.Fatal(err)
+	}
+	if err := adoptDir(dir, deps); err == nil {
+		t.Fatalf("want existing BUILD.bazel never overwritten\n")
+	}
+}
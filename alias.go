@@ -0,0 +1,26 @@
+package main
+
+import (
+	"log"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+)
+
+// replace deps on actual targets with their aliases
+func withAliases(edits []edit.Edit, aliases map[string]string) []edit.Edit {
+	for i, e := range edits {
+		fs := strings.Fields(e.Command)
+		if len(fs) < 3 || fs[0] != "add" || fs[1] != "deps" {
+			continue
+		}
+		for j := 2; j < len(fs); j++ {
+			if a, ok := aliases[fs[j]]; ok {
+				log.Printf("using alias %s for %s\n", a, fs[j])
+				fs[j] = a
+			}
+		}
+		edits[i].Command = strings.Join(fs, " ")
+	}
+	return edits
+}
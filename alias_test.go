@@ -0,0 +1,23 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+)
+
+func TestWithAliases(t *testing.T) {
+	m := map[string]string{
+		"@maven//:com_google_guava_guava": "//third_party/java/guava:guava",
+		"//ui:ui_web":                     "//ui:web",
+	}
+	edits := withAliases([]edit.Edit{
+		{Command: "add deps //ui:ui_web @maven//:com_google_guava_guava",
+			Target: "//:a"},
+	}, m)
+	got := edits[0].Command
+	wantCmd := "add deps //ui:web //third_party/java/guava:guava"
+	if wantCmd != got {
+		t.Fatalf("want %s but got %s\n", wantCmd, got)
+	}
+}
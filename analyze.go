@@ -0,0 +1,77 @@
+package main
+
+import (
+	"log"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+// Analysis compares the deps a target needs by the imports of its sources
+// with the deps it declares
+type Analysis struct {
+	Target      string
+	Missing     []string // needed, but not declared
+	Superfluous []string // declared and indexed, but nothing imports them
+}
+
+// source file of a label relative to the workspace, //a/b:c/D.java is
+// a/b/c/D.java
+func srcPath(label string) string {
+	s := strings.TrimPrefix(label, "//")
+	if i := strings.Index(s, ":"); i >= 0 {
+		return path.Join(s[:i], s[i+1:])
+	}
+	return s
+}
+
+// compare required and declared deps. Declared deps unknown to the index,
+// such as runtime or annotation processor deps, are never superfluous.
+func analyzeDeps(target string, srcs []SourceFile, declared []string,
+	deps []index.Dependency) Analysis {
+	own := make(map[string]bool)
+	var imports []index.JavaClass
+	for _, sf := range srcs {
+		own[sf.Package] = true
+		imports = append(imports, sf.Imports...)
+	}
+	required := make(map[string]bool)
+	for _, l := range resolveImports(imports, own, deps) {
+		required[l] = true
+	}
+	known := make(map[string]bool)
+	for _, d := range deps {
+		known[depLabel(d)] = true
+	}
+	has := make(map[string]bool)
+	a := Analysis{Target: target}
+	for _, l := range declared {
+		has[l] = true
+		if known[l] && !required[l] && l != target {
+			a.Superfluous = append(a.Superfluous, l)
+		}
+	}
+	for l := range required {
+		if !has[l] && l != target {
+			a.Missing = append(a.Missing, l)
+		}
+	}
+	sort.Strings(a.Missing)
+	sort.Strings(a.Superfluous)
+	return a
+}
+
+// log an analysis, superfluous deps are left to human judgement
+func (a Analysis) report() {
+	for _, l := range a.Missing {
+		log.Printf("%s: missing dep %s\n", a.Target, l)
+	}
+	for _, l := range a.Superfluous {
+		log.Printf("%s: superfluous dep %s, remove with buildozer "+
+			"'remove deps %s' %s\n", a.Target, l, l, a.Target)
+	}
+	log.Printf("%s: %d missing, %d superfluous deps\n", a.Target,
+		len(a.Missing), len(a.Superfluous))
+}
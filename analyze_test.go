@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+func TestSrcPath(t *testing.T) {
+	for label, want := range map[string]string{
+		"//ui/web:src/main/java/ui/Fx.java": "ui/web/src/main/java/ui/Fx.java",
+		"//:Fx.java":                        "Fx.java",
+	} {
+		if got := srcPath(label); want != got {
+			t.Fatalf("want %s but got %s\n", want, got)
+		}
+	}
+}
+
+func TestAnalyzeDeps(t *testing.T) {
+	deps := []index.Dependency{
+		{Name: "framework", Resources: classes("org.company.framework.A"),
+			Kind: index.Source},
+		{Name: "//external:junit", Resources: classes("org.junit.Test"),
+			Kind: index.MavenJar},
+		{Name: "util", Resources: classes("org.company.util.Strings"),
+			Kind: index.Source},
+	}
+	srcs := []SourceFile{{
+		Package: "ui",
+		Imports: []index.JavaClass{
+			{Name: "java.util.List"},
+			{Name: "org.company.framework.A"},
+			{Name: "org.junit.Test"},
+			{Name: "ui.Other"},
+		},
+	}}
+	declared := []string{"@junit//jar", "//:util", "//tools:processor"}
+	a := analyzeDeps("//:ui_web", srcs, declared, deps)
+	if len(a.Missing) != 1 || a.Missing[0] != "//:framework" {
+		t.Fatalf("want missing //:framework but got %+v\n", a.Missing)
+	}
+	if len(a.Superfluous) != 1 || a.Superfluous[0] != "//:util" {
+		t.Fatalf("want superfluous //:util but got %+v\n",
+			a.Superfluous)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+)
+
+// group edits by BUILD file, keeping the order of edits within each file.
+// Packages are returned in order of first appearance.
+func batch(edits []edit.Edit) ([]string, map[string][]edit.Edit) {
+	var pkgs []string
+	batches := make(map[string][]edit.Edit)
+	for _, e := range edits {
+		pkg := edit.LabelPackage(e.Target)
+		if _, ok := batches[pkg]; !ok {
+			pkgs = append(pkgs, pkg)
+		}
+		batches[pkg] = append(batches[pkg], e)
+	}
+	return pkgs, batches
+}
+
+// apply edits in phases, log the diff of all changed BUILD files, and append
+// it to the journal, if any
+func applyAll(edits []edit.Edit, workspace string, journal string) error {
+	// rules must exist before anything depends on them
+	gen, rest := edit.Phases(edits)
+	pkgs, _ := batch(edits)
+	before := snapshot(workspace, pkgs)
+	if err := createBuildFiles(gen, workspace); err != nil {
+		return err
+	}
+	if err := applyEdits(gen, workspace); err != nil {
+		return err
+	}
+	if err := applyEdits(rest, workspace); err != nil {
+		return err
+	}
+	// review artifact independent of any version control
+	diff := diffSnapshots(before, snapshot(workspace, pkgs))
+	fmt.Fprint(log.Writer(), diff)
+	for _, pkg := range pkgs {
+		err := own(filepath.Join(workspace, buildFile(workspace, pkg)))
+		if err != nil {
+			return err
+		}
+	}
+	if journal != "" {
+		if err := appendFile(journal, diff); err != nil {
+			return err
+		}
+		return own(journal)
+	}
+	return nil
+}
+
+// create empty BUILD files for rules generated into packages that have
+// none yet, such as source modules
+func createBuildFiles(gen []edit.Edit, workspace string) error {
+	for _, e := range gen {
+		if !strings.HasPrefix(e.Command, "new ") {
+			continue
+		}
+		pkg := edit.LabelPackage(e.Target)
+		f := filepath.Join(workspace, buildFile(workspace, pkg))
+		if pkg == "" || canRead(f) {
+			continue
+		}
+		log.Printf("creating %s\n", f)
+		if err := ioutil.WriteFile(f, nil, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply edits using one worker per BUILD file, so that buildozer never
+// touches the same file concurrently
+func applyEdits(edits []edit.Edit, workspace string) error {
+	pkgs, batches := batch(edits)
+	log.Printf("applying %d edits to %d BUILD files\n",
+		len(edits), len(pkgs))
+	errs := make([]error, len(pkgs))
+	var wg sync.WaitGroup
+	for i, pkg := range pkgs {
+		wg.Add(1)
+		go func(i int, pkg string) {
+			defer wg.Done()
+			for _, e := range batches[pkg] {
+				if err := bdRun(e, workspace); err != nil {
+					errs[i] = err
+					return
+				}
+			}
+		}(i, pkg)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func bdRun(e edit.Edit, workspace string) error {
+	prms := []string{"buildozer", e.Command, e.Target}
+	cmd := exec.Command(prms[0], prms[1:]...)
+	cmd.Dir = workspace
+	log.Printf("executing %v in %s\n", prms, cmd.Dir)
+	buf, err := cmd.CombinedOutput()
+	if err != nil {
+		// buildozer returns 3 if there was nothing to change
+		if ee, ok := err.(*exec.ExitError); ok && ee.ExitCode() == 3 {
+			return nil
+		}
+		return fmt.Errorf("%s: %v: %s", e, err, buf)
+	}
+	return nil
+}
+
+// BUILD file of a package relative to the workspace, preferring BUILD.bazel.
+// Packages naming a file, such as //WORKSPACE, are that file.
+func buildFile(workspace string, pkg string) string {
+	if fi, err := os.Stat(filepath.Join(workspace, pkg)); err == nil &&
+		fi.Mode().IsRegular() {
+		return pkg
+	}
+	for _, f := range []string{"BUILD.bazel", "BUILD"} {
+		if canRead(filepath.Join(workspace, pkg, f)) {
+			return filepath.Join(pkg, f)
+		}
+	}
+	return filepath.Join(pkg, "BUILD")
+}
+
+// textual state of the BUILD files of the given packages, missing files are
+// empty
+func snapshot(workspace string, pkgs []string) map[string]string {
+	m := make(map[string]string)
+	for _, pkg := range pkgs {
+		f := buildFile(workspace, pkg)
+		buf, err := ioutil.ReadFile(filepath.Join(workspace, f))
+		if err != nil && !os.IsNotExist(err) {
+			log.Printf("cannot snapshot %s: %v\n", f, err)
+		}
+		m[f] = string(buf)
+	}
+	return m
+}
+
+// unified diff of all BUILD files changed between two snapshots, in order of
+// file name
+func diffSnapshots(before, after map[string]string) string {
+	var fs []string
+	for f := range after {
+		fs = append(fs, f)
+	}
+	sort.Strings(fs)
+	var sb strings.Builder
+	for _, f := range fs {
+		sb.WriteString(unified(f, before[f], after[f]))
+	}
+	return sb.String()
+}
+
+func appendFile(filename string, s string) error {
+	f, err := os.OpenFile(filename,
+		os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(s)
+	return err
+}
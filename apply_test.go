@@ -0,0 +1,72 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+)
+
+func TestLabelPackage(t *testing.T) {
+	for target, want := range map[string]string{
+		"__pkg__":          "",
+		"ui_web":           "",
+		"//:ui_web":        "",
+		"//ui/web:web":     "ui/web",
+		"//ui/web":         "ui/web",
+		"//ui/web:__pkg__": "ui/web",
+	} {
+		got := edit.LabelPackage(target)
+		if want != got {
+			t.Fatalf("%s: want %q but got %q\n", target, want, got)
+		}
+	}
+}
+
+func TestBatch(t *testing.T) {
+	edits := []edit.Edit{
+		{Command: "add deps //:a", Target: "//ui:ui"},
+		{Command: "new java_library b", Target: "__pkg__"},
+		{Command: "add deps //:b", Target: "//ui:ui"},
+		{Command: "set srcs []", Target: "b"},
+	}
+	pkgs, batches := batch(edits)
+	if len(pkgs) != 2 || pkgs[0] != "ui" || pkgs[1] != "" {
+		t.Fatalf("want [ui ''] but got %q\n", pkgs)
+	}
+	ui := batches["ui"]
+	if len(ui) != 2 || ui[0] != edits[0] || ui[1] != edits[2] {
+		t.Fatalf("want ordered edits for ui but got %+v\n", ui)
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	before := map[string]string{"BUILD": "a\n", "ui/BUILD": "b\n"}
+	after := map[string]string{"BUILD": "a\n", "ui/BUILD": "c\n"}
+	want := "--- a/ui/BUILD\n+++ b/ui/BUILD\n@@ -1,1 +1,1 @@\n-b\n+c\n"
+	got := diffSnapshots(before, after)
+	if want != got {
+		t.Fatalf("want\n%s\nbut got\n%s\n", want, got)
+	}
+}
+
+func TestCreateBuildFiles(t *testing.T) {
+	ws := t.TempDir()
+	fixtureFiles(t, ws, map[string]string{
+		"ui/web/src/main/java/org/a/A.java": "",
+		"ui/app/BUILD.bazel":                "# keep\n",
+	})
+	err := createBuildFiles([]edit.Edit{
+		{Command: "new java_library web", Target: "//ui/web:__pkg__"},
+		{Command: "new java_library app", Target: "//ui/app:__pkg__"},
+	}, ws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !canRead(filepath.Join(ws, "ui/web/BUILD")) {
+		t.Fatalf("want ui/web/BUILD created\n")
+	}
+	if canRead(filepath.Join(ws, "ui/app/BUILD")) {
+		t.Fatalf("want ui/app/BUILD.bazel kept\n")
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+)
+
+var (
+	// version of rules_jvm_external a MODULE.bazel gets if it has none
+	rulesJvmExternalVersion = "6.5"
+	REMavenInstallName      = regexp.MustCompile(
+		`maven_install\(\s*name\s*=\s*"([^"]+)"`)
+)
+
+// declaration of an artifact in a workspace: the label of its jar, the
+// stanza declaring it, and the buildozer commands writing the stanza. bzlmod
+// workspaces get an artifact of maven.install, and a bazel_dep on
+// rules_jvm_external if missing, WORKSPACE files an artifact of
+// maven_install. ok is false if buildozer cannot write the stanza, such as
+// the use_extension of a MODULE.bazel without maven extension, or the
+// maven_install of a WORKSPACE without any: the user adds it.
+func declare(workspace string, artifact string) (label string, stanza string,
+	es []edit.Edit, ok bool) {
+	parts := strings.Split(artifact, ":")
+	if buf, err := ioutil.ReadFile(filepath.Join(workspace,
+		"MODULE.bazel")); err == nil {
+		module := string(buf)
+		dep := !strings.Contains(module, `"rules_jvm_external"`)
+		mis := mavenInstalls(module)
+		if len(mis) == 0 {
+			stanza = fmt.Sprintf("maven = use_extension(\"@"+
+				"rules_jvm_external//:extensions.bzl\", "+
+				"\"maven\"); maven.artifact(group = %q, "+
+				"artifact = %q, version = %q); "+
+				"use_repo(maven, \"maven\")", parts[0],
+				parts[1], parts[2])
+			if dep {
+				stanza = fmt.Sprintf("bazel_dep(name = "+
+					"\"rules_jvm_external\", version = "+
+					"%q); %s", rulesJvmExternalVersion,
+					stanza)
+			}
+			return mavenLabel(artifact), "MODULE.bazel: " + stanza,
+				nil, false
+		}
+		if dep {
+			es = edit.NewBazelDep("rules_jvm_external",
+				rulesJvmExternalVersion)
+		}
+		mi := defaultInstall(mis)
+		target := "//MODULE.bazel:%maven.install"
+		if mi.named {
+			target = "//MODULE.bazel:" + mi.repo
+		} else if len(mis) > 1 {
+			target = fmt.Sprintf("//MODULE.bazel:%%%d", mi.line)
+		}
+		es = append(es, edit.AddArtifact(target, artifact))
+		return repoLabel(mi.repo, artifact), repin(workspace,
+			mi.repo, fmt.Sprintf("MODULE.bazel: maven.install "+
+				"artifacts += [%q]", artifact)), es, true
+	}
+	file := "WORKSPACE"
+	if canRead(filepath.Join(workspace, "WORKSPACE.bazel")) {
+		file = "WORKSPACE.bazel"
+	}
+	buf, _ := ioutil.ReadFile(filepath.Join(workspace, file))
+	if !strings.Contains(string(buf), "maven_install(") {
+		// maven_jar is gone since Bazel 2
+		return mavenLabel(artifact), fmt.Sprintf("%s: load(\"@"+
+			"rules_jvm_external//:defs.bzl\", \"maven_install\"); "+
+			"maven_install(artifacts = [%q], repositories = "+
+			"[\"https://repo1.maven.org/maven2\"])", file,
+			artifact), nil, false
+	}
+	repo := "maven"
+	if m := REMavenInstallName.FindStringSubmatch(string(buf)); m != nil {
+		repo = m[1]
+	}
+	return repoLabel(repo, artifact), repin(workspace, repo,
+			fmt.Sprintf("%s: maven_install artifacts += [%q]", file,
+				artifact)),
+		[]edit.Edit{edit.AddArtifact("//"+file+":"+repo, artifact)},
+		true
+}
+
+// maven.install tag new artifacts go to: that of the maven repository, or
+// the first
+func defaultInstall(mis []mavenInstall) mavenInstall {
+	for _, mi := range mis {
+		if mi.repo == "maven" {
+			return mi
+		}
+	}
+	return mis[0]
+}
+
+// a stanza adding to a pinned repository, followed by repinning it. Pinned
+// repositories resolve nothing new until their lock file is written again.
+func repin(workspace string, repo string, stanza string) string {
+	for _, l := range lockFiles(workspace) {
+		if l.Repo == repo {
+			log.Printf("%s is pinned in %s, repin it after adding "+
+				"artifacts\n", repo, l.File)
+			return fmt.Sprintf("%s; then REPIN=1 bazel run "+
+				"@unpinned_%s//:pin", stanza, repo)
+		}
+	}
+	return stanza
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDeclare(t *testing.T) {
+	const artifact = "org.slf4j:slf4j-api:2.0.13"
+	for _, tt := range []struct {
+		files         map[string]string
+		label, stanza string
+		edits         []string
+		ok            bool
+	}{
+		{map[string]string{"MODULE.bazel": "bazel_dep(name = " +
+			"\"rules_jvm_external\", version = \"6.0\")\n" +
+			"maven.install(\n    artifacts = [],\n)\n"},
+			"@maven//:org_slf4j_slf4j_api", "MODULE.bazel: " +
+				`maven.install artifacts += ["` + artifact + `"]`,
+			[]string{"buildozer 'add artifacts " + artifact +
+				"' '//MODULE.bazel:%maven.install'"}, true},
+		{map[string]string{"MODULE.bazel": "maven.install(\n" +
+			"    name = \"deps\",\n    lock_file = " +
+			"\"//:deps_install.json\",\n)\n"},
+			"@deps//:org_slf4j_slf4j_api", "MODULE.bazel: " +
+				`maven.install artifacts += ["` + artifact +
+				`"]; then REPIN=1 bazel run @unpinned_deps//:pin`,
+			[]string{"buildozer 'new bazel_dep rules_jvm_external' " +
+				"'//MODULE.bazel:__pkg__'",
+				`buildozer 'set version "6.5"' ` +
+					"'//MODULE.bazel:rules_jvm_external'",
+				"buildozer 'add artifacts " + artifact +
+					"' '//MODULE.bazel:deps'"}, true},
+		{map[string]string{"MODULE.bazel": "bazel_dep(name = " +
+			"\"rules_jvm_external\", version = \"6.0\")\n" +
+			"maven.install(name = \"tools\")\n" +
+			"maven.install(artifacts = [])\n"},
+			"@maven//:org_slf4j_slf4j_api", "MODULE.bazel: " +
+				`maven.install artifacts += ["` + artifact + `"]`,
+			[]string{"buildozer 'add artifacts " + artifact +
+				"' '//MODULE.bazel:%3'"}, true},
+		{map[string]string{"MODULE.bazel": ""},
+			"@maven//:org_slf4j_slf4j_api", "MODULE.bazel: " +
+				`bazel_dep(name = "rules_jvm_external", version = ` +
+				`"6.5"); maven = use_extension("@rules_jvm_external` +
+				`//:extensions.bzl", "maven"); maven.artifact(` +
+				`group = "org.slf4j", artifact = "slf4j-api", ` +
+				`version = "2.0.13"); use_repo(maven, "maven")`,
+			nil, false},
+		{map[string]string{"WORKSPACE": "maven_install(\n" +
+			"    name = \"deps\",\n"},
+			"@deps//:org_slf4j_slf4j_api", "WORKSPACE: " +
+				`maven_install artifacts += ["` + artifact + `"]`,
+			[]string{"buildozer 'add artifacts " + artifact +
+				"' '//WORKSPACE:deps'"}, true},
+		{map[string]string{"WORKSPACE": "maven_install(\n" +
+			"    name = \"deps\",\n", "deps_install.json": "{}"},
+			"@deps//:org_slf4j_slf4j_api", "WORKSPACE: " +
+				`maven_install artifacts += ["` + artifact +
+				`"]; then REPIN=1 bazel run @unpinned_deps//:pin`,
+			[]string{"buildozer 'add artifacts " + artifact +
+				"' '//WORKSPACE:deps'"}, true},
+		{map[string]string{"WORKSPACE": ""},
+			"@maven//:org_slf4j_slf4j_api", "WORKSPACE: " +
+				`load("@rules_jvm_external//:defs.bzl", ` +
+				`"maven_install"); maven_install(artifacts = ["` +
+				artifact + `"], repositories = ` +
+				`["https://repo1.maven.org/maven2"])`,
+			nil, false},
+	} {
+		ws := t.TempDir()
+		fixtureFiles(t, ws, tt.files)
+		label, stanza, es, ok := declare(ws, artifact)
+		if tt.label != label || tt.stanza != stanza || tt.ok != ok {
+			t.Fatalf("want %s %s %v but got %s %s %v\n", tt.label,
+				tt.stanza, tt.ok, label, stanza, ok)
+		}
+		var got []string
+		for _, e := range es {
+			got = append(got, e.String())
+		}
+		if fmt.Sprint(tt.edits) != fmt.Sprint(got) {
+			t.Fatalf("want %q but got %q\n", tt.edits, got)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+)
+
+// Attribute is set on every library or alias kaizen generates for a
+// dependency
+type Attribute struct {
+	Name  string
+	Value string // buildozer syntax, such as //visibility:public
+}
+
+// parse comma separated name=value pairs of -default-attributes, such as
+// visibility=//visibility:public,tags=generated
+func parseAttributes(s string) ([]Attribute, error) {
+	var as []Attribute
+	for _, pair := range strings.Split(s, ",") {
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, fmt.Errorf("want attribute=value but got %q",
+				pair)
+		}
+		as = append(as, Attribute{kv[0], kv[1]})
+	}
+	return as, nil
+}
+
+// edits setting attributes of a generated rule
+func setAttributes(rule string, as []Attribute) []edit.Edit {
+	var es []edit.Edit
+	for _, a := range as {
+		es = append(es, edit.Edit{
+			Command: fmt.Sprintf("set %s %s", a.Name, a.Value),
+			Target:  rule,
+		})
+	}
+	return es
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+)
+
+func TestSetAttributes(t *testing.T) {
+	as, err := parseAttributes("visibility=//visibility:public,tags=x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	es := setAttributes("framework", as)
+	want := []edit.Edit{
+		{Command: "set visibility //visibility:public",
+			Target: "framework"},
+		{Command: "set tags x", Target: "framework"},
+	}
+	if len(es) != len(want) || es[0] != want[0] || es[1] != want[1] {
+		t.Fatalf("want %+v but got %+v\n", want, es)
+	}
+	for _, s := range []string{"visibility", "=x", "tags="} {
+		if _, err := parseAttributes(s); err == nil {
+			t.Fatalf("%s: want error\n", s)
+		}
+	}
+}
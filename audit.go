@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jhinrichsen/bazel-kaizen/bazel"
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+// digest of everything an analysis depends on: declared deps, sources, and
+// the version of the index
+func digest(r bazel.JavaRule, workspace string, version string) string {
+	h := sha256.New()
+	fmt.Fprintln(h, version)
+	fmt.Fprintln(h, r.TestOnly)
+	fmt.Fprintln(h, strings.Join(r.Deps, " "))
+	for _, s := range r.Srcs {
+		fmt.Fprint(h, s)
+		if fi, err := os.Stat(filepath.Join(workspace, srcPath(s))); err == nil {
+			fmt.Fprint(h, fi.Size(), fi.ModTime().UnixNano())
+		}
+		fmt.Fprintln(h)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// AuditEntry is a cached analysis
+type AuditEntry struct {
+	Digest   string
+	Analysis Analysis
+}
+
+func readAudit(filename string) map[string]AuditEntry {
+	m := make(map[string]AuditEntry)
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return m
+	}
+	if err := gob.NewDecoder(bytes.NewReader(buf)).Decode(&m); err != nil {
+		log.Printf("ignoring audit cache %s: %v\n", filename, err)
+		return make(map[string]AuditEntry)
+	}
+	return m
+}
+
+func writeAudit(filename string, m map[string]AuditEntry) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(m); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return own(filename)
+}
+
+// whether the imports of a rule can be read: Java rules of Java sources
+// only. Kotlin and generated sources import what kaizen cannot see, so all
+// their deps would look superfluous.
+func javaOnly(r bazel.JavaRule) bool {
+	if !strings.HasPrefix(r.Class, "java_") {
+		return false
+	}
+	for _, s := range r.Srcs {
+		if !strings.HasSuffix(s, ".java") {
+			return false
+		}
+	}
+	return true
+}
+
+// analyze a rule from its Java sources
+func analyzeRule(r bazel.JavaRule, workspace string,
+	deps []index.Dependency) Analysis {
+	var srcs []SourceFile
+	for _, l := range r.Srcs {
+		if !strings.HasSuffix(l, ".java") {
+			continue
+		}
+		sf, err := parseSource(filepath.Join(workspace, srcPath(l)))
+		if err != nil {
+			log.Printf("skipping %s: %v\n", l, err)
+			continue
+		}
+		srcs = append(srcs, sf)
+	}
+	return analyzeDeps(r.Label, srcs, r.Deps, deps)
+}
+
+// analyze rules in parallel, reusing cached analyses of unchanged rules.
+// The cache is updated in place. Analyses are ordered by target, rules of
+// sources other than Java are skipped.
+func audit(all []bazel.JavaRule, workspace string, deps []index.Dependency,
+	cache map[string]AuditEntry, version string) []Analysis {
+	var rules []bazel.JavaRule
+	for _, r := range all {
+		if !javaOnly(r) {
+			log.Printf("skipping %s %s, cannot read imports of its "+
+				"sources\n", r.Class, r.Label)
+			continue
+		}
+		rules = append(rules, r)
+	}
+	as := make([]Analysis, len(rules))
+	digests := make([]string, len(rules))
+	// test only jars provide to tests only
+	production := index.Production(deps)
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				r := rules[i]
+				digests[i] = digest(r, workspace, version)
+				if e, ok := cache[r.Label]; ok &&
+					e.Digest == digests[i] {
+					as[i] = e.Analysis
+					continue
+				}
+				if r.TestOnly {
+					as[i] = analyzeRule(r, workspace, deps)
+				} else {
+					as[i] = analyzeRule(r, workspace, production)
+				}
+			}
+		}()
+	}
+	for i := range rules {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+	reused := 0
+	for i, r := range rules {
+		if cache[r.Label].Digest == digests[i] {
+			reused++
+		}
+		cache[r.Label] = AuditEntry{digests[i], as[i]}
+	}
+	log.Printf("analyzed %d rules, %d unchanged\n", len(rules), reused)
+	sort.Slice(as, func(i, j int) bool {
+		return as[i].Target < as[j].Target
+	})
+	return as
+}
+
+// audit rules against the audit cache next to the index cache
+func auditCached(rules []bazel.JavaRule, workspace string, cachefile string,
+	deps []index.Dependency) ([]Analysis, error) {
+	auditfile := cachefile + ".audit"
+	cache := readAudit(auditfile)
+	// a new index invalidates all analyses
+	version := ""
+	if fi, err := os.Stat(cachefile); err == nil {
+		version = fi.ModTime().String()
+	}
+	as := audit(rules, workspace, deps, cache, version)
+	return as, writeAudit(auditfile, cache)
+}
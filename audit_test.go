@@ -0,0 +1,136 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/bazel"
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+const fixtureRulesXML = `<?xml version="1.1" encoding="UTF-8" standalone="no"?>
+<query version="2">
+    <rule class="java_library" location="/ws/ui/web/BUILD:1:13" name="//ui/web:web">
+        <string name="name" value="web"/>
+        <list name="srcs">
+            <label value="//ui/web:src/main/java/ui/Fx.java"/>
+        </list>
+        <list name="deps">
+            <label value="//:util"/>
+        </list>
+        <list name="javacopts">
+            <string value="-Xlint:all"/>
+        </list>
+    </rule>
+</query>
+`
+
+func TestParseRulesXML(t *testing.T) {
+	rules, err := bazel.ParseRules(strings.NewReader(fixtureRulesXML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 1 || rules[0].Label != "//ui/web:web" ||
+		len(rules[0].Srcs) != 1 || len(rules[0].Deps) != 1 ||
+		rules[0].Class != "java_library" || len(rules[0].Javacopts) != 1 {
+		t.Fatalf("want //ui/web:web with 1 src and 1 dep but got %+v\n",
+			rules)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	ws := t.TempDir()
+	fixtureFiles(t, ws, map[string]string{
+		"ui/web/src/main/java/ui/Fx.java": "package ui;\n\n" +
+			"import org.company.framework.A;\n\npublic class Fx {}\n",
+	})
+	rules, _ := bazel.ParseRules(strings.NewReader(fixtureRulesXML))
+	deps := []index.Dependency{
+		{Name: "framework", Resources: classes("org.company.framework.A"),
+			Kind: index.Source},
+		{Name: "util", Resources: classes("org.company.util.Strings"),
+			Kind: index.Source},
+	}
+	cache := make(map[string]AuditEntry)
+	as := audit(rules, ws, deps, cache, "1")
+	if len(as) != 1 || len(as[0].Missing) != 1 ||
+		len(as[0].Superfluous) != 1 {
+		t.Fatalf("want 1 missing and 1 superfluous dep but got %+v\n",
+			as)
+	}
+
+	// cached analyses survive a round trip, and are reused as long as
+	// nothing changed
+	filename := filepath.Join(ws, ".healdb.audit")
+	if err := writeAudit(filename, cache); err != nil {
+		t.Fatal(err)
+	}
+	cache = readAudit(filename)
+	as = audit(rules, ws, nil, cache, "1")
+	if len(as[0].Missing) != 1 {
+		t.Fatalf("want cached analysis but got %+v\n", as)
+	}
+	as = audit(rules, ws, nil, cache, "2")
+	if len(as[0].Missing) != 0 {
+		t.Fatalf("want new analysis for new index but got %+v\n", as)
+	}
+}
+
+func TestAuditSkipsKotlin(t *testing.T) {
+	ws := t.TempDir()
+	fixtureFiles(t, ws, map[string]string{
+		"ui/kt/src/main/kotlin/ui/Fx.kt": "package ui\n\n" +
+			"import org.company.util.Strings\n\nclass Fx\n",
+	})
+	rules := []bazel.JavaRule{
+		{Class: "kt_jvm_library", Label: "//ui/kt:kt",
+			Srcs: []string{"//ui/kt:src/main/kotlin/ui/Fx.kt"},
+			Deps: []string{"//:util"}},
+		// generated sources hide their imports as well
+		{Class: "java_library", Label: "//ui/gen:gen",
+			Srcs: []string{"//ui/gen:api_srcs"},
+			Deps: []string{"//:util"}},
+	}
+	deps := []index.Dependency{
+		{Name: "util", Resources: classes("org.company.util.Strings"),
+			Kind: index.Source},
+	}
+	as := audit(rules, ws, deps, make(map[string]AuditEntry), "1")
+	if len(as) != 0 {
+		t.Fatalf("want no analysis but got %+v\n", as)
+	}
+}
+
+func TestAuditTestOnly(t *testing.T) {
+	ws := t.TempDir()
+	fixtureFiles(t, ws, map[string]string{
+		"testing/src/main/java/testing/Fakes.java": "package " +
+			"testing;\n\nimport org.company.util.StringsTest;\n\n" +
+			"public class Fakes {}\n",
+	})
+	rules, err := bazel.ParseRules(strings.NewReader(`<query version="2">
+    <rule class="java_library" name="//testing:testing">
+        <boolean name="testonly" value="true"/>
+        <list name="srcs">
+            <label value="//testing:src/main/java/testing/Fakes.java"/>
+        </list>
+    </rule>
+</query>
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 1 || !rules[0].TestOnly {
+		t.Fatalf("want 1 test only rule but got %+v\n", rules)
+	}
+	deps := []index.Dependency{
+		{Name: "util_test",
+			Resources: classes("org.company.util.StringsTest"),
+			Kind:      index.Source, TestOnly: true},
+	}
+	as := audit(rules, ws, deps, make(map[string]AuditEntry), "1")
+	if len(as) != 1 || len(as[0].Missing) != 1 {
+		t.Fatalf("want missing test only dep but got %+v\n", as)
+	}
+}
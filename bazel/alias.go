@@ -0,0 +1,88 @@
+package bazel
+
+import (
+	"bufio"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	RELocation  = regexp.MustCompile(`^# (.*)/BUILD(\.bazel)?:\d+:\d+$`)
+	REAttribute = regexp.MustCompile(`^\s*(name|actual) = "(.*)",?$`)
+)
+
+// prefer aliases in third_party, then the lexically first one
+func preferred(a, b string) bool {
+	ta := strings.HasPrefix(a, "//third_party/")
+	tb := strings.HasPrefix(b, "//third_party/")
+	if ta != tb {
+		return ta
+	}
+	return a < b
+}
+
+// map actual targets to the preferred alias pointing at them, parsing bazel
+// query --output=build output. BUILD file locations are made relative to the
+// absolute workspace directory.
+func aliases(build string, workspace string) map[string]string {
+	m := make(map[string]string)
+	var pkg, name, actual string
+	add := func() {
+		if name == "" || actual == "" {
+			return
+		}
+		label := "//" + pkg + ":" + name
+		if strings.HasPrefix(actual, ":") {
+			actual = "//" + pkg + actual
+		}
+		if a, ok := m[actual]; !ok || preferred(label, a) {
+			m[actual] = label
+		}
+		name, actual = "", ""
+	}
+	scanner := bufio.NewScanner(strings.NewReader(build))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if matches := RELocation.FindStringSubmatch(line); matches != nil {
+			add()
+			rel, err := filepath.Rel(workspace, matches[1])
+			if err != nil || rel == "." {
+				rel = ""
+			}
+			pkg = filepath.ToSlash(rel)
+		} else if matches := REAttribute.FindStringSubmatch(line); matches != nil {
+			if matches[1] == "name" {
+				name = matches[2]
+			} else {
+				actual = matches[2]
+			}
+		}
+	}
+	add()
+	return m
+}
+
+// Aliases maps actual targets of the workspace to the preferred alias
+// pointing at them
+func Aliases(workspace string) (map[string]string, error) {
+	abs, err := filepath.Abs(workspace)
+	if err != nil {
+		return nil, err
+	}
+	prms := []string{
+		"bazel",
+		"query",
+		"kind(alias, //...)",
+		"--output=build",
+	}
+	buf, err := Query(prms, workspace)
+	if err != nil {
+		log.Printf("cannot query aliases: %v\n", err)
+		return nil, nil
+	}
+	m := aliases(string(buf), abs)
+	log.Printf("found %d aliases\n", len(m))
+	return m, nil
+}
@@ -0,0 +1,45 @@
+package bazel
+
+import (
+	"testing"
+)
+
+func TestAliases(t *testing.T) {
+	build := `# /ws/BUILD:3:6
+alias(
+  name = "guava",
+  actual = "@maven//:com_google_guava_guava",
+)
+# /ws/third_party/java/guava/BUILD.bazel:1:6
+alias(
+  name = "guava",
+  actual = "@maven//:com_google_guava_guava",
+)
+# /ws/ui/BUILD:1:6
+alias(
+  name = "web",
+  actual = ":ui_web",
+)
+`
+	m := aliases(build, "/ws")
+	want := map[string]string{
+		"@maven//:com_google_guava_guava": "//third_party/java/guava:guava",
+		"//ui:ui_web":                     "//ui:web",
+	}
+	if len(m) != len(want) {
+		t.Fatalf("want %+v but got %+v\n", want, m)
+	}
+	for k, v := range want {
+		if m[k] != v {
+			t.Fatalf("%s: want %s but got %s\n", k, v, m[k])
+		}
+	}
+}
+
+func FuzzAliases(f *testing.F) {
+	f.Add("# /ws/a/BUILD:1:1\nalias(\n  name = \"b\",\n" +
+		"  actual = \":c\",\n)\n")
+	f.Fuzz(func(t *testing.T, s string) {
+		aliases(s, "/ws")
+	})
+}
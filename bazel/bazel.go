@@ -0,0 +1,174 @@
+// Package bazel runs bazel commands and queries, honoring offline mode,
+// startup options, and --config names.
+package bazel
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Offline keeps bazel from fetching external repositories (-no-network)
+var Offline bool
+
+// startup options and --config names of every bazel invocation (-bazelrc,
+// -bazel-config)
+var (
+	Startup []string
+	Configs []string
+)
+
+// QueryOutputBase runs queries on a bazel server of their own, which stays
+// warm while builds keep the workspace's server busy (-query-output-base)
+var QueryOutputBase string
+
+// results of queries by command, nil unless cached
+var (
+	queries   map[string][]byte
+	queriesMu sync.Mutex
+)
+
+// bazel commands accepting --[no]fetch
+var fetching = map[string]bool{
+	"build":  true,
+	"test":   true,
+	"query":  true,
+	"cquery": true,
+	"aquery": true,
+}
+
+// options of every bazel query: never fetch while healing, and rather return
+// partial results than none
+var queryOptions = []string{"--nofetch", "--keep_going"}
+
+// Cmd is a bazel command in workdir. Startup options go before, and --config
+// names after the bazel command. Queries get queryOptions and
+// QueryOutputBase, and offline, other commands get --nofetch, so that bazel
+// fails instead of downloading. The query server of QueryOutputBase has
+// external repositories of its own, which it fetches unless offline.
+func Cmd(prms []string, workdir string) *exec.Cmd {
+	if len(prms) > 1 {
+		var options []string
+		for _, c := range Configs {
+			options = append(options, "--config="+c)
+		}
+		if prms[1] == "query" && QueryOutputBase != "" && !Offline {
+			options = append(options, "--keep_going")
+		} else if prms[1] == "query" {
+			options = append(options, queryOptions...)
+		} else if Offline && fetching[prms[1]] {
+			options = append(options, "--nofetch")
+		}
+		ps := append([]string{prms[0]}, Startup...)
+		if prms[1] == "query" && QueryOutputBase != "" {
+			ps = append(ps, "--output_base="+QueryOutputBase)
+		}
+		ps = append(append(ps, prms[1]), options...)
+		prms = append(ps, prms[2:]...)
+	}
+	cmd := exec.Command(prms[0], prms[1:]...)
+	cmd.Dir = workdir
+	log.Printf("executing %v in %s\n", prms, cmd.Dir)
+	return cmd
+}
+
+// CacheQueries keeps the results of queries until ForgetQueries, for a
+// kaizen running as long as the BUILD files stay the same, or stops caching
+func CacheQueries(on bool) {
+	queriesMu.Lock()
+	defer queriesMu.Unlock()
+	queries = nil
+	if on {
+		queries = make(map[string][]byte)
+	}
+}
+
+// ForgetQueries drops cached results after BUILD files changed
+func ForgetQueries() {
+	queriesMu.Lock()
+	defer queriesMu.Unlock()
+	if queries != nil {
+		queries = make(map[string][]byte)
+	}
+}
+
+// Query runs a bazel query, accepting partial results, from the cache if
+// queries are cached
+func Query(prms []string, workdir string) ([]byte, error) {
+	key := workdir + "\x00" + strings.Join(prms, "\x00")
+	queriesMu.Lock()
+	buf, ok := queries[key]
+	queriesMu.Unlock()
+	if ok {
+		return buf, nil
+	}
+	cmd := Cmd(prms, workdir)
+	buf, err := cmd.Output()
+	err = Partial(err, buf, prms)
+	if err == nil {
+		queriesMu.Lock()
+		if queries != nil {
+			queries[key] = buf
+		}
+		queriesMu.Unlock()
+	}
+	return buf, err
+}
+
+// Unfetched reports whether bazel failed for an external repository that
+// offline mode did not fetch
+func Unfetched(output []byte) bool {
+	return bytes.Contains(output,
+		[]byte("fetching repositories is disabled"))
+}
+
+// ExitStatus is the exit status of a failed command, -1 if it did not run
+func ExitStatus(err error) int {
+	if ee, ok := err.(*exec.ExitError); ok {
+		return ee.ExitCode()
+	}
+	return -1
+}
+
+// Partial accepts partial results. bazel query exits with 3 if --keep_going
+// skipped errors. Warn about incomplete results, and carry on with what
+// bazel could evaluate.
+func Partial(err error, output []byte, prms []string) error {
+	if err == nil || ExitStatus(err) != 3 {
+		return err
+	}
+	if ee, ok := err.(*exec.ExitError); ok {
+		output = append(output, ee.Stderr...)
+	}
+	reason := ""
+	if Unfetched(output) {
+		reason = ", external repositories are not fetched"
+	}
+	log.Printf("warning: results of %v are incomplete%s\n", prms, reason)
+	return nil
+}
+
+// CheckFetched fails if offline mode kept bazel from fetching
+func CheckFetched(output []byte, what string) error {
+	if Offline && Unfetched(output) {
+		return fmt.Errorf("%s needs external repositories that are "+
+			"not fetched yet, run bazel fetch or drop -no-network",
+			what)
+	}
+	return nil
+}
+
+// QueryLabels returns the labels of a query expression, nil if the query
+// fails
+func QueryLabels(expr string, workdir string) []string {
+	prms := []string{"bazel", "query", expr}
+	buf, err := Query(prms, workdir)
+	if err != nil {
+		log.Printf("cannot query %s: %v\n", expr, err)
+		return nil
+	}
+	return strings.Fields(string(buf))
+}
@@ -0,0 +1,134 @@
+package bazel
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCmdOffline(t *testing.T) {
+	defer func() { Offline = false }()
+	for _, tt := range []struct {
+		offline bool
+		prms    []string
+		want    string
+	}{
+		{false, []string{"bazel", "query", "//..."},
+			"bazel query --nofetch --keep_going //..."},
+		{false, []string{"bazel", "build", "//a"},
+			"bazel build //a"},
+		{true, []string{"bazel", "query", "//..."},
+			"bazel query --nofetch --keep_going //..."},
+		{true, []string{"bazel", "build", "--color=no", "//a"},
+			"bazel build --nofetch --color=no //a"},
+		{true, []string{"bazel", "info", "output_base"},
+			"bazel info output_base"},
+	} {
+		Offline = tt.offline
+		cmd := Cmd(tt.prms, ".")
+		got := strings.Join(cmd.Args, " ")
+		if tt.want != got {
+			t.Fatalf("want %s but got %s\n", tt.want, got)
+		}
+	}
+}
+
+func TestCmdConfig(t *testing.T) {
+	defer func() {
+		Startup, Configs = nil, nil
+	}()
+	Startup = []string{"--bazelrc=ci.bazelrc"}
+	Configs = []string{"ci", "remote"}
+	cmd := Cmd([]string{"bazel", "info", "output_base"}, ".")
+	want := "bazel --bazelrc=ci.bazelrc info --config=ci --config=remote " +
+		"output_base"
+	got := strings.Join(cmd.Args, " ")
+	if want != got {
+		t.Fatalf("want %s but got %s\n", want, got)
+	}
+}
+
+// the query server fetches external repositories of its own, unless
+// offline
+func TestCmdQueryOutputBase(t *testing.T) {
+	defer func() { QueryOutputBase, Offline = "", false }()
+	QueryOutputBase = "/tmp/kaizen"
+	for _, tt := range []struct {
+		offline bool
+		prms    string
+		want    string
+	}{
+		{false, "bazel query //...", "bazel --output_base=/tmp/kaizen " +
+			"query --keep_going //..."},
+		{true, "bazel query //...", "bazel --output_base=/tmp/kaizen " +
+			"query --nofetch --keep_going //..."},
+		{false, "bazel build //a", "bazel build //a"},
+		{true, "bazel build //a", "bazel build --nofetch //a"},
+	} {
+		Offline = tt.offline
+		got := strings.Join(Cmd(strings.Fields(tt.prms), ".").Args,
+			" ")
+		if tt.want != got {
+			t.Fatalf("want %s but got %s\n", tt.want, got)
+		}
+	}
+}
+
+func TestQueryCache(t *testing.T) {
+	dir := t.TempDir()
+	// counts its runs
+	script := "#!/bin/sh\necho x >> " + filepath.Join(dir, "runs") +
+		"\necho //a:b\n"
+	err := ioutil.WriteFile(filepath.Join(dir, "bazel"), []byte(script),
+		0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	defer CacheQueries(false)
+	runs := func() int {
+		buf, _ := ioutil.ReadFile(filepath.Join(dir, "runs"))
+		return len(strings.Fields(string(buf)))
+	}
+	CacheQueries(true)
+	for i := 0; i < 2; i++ {
+		if got := QueryLabels("//a:b", dir); len(got) != 1 {
+			t.Fatalf("want //a:b but got %v\n", got)
+		}
+	}
+	if runs() != 1 {
+		t.Fatalf("want 1 query run but got %d\n", runs())
+	}
+	ForgetQueries()
+	QueryLabels("//a:b", dir)
+	if runs() != 2 {
+		t.Fatalf("want 2 query runs but got %d\n", runs())
+	}
+}
+
+func TestUnfetched(t *testing.T) {
+	out := "ERROR: An error occurred during the fetch of repository " +
+		"'maven':\n   fetching repositories is disabled\n"
+	if !Unfetched([]byte(out)) {
+		t.Fatalf("want unfetched for %q\n", out)
+	}
+	if Unfetched([]byte("ERROR: no such target '//a:b'")) {
+		t.Fatalf("want fetched for missing target\n")
+	}
+}
+
+func TestPartial(t *testing.T) {
+	prms := []string{"sh", "-c", "exit 3"}
+	err := exec.Command(prms[0], prms[1:]...).Run()
+	if err := Partial(err, nil, prms); err != nil {
+		t.Fatalf("want partial result but got %v\n", err)
+	}
+	prms = []string{"sh", "-c", "exit 7"}
+	err = exec.Command(prms[0], prms[1:]...).Run()
+	if err := Partial(err, nil, prms); ExitStatus(err) != 7 {
+		t.Fatalf("want exit status 7 but got %v\n", err)
+	}
+}
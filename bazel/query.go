@@ -0,0 +1,369 @@
+package bazel
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Info returns a single value of bazel info, such as output_base or
+// bazel-bin
+func Info(key string, workdir string) (string, error) {
+	prms := []string{"bazel", "info", key}
+	cmd := Cmd(prms, workdir)
+	buf, err := cmd.Output()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok {
+			log.Printf("stderr: %s\n", string(ee.Stderr))
+		}
+		return "", err
+	}
+	// expect exactly one line, but just to be on the safe side
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if len(lines) != 1 {
+		return "", fmt.Errorf("bazel info %s: want exactly one line "+
+			"but got %+v", key, lines)
+	}
+	return lines[0], nil
+}
+
+// OutputBase is the output_base of the workspace, such as
+// ~/.cache/bazel/_bazel_$USER/<md5>
+func OutputBase(workdir string) (string, error) {
+	return Info("output_base", workdir)
+}
+
+// Build builds a target, returning the combined output and whether the
+// build succeeded. It fails if bazel did not run, or needs repositories
+// that offline mode did not fetch.
+func Build(target string, workdir string) ([]byte, bool, error) {
+	prms := []string{
+		"bazel",
+		"build",
+		"--noshow_progress",
+		"--verbose_failures",
+		"--color=no",
+		target,
+	}
+	cmd := Cmd(prms, workdir)
+	buf, err := cmd.CombinedOutput()
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); !ok {
+			return buf, false, err
+		}
+		if err := CheckFetched(buf, target); err != nil {
+			return buf, false, err
+		}
+		log.Printf("build failed: %v\n", err)
+		return buf, false, nil
+	}
+	return buf, true, nil
+}
+
+// RuleExists reports whether bazel knows a rule
+func RuleExists(rule string, workdir string) (bool, error) {
+	prms := []string{
+		"bazel",
+		"query",
+		rule,
+	}
+	buf, err := Query(prms, workdir)
+	if ExitStatus(err) == 7 {
+		// Not found
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	// --keep_going reports a missing rule as partial, empty result
+	return len(bytes.TrimSpace(buf)) > 0, nil
+}
+
+// RuleKind is the kind of a rule, such as java_library, empty if unknown
+func RuleKind(rule string, workdir string) string {
+	prms := []string{
+		"bazel",
+		"query",
+		rule,
+		"--output=label_kind",
+	}
+	buf, err := Query(prms, workdir)
+	if err != nil {
+		log.Printf("cannot query kind of %s: %v\n", rule, err)
+		return ""
+	}
+	// java_library rule //a:b
+	fs := strings.Fields(string(buf))
+	if len(fs) < 3 || fs[1] != "rule" {
+		return ""
+	}
+	return fs[0]
+}
+
+// RuleDefinition is the rule definition as printed by bazel query, empty if
+// unknown
+func RuleDefinition(rule string, workdir string) string {
+	prms := []string{
+		"bazel",
+		"query",
+		rule,
+		"--output=build",
+	}
+	buf, err := Query(prms, workdir)
+	if err != nil {
+		log.Printf("cannot query definition of %s: %v\n", rule, err)
+		return ""
+	}
+	return string(buf)
+}
+
+// Fetch fetches the repository of an external dependency
+func Fetch(dep string, workdir string) bool {
+	prms := []string{"bazel", "fetch", dep}
+	cmd := Cmd(prms, workdir)
+	buf, err := cmd.CombinedOutput()
+	if err != nil {
+		log.Printf("cannot fetch %s: %v: %s\n", dep, err, buf)
+		return false
+	}
+	return true
+}
+
+// FindGenrule finds the genrule generating a Java package, nil if there is
+// none. There's a 1:1 mapping of genrule name to java package name, the
+// genrule may live in any package.
+func FindGenrule(javaPackage string, workdir string) (*string, error) {
+	rule := strings.Replace(javaPackage, ".", "_", -1)
+	prms := []string{
+		"bazel",
+		"query",
+		fmt.Sprintf("attr(name, '^%s$', kind(genrule, //...))", rule),
+		"--output=label_kind",
+	}
+	buf, err := Query(prms, workdir)
+	if ExitStatus(err) == 7 {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if len(lines) != 1 {
+		return nil, nil
+	}
+	fs := strings.Fields(lines[0])
+	if len(fs) == 3 && fs[0] == "genrule" &&
+		strings.HasSuffix(fs[2], ":"+rule) {
+		return &fs[2], nil
+	}
+	return nil, nil
+}
+
+// SrcsQuery queries for rules of any package having a class in their srcs,
+// making use of java package '.' as regexp to find /. Several classes are
+// separated by |.
+func SrcsQuery(class string) string {
+	return fmt.Sprintf("attr('srcs', '%s', //...)", class)
+}
+
+// FindSrcs finds the single rule having a class in its srcs, nil if there
+// is none or the query fails
+func FindSrcs(class string, workdir string) *string {
+	q := SrcsQuery(class)
+	prms := []string{
+		"bazel",
+		"query",
+		q,
+	}
+	buf, err := Query(prms, workdir)
+	if err != nil {
+		return nil
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if len(lines) == 1 && strings.HasPrefix(lines[0], "//") {
+		return &lines[0]
+	}
+	return nil
+}
+
+// ExternalDependencies lists all maven_jar rules of the WORKSPACE, such as
+// //external:junit
+func ExternalDependencies(workdir string) ([]string, error) {
+	prms := []string{
+		"bazel",
+		"query",
+		"kind(maven_jar, //external:all)"}
+	buf, err := Query(prms, workdir)
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok {
+			e := CheckFetched(ee.Stderr,
+				"listing external dependencies")
+			if e != nil {
+				return nil, e
+			}
+			log.Printf("stderr: %s\n", string(ee.Stderr))
+		}
+		return nil, err
+	}
+	var deps []string
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		line := scanner.Text()
+		deps = append(deps, line)
+	}
+	return deps, nil
+}
+
+// REArtifact is the Maven coordinates attribute of a maven_jar rule
+var REArtifact = regexp.MustCompile(`artifact = "(.*?)"`)
+
+// Artifact is the Maven coordinates of an external maven_jar rule, empty if
+// unknown
+func Artifact(rule string, workdir string) string {
+	prms := []string{
+		"bazel",
+		"query",
+		rule,
+		"--output=build",
+	}
+	buf, err := Query(prms, workdir)
+	if err != nil {
+		log.Printf("cannot determine artifact of %s: %v\n", rule, err)
+		return ""
+	}
+	matches := REArtifact.FindSubmatch(buf)
+	if len(matches) == 0 {
+		return ""
+	}
+	return string(matches[1])
+}
+
+// JarFiles returns the jar files of external dependencies by repository
+// name, nil if bazel cannot tell
+func JarFiles(deps []string, workdir string) map[string]string {
+	if len(deps) == 0 {
+		return nil
+	}
+	execroot, err := Info("execution_root", workdir)
+	if err != nil {
+		log.Printf("cannot determine execution root: %v\n", err)
+		return nil
+	}
+	var targets []string
+	for _, dep := range deps {
+		targets = append(targets,
+			"@"+strings.TrimPrefix(dep, "//external:")+"//jar")
+	}
+	prms := []string{
+		"bazel",
+		"cquery",
+		"--output=files",
+		"set(" + strings.Join(targets, " ") + ")",
+	}
+	cmd := Cmd(prms, workdir)
+	buf, err := cmd.Output()
+	if err != nil {
+		log.Printf("cannot query jar files: %v\n", err)
+		return nil
+	}
+	return parseJarFiles(buf, execroot)
+}
+
+// jars of external repositories by repository name, as reported by cquery
+// --output=files relative to the execution root, such as
+// external/junit/jar/junit-4.10.jar. Source jars are no class providers.
+func parseJarFiles(output []byte, execroot string) map[string]string {
+	m := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		p := strings.TrimSpace(scanner.Text())
+		if !strings.HasSuffix(p, ".jar") ||
+			strings.HasSuffix(p, "-sources.jar") {
+			continue
+		}
+		parts := strings.Split(filepath.ToSlash(p), "/")
+		if len(parts) < 3 || parts[0] != "external" {
+			continue
+		}
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(execroot, p)
+		}
+		m[parts[1]] = p
+	}
+	return m
+}
+
+// TestOnly reports whether a rule is testonly, as all test rules are
+func TestOnly(rule string, workdir string) bool {
+	if rule == "" {
+		return false
+	}
+	return len(QueryLabels("attr(testonly, 1, "+rule+")", workdir)) > 0
+}
+
+// RulePlugins returns the java_plugins a rule runs already: its own
+// plugins, and those exported by its direct deps
+func RulePlugins(rule string, workdir string) map[string]bool {
+	prms := []string{
+		"bazel",
+		"query",
+		fmt.Sprintf("labels(plugins, %s) + "+
+			"labels(exported_plugins, deps(%s, 1))", rule, rule),
+	}
+	buf, err := Query(prms, workdir)
+	if err != nil {
+		log.Printf("cannot query plugins of %s: %v\n", rule, err)
+		return nil
+	}
+	m := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		m[scanner.Text()] = true
+	}
+	return m
+}
+
+// ProtoLibrary is the proto_library having a .proto file in its srcs, empty
+// if there is none
+func ProtoLibrary(path string, workdir string) string {
+	ls := QueryLabels("kind(proto_library, rdeps(//..., "+path+", 1))",
+		workdir)
+	if len(ls) != 1 {
+		return ""
+	}
+	return ls[0]
+}
+
+// JavaProtoLibrary is the java_proto_library generating Java classes of a
+// proto_library, empty if there is none
+func JavaProtoLibrary(protoLibrary string, workdir string) string {
+	ls := QueryLabels("kind(java_proto_library, rdeps(//..., "+
+		protoLibrary+", 1))", workdir)
+	if len(ls) != 1 {
+		return ""
+	}
+	return ls[0]
+}
+
+// DependsOn reports whether a rule directly depends on another one
+func DependsOn(rule string, dep string, workdir string) bool {
+	ls := QueryLabels("deps("+rule+", 1) intersect "+dep, workdir)
+	return len(ls) > 0
+}
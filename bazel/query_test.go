@@ -0,0 +1,21 @@
+package bazel
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestParseJarFiles(t *testing.T) {
+	out := "external/junit/jar/junit-4.10.jar\n" +
+		"external/junit/jar/junit-4.10-sources.jar\n" +
+		"external/guava/jar/guava-20.0.jar\n" +
+		"bazel-out/k8-fastbuild/bin/ui/web/libweb.jar\n"
+	jars := parseJarFiles([]byte(out), "/execroot")
+	if len(jars) != 2 {
+		t.Fatalf("want 2 jars but got %+v\n", jars)
+	}
+	want := filepath.Join("/execroot", "external/junit/jar/junit-4.10.jar")
+	if got := jars["junit"]; want != got {
+		t.Fatalf("want %s but got %s\n", want, got)
+	}
+}
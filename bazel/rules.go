@@ -0,0 +1,134 @@
+package bazel
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"log"
+	"strings"
+)
+
+// JavaRule is a JVM rule with its sources and declared deps
+type JavaRule struct {
+	Class     string
+	Label     string
+	Srcs      []string
+	Deps      []string
+	Exports   []string
+	Javacopts []string
+	TestOnly  bool // testonly attribute, set for tests by default
+}
+
+// ParseRules reads the rules of bazel query --output=xml. Bazel declares XML
+// 1.1, which encoding/xml refuses, so the declaration is skipped.
+func ParseRules(r io.Reader) ([]JavaRule, error) {
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(buf, []byte("<?xml")) {
+		if i := bytes.Index(buf, []byte("?>")); i >= 0 {
+			buf = buf[i+2:]
+		}
+	}
+	var q struct {
+		Rules []struct {
+			Class    string `xml:"class,attr"`
+			Name     string `xml:"name,attr"`
+			Booleans []struct {
+				Name  string `xml:"name,attr"`
+				Value string `xml:"value,attr"`
+			} `xml:"boolean"`
+			Lists []struct {
+				Name   string `xml:"name,attr"`
+				Labels []struct {
+					Value string `xml:"value,attr"`
+				} `xml:"label"`
+				Strings []struct {
+					Value string `xml:"value,attr"`
+				} `xml:"string"`
+			} `xml:"list"`
+		} `xml:"rule"`
+	}
+	if err := xml.Unmarshal(buf, &q); err != nil {
+		return nil, err
+	}
+	var rules []JavaRule
+	for _, r := range q.Rules {
+		jr := JavaRule{Class: r.Class, Label: r.Name,
+			TestOnly: strings.HasSuffix(r.Class, "_test")}
+		for _, b := range r.Booleans {
+			if b.Name == "testonly" {
+				jr.TestOnly = b.Value == "true"
+			}
+		}
+		for _, l := range r.Lists {
+			if l.Name == "javacopts" {
+				for _, v := range l.Strings {
+					jr.Javacopts = append(jr.Javacopts, v.Value)
+				}
+			}
+			for _, v := range l.Labels {
+				switch l.Name {
+				case "srcs":
+					jr.Srcs = append(jr.Srcs, v.Value)
+				case "deps":
+					jr.Deps = append(jr.Deps, v.Value)
+				case "exports":
+					jr.Exports = append(jr.Exports, v.Value)
+				}
+			}
+		}
+		rules = append(rules, jr)
+	}
+	return rules, nil
+}
+
+// JavaRules returns the JVM rules matching a target pattern, such as //...
+// or //ui/web:web
+func JavaRules(pattern string, workdir string) []JavaRule {
+	prms := []string{
+		"bazel",
+		"query",
+		"kind('java_library|java_binary|java_test|kt_jvm_library', " +
+			pattern + ")",
+		"--output=xml",
+	}
+	buf, err := Query(prms, workdir)
+	if err != nil {
+		log.Printf("cannot query rules of %s: %v\n", pattern, err)
+		return nil
+	}
+	rules, err := ParseRules(bytes.NewReader(buf))
+	if err != nil {
+		log.Printf("cannot parse rules of %s: %v\n", pattern, err)
+	}
+	return rules
+}
+
+// Libraries returns the java_library rules among deps and their exports, by
+// label
+func Libraries(deps []string, workspace string) map[string]JavaRule {
+	libraries := make(map[string]JavaRule)
+	if len(deps) == 0 {
+		return libraries
+	}
+	set := strings.Join(deps, " + ")
+	prms := []string{"bazel", "query",
+		"kind(java_library, " + set + " + labels(exports, " + set + "))",
+		"--output=xml"}
+	buf, err := Query(prms, workspace)
+	if err != nil {
+		log.Printf("cannot query deps: %v\n", err)
+		return libraries
+	}
+	rules, err := ParseRules(bytes.NewReader(buf))
+	if err != nil {
+		log.Printf("cannot parse deps: %v\n", err)
+	}
+	for _, r := range rules {
+		libraries[r.Label] = r
+	}
+	return libraries
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+	"github.com/jhinrichsen/bazel-kaizen/parser"
+)
+
+// 100k classes in 1000 dependencies
+func syntheticDependencies() []index.Dependency {
+	const (
+		ndeps    = 1000
+		nclasses = 100
+	)
+	deps := make([]index.Dependency, ndeps)
+	for i := range deps {
+		deps[i].Name = fmt.Sprintf("//external:dep%d", i)
+		deps[i].ExternalReference = fmt.Sprintf("dep%d.jar", i)
+		var cs []string
+		for j := 0; j < nclasses; j++ {
+			cs = append(cs,
+				fmt.Sprintf("org.dep%d.pkg%d.Class%d", i, j%10, j))
+		}
+		deps[i].Resources = index.Resources(cs, nil)
+	}
+	return deps
+}
+
+// build log of about 10k lines
+func syntheticLog() string {
+	var sb strings.Builder
+	sb.WriteString("ERROR: /ws/BUILD:1:1: Building libui_web.jar " +
+		"(1 source file) failed (Exit 1)\n")
+	for i := 0; i < 3333; i++ {
+		fmt.Fprintf(&sb, "ui/web/src/main/java/ui/Fx.java:%d: error: "+
+			"package org.dep%d.pkg0 does not exist\n", i, i)
+		fmt.Fprintf(&sb, "import org.dep%d.pkg0.Class0;\n", i)
+		sb.WriteString("                ^\n")
+	}
+	return sb.String()
+}
+
+func quiet(b *testing.B) {
+	log.SetOutput(ioutil.Discard)
+	b.Cleanup(func() {
+		log.SetOutput(os.Stderr)
+	})
+}
+
+func BenchmarkFindClass(b *testing.B) {
+	quiet(b)
+	deps := syntheticDependencies()
+	// worst case, last class of the last dependency
+	j := index.JavaClass{Name: "org.dep999.pkg9.Class99"}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if d, _ := index.FindClass(j, deps); d == nil {
+			b.Fatalf("want provider for %s\n", j.Name)
+		}
+	}
+}
+
+func BenchmarkCacheLoad(b *testing.B) {
+	quiet(b)
+	filename := filepath.Join(b.TempDir(), ".healdb")
+	err := index.UpdateCache(filename,
+		index.Cache{Dependencies: syntheticDependencies()})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		index.ReadCache(filename)
+	}
+}
+
+func BenchmarkProblems(b *testing.B) {
+	quiet(b)
+	s := syntheticLog()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		parser.Problems(*bufio.NewScanner(strings.NewReader(s)))
+	}
+}
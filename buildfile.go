@@ -0,0 +1,116 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	// a name attribute of a literal string, as most rules and macros have
+	REName    = regexp.MustCompile(`\bname\s*=\s*(?:"([^"]+)"|'([^']+)')`)
+	REComment = regexp.MustCompile(`(?m)#.*$`)
+	// a call of a BUILD file, such as java_library(
+	RETopLevelCall = regexp.MustCompile(
+		`(?m)^([A-Za-z_][A-Za-z0-9_]*)\s*\(`)
+)
+
+// rule kinds creating a rule of their name attribute. Macros may create
+// rules of any other name, or none.
+var nativeKinds = map[string]bool{
+	"alias":                   true,
+	"android_binary":          true,
+	"android_library":         true,
+	"filegroup":               true,
+	"genrule":                 true,
+	"java_binary":             true,
+	"java_import":             true,
+	"java_library":            true,
+	"java_lite_proto_library": true,
+	"java_plugin":             true,
+	"java_proto_library":      true,
+	"java_test":               true,
+	"kt_jvm_binary":           true,
+	"kt_jvm_import":           true,
+	"kt_jvm_library":          true,
+	"kt_jvm_test":             true,
+	"proto_library":           true,
+	"scala_binary":            true,
+	"scala_library":           true,
+	"scala_test":              true,
+	"sh_binary":               true,
+	"sh_library":              true,
+	"sh_test":                 true,
+	"test_suite":              true,
+}
+
+// arguments of a call, from its opening parenthesis at i to the matching
+// closing one. Parentheses in strings don't count.
+func callArgs(s string, i int) (string, bool) {
+	depth := 0
+	var quote byte
+	for j := i; j < len(s); j++ {
+		c := s[j]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				j++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return s[i+1 : j], true
+			}
+		}
+	}
+	return "", false
+}
+
+// whether the BUILD file of its package declares a rule of the main
+// repository, without a round trip to bazel: a rule of a native kind, or of
+// a kind of attributes, -deps-attributes. Only bazel knows about external
+// repositories, missing BUILD files, or names computed by macros.
+func declared(workspace string, label string,
+	attributes map[string]string) bool {
+	l := strings.TrimPrefix(label, "@")
+	if !strings.HasPrefix(l, "//") {
+		return false
+	}
+	pkg, name := strings.TrimPrefix(l, "//"), ""
+	if i := strings.Index(pkg, ":"); i >= 0 {
+		pkg, name = pkg[:i], pkg[i+1:]
+	} else {
+		name = filepath.Base(pkg)
+	}
+	if strings.HasSuffix(pkg, "...") || name == "all" || name == "*" {
+		return false
+	}
+	buf, err := ioutil.ReadFile(filepath.Join(workspace,
+		buildFile(workspace, pkg)))
+	if err != nil {
+		return false
+	}
+	build := REComment.ReplaceAllString(string(buf), "")
+	for _, m := range RETopLevelCall.FindAllStringSubmatchIndex(build, -1) {
+		kind := build[m[2]:m[3]]
+		if _, ok := attributes[kind]; !ok && !nativeKinds[kind] {
+			continue
+		}
+		args, ok := callArgs(build, m[1]-1)
+		if !ok {
+			continue
+		}
+		n := REName.FindStringSubmatch(args)
+		if n != nil && (n[1] == name || n[2] == name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDeclared(t *testing.T) {
+	ws := t.TempDir()
+	fixtureFiles(t, ws, map[string]string{
+		"BUILD": "java_library(name = \"root\")\n",
+		"a/b/BUILD.bazel": "load(\"//:defs.bzl\", \"service\")\n\n" +
+			"java_library(\n    name = \"lib\",\n)\n\n" +
+			"service(\n    name = 'b',\n)\n\n" +
+			"java_library(\n    name = \"c\",\n    srcs = glob(" +
+			"[\"(*.java\"]),\n    tags = [\")\"],\n)\n\n" +
+			"# java_library(name = \"commented\")\n",
+	})
+	for _, tt := range []struct {
+		label      string
+		attributes map[string]string
+		want       bool
+	}{
+		{"//:root", nil, true},
+		{"//a/b:lib", nil, true},
+		{"//a/b:c", nil, true},
+		// macros may name their rules otherwise
+		{"//a/b", depsAttributes, false},
+		{"//a/b", map[string]string{"service": "libs"}, true},
+		{"@//a/b:lib", nil, true},
+		{"//a/b:commented", nil, false},
+		{"//a/b:missing", nil, false},
+		{"//a/c:c", nil, false},
+		{"@maven//:lib", nil, false},
+		{"//a/...", nil, false},
+	} {
+		got := declared(ws, tt.label, tt.attributes)
+		if tt.want != got {
+			t.Fatalf("%s: want %v but got %v\n", tt.label, tt.want,
+				got)
+		}
+	}
+}
+
+func TestExistsReadsBuildFile(t *testing.T) {
+	calls := filepath.Join(t.TempDir(), "calls")
+	fakeTools(t, `echo "$@" >> `+calls+"\nexit 7\n", "exit 0\n")
+	ws := t.TempDir()
+	fixtureFiles(t, ws, map[string]string{
+		"a/BUILD": "java_library(name = \"a\")\n",
+	})
+	rules := &Rules{workspace: ws, kinds: make(map[string]string),
+		asked: make(map[string]bool)}
+	if !rules.Exists("//a:a") {
+		t.Fatalf("want //a:a\n")
+	}
+	if canRead(calls) {
+		t.Fatalf("want no bazel query for a declared rule\n")
+	}
+	if rules.Exists("//a:generated") {
+		t.Fatalf("want no //a:generated\n")
+	}
+	buf, err := ioutil.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(buf), "//a:generated") {
+		t.Fatalf("want query for //a:generated but got %s\n", buf)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// start of a maven.install tag, its arguments end at the balanced
+	// closing parenthesis
+	REMavenInstall = regexp.MustCompile(`\bmaven\.install\s*\(`)
+	RENameAttr     = regexp.MustCompile(`\bname\s*=\s*"([^"]+)"`)
+	RELockFile     = regexp.MustCompile(`\block_file\s*=\s*"([^"]+)"`)
+)
+
+// bzlmod workspaces without WORKSPACE file have no //external package, and
+// no maven_jar
+func bzlmodOnly(workspace string) bool {
+	return canRead(filepath.Join(workspace, "MODULE.bazel")) &&
+		!canRead(filepath.Join(workspace, "WORKSPACE")) &&
+		!canRead(filepath.Join(workspace, "WORKSPACE.bazel"))
+}
+
+// file of a label in the main repository, such as third_party/deps.json for
+// //third_party:deps.json
+func labelFile(label string) string {
+	l := strings.TrimLeft(label, "@")
+	l = strings.TrimPrefix(strings.TrimPrefix(l, "//"), ":")
+	return filepath.FromSlash(strings.Replace(l, ":", "/", 1))
+}
+
+// mavenInstall is a maven.install tag of MODULE.bazel
+type mavenInstall struct {
+	repo  string // maven unless given a name
+	named bool
+	lock  string // file of lock_file, if any
+	line  int
+}
+
+// maven.install tags of MODULE.bazel, in order
+func mavenInstalls(module string) []mavenInstall {
+	var mis []mavenInstall
+	for _, m := range REMavenInstall.FindAllStringIndex(module, -1) {
+		args, ok := callArgs(module, m[1]-1)
+		if !ok {
+			continue
+		}
+		mi := mavenInstall{repo: "maven",
+			line: strings.Count(module[:m[0]], "\n") + 1}
+		if n := RENameAttr.FindStringSubmatch(args); n != nil {
+			mi.repo, mi.named = n[1], true
+		}
+		if l := RELockFile.FindStringSubmatch(args); l != nil {
+			mi.lock = labelFile(l[1])
+		}
+		mis = append(mis, mi)
+	}
+	return mis
+}
+
+// lock files of the maven repositories of MODULE.bazel, by the lock_file of
+// their maven.install
+func moduleLockFiles(module string) map[string]string {
+	locks := make(map[string]string)
+	for _, mi := range mavenInstalls(module) {
+		if mi.lock != "" {
+			locks[mi.repo] = mi.lock
+		}
+	}
+	return locks
+}
+
+// MavenLock is a maven_install lock file of a repository
+type MavenLock struct {
+	Repo string
+	File string // absolute
+}
+
+// repositories of maven.install tags of MODULE.bazel without lock file
+func unpinnedRepos(workspace string, locks []MavenLock) []string {
+	buf, err := ioutil.ReadFile(filepath.Join(workspace, "MODULE.bazel"))
+	if err != nil {
+		return nil
+	}
+	pinned := make(map[string]bool)
+	for _, l := range locks {
+		pinned[l.Repo] = true
+	}
+	var repos []string
+	for _, mi := range mavenInstalls(string(buf)) {
+		if !pinned[mi.repo] {
+			repos = append(repos, mi.repo)
+		}
+	}
+	return repos
+}
+
+// lock files of a workspace: <repository>_install.json in its root, and
+// those MODULE.bazel names, in order of repository
+func lockFiles(workspace string) []MavenLock {
+	locks := make(map[string]string)
+	files, _ := filepath.Glob(filepath.Join(workspace, "*_install.json"))
+	for _, f := range files {
+		locks[strings.TrimSuffix(filepath.Base(f), "_install.json")] = f
+	}
+	buf, err := ioutil.ReadFile(filepath.Join(workspace, "MODULE.bazel"))
+	if err == nil {
+		for repo, f := range moduleLockFiles(string(buf)) {
+			locks[repo] = filepath.Join(workspace, f)
+		}
+	}
+	var ls []MavenLock
+	for repo, f := range locks {
+		ls = append(ls, MavenLock{repo, f})
+	}
+	sort.Slice(ls, func(i, j int) bool { return ls[i].Repo < ls[j].Repo })
+	return ls
+}
+
+// directory of an external repository below output_base. Repositories of
+// bzlmod module extensions have canonical names, such as
+// rules_jvm_external~~maven~maven (Bazel 7) or rules_jvm_external++maven+maven
+// (Bazel 8).
+func repoDir(base string, repo string) string {
+	plain := filepath.Join(base, "external", repo)
+	if canRead(plain) {
+		return plain
+	}
+	dirs, _ := filepath.Glob(filepath.Join(base, "external", "*"+repo))
+	for _, d := range dirs {
+		prefix := strings.TrimSuffix(filepath.Base(d), repo)
+		if strings.HasSuffix(prefix, "~") || strings.HasSuffix(prefix, "+") {
+			return d
+		}
+	}
+	return plain
+}
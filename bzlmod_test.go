@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+func TestLockFiles(t *testing.T) {
+	ws := t.TempDir()
+	fixtureFiles(t, ws, map[string]string{
+		"MODULE.bazel": `bazel_dep(name = "rules_jvm_external", version = "6.5")
+maven = use_extension("@rules_jvm_external//:extensions.bzl", "maven")
+maven.install(
+    artifacts = ["junit:junit:4.13.2"],
+    lock_file = "//third_party:maven_install.json",
+)
+maven.install(
+    name = "tools",
+    artifacts = ["org.x:x:1.0"],
+)
+maven.install(
+    name = "web",
+    artifacts = [maven.artifact("org.y", "y", "1.0")],
+    lock_file = "//:web_install.json",
+)
+maven.install(
+    name = "unpinned",
+    artifacts = ["org.z:z:1.0"],
+)
+use_repo(maven, "maven", "tools", "web", "unpinned")
+`,
+		"tools_install.json": "{}",
+	})
+	if !bzlmodOnly(ws) {
+		t.Fatalf("want bzlmod workspace\n")
+	}
+	want := []MavenLock{
+		{"maven", filepath.Join(ws, "third_party/maven_install.json")},
+		{"tools", filepath.Join(ws, "tools_install.json")},
+		{"web", filepath.Join(ws, "web_install.json")},
+	}
+	got := lockFiles(ws)
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("want %+v but got %+v\n", want, got)
+	}
+	unpinned := unpinnedRepos(ws, got)
+	if len(unpinned) != 1 || unpinned[0] != "unpinned" {
+		t.Fatalf("want unpinned repository but got %q\n", unpinned)
+	}
+	fixtureFiles(t, ws, map[string]string{"WORKSPACE": ""})
+	if bzlmodOnly(ws) {
+		t.Fatalf("want hybrid workspace\n")
+	}
+}
+
+func TestBzlmodArtifacts(t *testing.T) {
+	ws, base := t.TempDir(), t.TempDir()
+	fakeTools(t, `case "$*" in *info*) echo `+base+`;; esac
+`, "exit 0\n")
+	fixtureFiles(t, ws, map[string]string{
+		"MODULE.bazel": "maven.install(\n" +
+			"    lock_file = \"//:maven_install.json\",\n)\n",
+		"maven_install.json": `{"artifacts": {"com.google.guava:guava": ` +
+			`{"version": "31.1-jre"}}, "packages": {` +
+			`"com.google.guava:guava": ["com.google.common.base"]}}`,
+	})
+	for _, dir := range []string{"rules_jvm_external~~maven~maven",
+		"rules_jvm_external++maven+maven"} {
+		repodir := filepath.Join(base, "external", dir)
+		if got := repoDir(base, "maven"); got == repodir {
+			t.Fatalf("want no %s before it exists\n", dir)
+		}
+		jar := filepath.Join(repodir, "v1/https/repo1.maven.org/maven2/"+
+			"com/google/guava/guava/31.1-jre/guava-31.1-jre.jar")
+		fixtureJar(t, jar, "com.google.common.base.Optional")
+		deps, err := mavenInstallDependencies(ws, 100, false, nil, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deps) != 1 || deps[0].ExternalReference != jar ||
+			!deps[0].Provides(index.Class,
+				"com.google.common.base.Optional") {
+			t.Fatalf("want guava in %s but got %+v\n", dir, deps)
+		}
+		if err := os.RemoveAll(filepath.Join(base, "external")); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
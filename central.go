@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+// search API of Maven Central, fc: finds artifacts by fully qualified class
+var centralURL = "https://search.maven.org/solrsearch/select"
+
+// response of a class search, artifacts in order of relevance
+type centralResponse struct {
+	Response struct {
+		Docs []struct {
+			Group    string `json:"g"`
+			Artifact string `json:"a"`
+			Version  string `json:"v"`
+		} `json:"docs"`
+	} `json:"response"`
+}
+
+// artifacts of Maven Central having a class, as group:artifact:version,
+// one version per artifact
+func searchCentral(class string) ([]string, error) {
+	q := url.Values{}
+	q.Set("q", fmt.Sprintf("fc:%q", class))
+	q.Set("rows", "20")
+	q.Set("wt", "json")
+	client := http.Client{Timeout: 10 * time.Second}
+	res, err := client.Get(centralURL + "?" + q.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("searching %s: %s", class, res.Status)
+	}
+	var cr centralResponse
+	if err := json.NewDecoder(res.Body).Decode(&cr); err != nil {
+		return nil, fmt.Errorf("searching %s: %v", class, err)
+	}
+	seen := make(map[string]bool)
+	var artifacts []string
+	for _, d := range cr.Response.Docs {
+		ga := d.Group + ":" + d.Artifact
+		if seen[ga] || d.Version == "" {
+			continue
+		}
+		seen[ga] = true
+		artifacts = append(artifacts, ga+":"+d.Version)
+	}
+	return artifacts, nil
+}
+
+// centralProvider asks Maven Central for classes no other provider knows,
+// -online. Artifacts found need declaring in the workspace, as for
+// wellKnownProvider.
+type centralProvider struct {
+	workspace string
+}
+
+func (a centralProvider) Lookup(j index.JavaClass) []Suggestion {
+	artifacts, err := searchCentral(j.Name)
+	if err != nil {
+		log.Printf("cannot search Maven Central: %v\n", err)
+		return nil
+	}
+	if len(artifacts) == 0 {
+		log.Printf("not on Maven Central\n")
+		return nil
+	}
+	if len(artifacts) > 1 {
+		log.Printf("class %s is in %d artifacts on Maven Central, "+
+			"picking the most relevant of %v\n", j.Name,
+			len(artifacts), artifacts)
+	}
+	label, stanza, es, ok := declare(a.workspace, artifacts[0])
+	if !ok {
+		log.Printf("class %s is in %s on Maven Central, which "+
+			"buildozer cannot declare, add %s\n", j.Name,
+			artifacts[0], stanza)
+		return nil
+	}
+	log.Printf("class %s is in %s on Maven Central, declare it in %s\n",
+		j.Name, artifacts[0], stanza)
+	return []Suggestion{{Dep: label, Provider: "central",
+		Action: AddArtifact, Confidence: index.Low, Edits: es,
+		Reason: fmt.Sprintf("class %s is in %s on Maven Central, which "+
+			"the workspace does not declare yet", j.Name, artifacts[0]),
+		Evidence: []string{stanza, fmt.Sprintf("search: %s?q=fc:%q",
+			centralURL, j.Name)}}}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+func TestCentralProvider(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		if r.URL.Query().Get("q") != `fc:"org.x.Widget"` {
+			fmt.Fprint(w, `{"response":{"numFound":0,"docs":[]}}`)
+			return
+		}
+		fmt.Fprint(w, `{"response":{"numFound":3,"docs":[
+{"id":"org.x:widgets:2.0","g":"org.x","a":"widgets","v":"2.0"},
+{"id":"org.x:widgets:1.0","g":"org.x","a":"widgets","v":"1.0"},
+{"id":"org.y:shaded:1.0","g":"org.y","a":"shaded","v":"1.0"}]}}`)
+	}))
+	defer ts.Close()
+	defer func(u string) { centralURL = u }(centralURL)
+	centralURL = ts.URL
+
+	artifacts, err := searchCentral("org.x.Widget")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"org.x:widgets:2.0", "org.y:shaded:1.0"}
+	if fmt.Sprint(want) != fmt.Sprint(artifacts) {
+		t.Fatalf("want %v but got %v\n", want, artifacts)
+	}
+
+	ws := t.TempDir()
+	fixtureFiles(t, ws, map[string]string{"WORKSPACE": "maven_install(\n"})
+	ss := centralProvider{ws}.Lookup(index.JavaClass{Name: "org.x.Widget"})
+	if len(ss) != 1 || ss[0].Dep != "@maven//:org_x_widgets" ||
+		ss[0].Provider != "central" ||
+		ss[0].Evidence[0] != `WORKSPACE: maven_install artifacts += `+
+			`["org.x:widgets:2.0"]` {
+		t.Fatalf("want @maven//:org_x_widgets but got %+v\n", ss)
+	}
+	if ss := (centralProvider{ws}).Lookup(
+		index.JavaClass{Name: "org.x.Unknown"}); len(ss) != 0 {
+		t.Fatalf("want no suggestion but got %+v\n", ss)
+	}
+}
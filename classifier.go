@@ -0,0 +1,76 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// Classifiers select the jar to index among its siblings in a repository,
+// such as guava-31.1-jre-sources.jar next to guava-31.1-jre.jar
+type Classifiers struct {
+	Exclude []string // never indexed, such as sources and javadoc
+	Prefer  []string // preferred over the plain jar, in order
+}
+
+// classifiers of -exclude-classifiers and -prefer-classifiers
+func parseClassifiers(exclude string, prefer string) Classifiers {
+	return Classifiers{split(exclude), split(prefer)}
+}
+
+// values of a comma separated flag, nil if empty
+func split(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// classifier of a jar, "" for the plain one. Configured classifiers are
+// recognized by suffix, all others by a plain sibling jar.
+func (a Classifiers) classifier(jar string, jars []string) string {
+	for _, c := range append(append([]string{}, a.Exclude...),
+		a.Prefer...) {
+		if strings.HasSuffix(jar, "-"+c+".jar") {
+			return c
+		}
+	}
+	base := strings.TrimSuffix(jar, ".jar")
+	for _, j := range jars {
+		b := strings.TrimSuffix(j, ".jar")
+		if j != jar && strings.HasPrefix(base, b+"-") {
+			return strings.TrimPrefix(base, b+"-")
+		}
+	}
+	return ""
+}
+
+// jars not excluded, best first: preferred classifiers, the plain jar, and
+// any other classifier, ties broken by name
+func (a Classifiers) rank(jars []string) []string {
+	order := func(j string) int {
+		c := a.classifier(j, jars)
+		for i, p := range a.Prefer {
+			if p == c {
+				return i
+			}
+		}
+		if c == "" {
+			return len(a.Prefer)
+		}
+		return len(a.Prefer) + 1
+	}
+	var candidates []string
+	for _, j := range jars {
+		if !contains(a.Exclude, a.classifier(j, jars)) {
+			candidates = append(candidates, j)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		oi, oj := order(candidates[i]), order(candidates[j])
+		if oi != oj {
+			return oi < oj
+		}
+		return candidates[i] < candidates[j]
+	})
+	return candidates
+}
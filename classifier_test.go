@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestClassifiersRank(t *testing.T) {
+	jars := []string{"a-1.0-tests.jar", "a-1.0-shaded.jar", "a-1.0.jar",
+		"a-1.0-sources.jar", "a-1.0-jdk8.jar"}
+	for _, tt := range []struct {
+		cs   Classifiers
+		want string
+	}{
+		{parseClassifiers("sources,javadoc,tests", ""),
+			"[a-1.0.jar a-1.0-jdk8.jar a-1.0-shaded.jar]"},
+		{parseClassifiers("sources", "jdk8,shaded"),
+			"[a-1.0-jdk8.jar a-1.0-shaded.jar a-1.0.jar a-1.0-tests.jar]"},
+		{parseClassifiers("", ""),
+			"[a-1.0.jar a-1.0-jdk8.jar a-1.0-shaded.jar " +
+				"a-1.0-sources.jar a-1.0-tests.jar]"},
+	} {
+		got := fmt.Sprint(tt.cs.rank(jars))
+		if tt.want != got {
+			t.Fatalf("%+v: want %s but got %s\n", tt.cs, tt.want, got)
+		}
+	}
+}
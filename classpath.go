@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+// reduce a jar to its artifact name, so that interface and header jars
+// match the jars they were derived from
+func jarBase(jar string) string {
+	b := strings.TrimSuffix(path.Base(filepath.ToSlash(jar)), ".jar")
+	for _, suffix := range []string{"-ijar", "-hjar"} {
+		b = strings.TrimSuffix(b, suffix)
+	}
+	return strings.TrimPrefix(b, "header_")
+}
+
+// whether the jar of a dependency is on a classpath
+func onClasspath(d index.Dependency, cp []string) bool {
+	want := jarBase(d.ExternalReference)
+	if !strings.HasSuffix(d.ExternalReference, ".jar") {
+		// source modules compile into lib<name>.jar
+		want = "lib" + path.Base(strings.Replace(d.Name, ":", "/", -1))
+	}
+	for _, jar := range cp {
+		if jarBase(jar) == want {
+			return true
+		}
+	}
+	return false
+}
+
+// explain why a dependency fixes a missing class, in terms of the classpath of
+// the failing action if known
+func explain(j index.JavaClass, d index.Dependency, rule string,
+	cp []string) string {
+	provider := d.Name
+	if strings.HasSuffix(d.ExternalReference, ".jar") {
+		provider = fmt.Sprintf("%s (%s)", d.Name,
+			path.Base(filepath.ToSlash(d.ExternalReference)))
+	}
+	switch {
+	case len(cp) == 0:
+		// no --verbose_failures
+		return fmt.Sprintf("class %s is in %s", j.Name, provider)
+	case onClasspath(d, cp):
+		return fmt.Sprintf("class %s is in %s which already is on "+
+			"%s's compile classpath, the failure may have "+
+			"another cause", j.Name, provider, rule)
+	}
+	return fmt.Sprintf("class %s is in %s which is not on %s's compile "+
+		"classpath", j.Name, provider, rule)
+}
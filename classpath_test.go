@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+func TestOnClasspath(t *testing.T) {
+	cp := []string{
+		"bazel-out/k8-fastbuild/bin/api/libapi-hjar.jar",
+		"bazel-out/k8-fastbuild/bin/external/guava/jar/_ijar/jar/" +
+			"external/guava/jar/guava-20.0-ijar.jar",
+	}
+	guava := index.Dependency{
+		Name:              "//external:guava",
+		ExternalReference: "/ob/external/guava/jar/guava-20.0.jar",
+	}
+	if !onClasspath(guava, cp) {
+		t.Fatalf("want guava on classpath\n")
+	}
+	if !onClasspath(index.Dependency{Name: "//api:api"}, cp) {
+		t.Fatalf("want //api:api on classpath\n")
+	}
+	if onClasspath(index.Dependency{Name: "ui_common",
+		ExternalReference: "ui/common/src/main/java/"}, cp) {
+		t.Fatalf("want ui_common not on classpath\n")
+	}
+}
+
+func TestExplain(t *testing.T) {
+	j := index.JavaClass{Name: "com.google.common.base.Optional"}
+	d := index.Dependency{
+		Name:              "//external:guava",
+		ExternalReference: "/ob/external/guava/jar/guava-32.jar",
+	}
+	want := "class com.google.common.base.Optional is in " +
+		"//external:guava (guava-32.jar) which is not on //a:b's " +
+		"compile classpath"
+	got := explain(j, d, "//a:b", []string{"liba-hjar.jar"})
+	if want != got {
+		t.Fatalf("want %s but got %s\n", want, got)
+	}
+}
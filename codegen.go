@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Codegen maps Java packages below a prefix to the label of the rule
+// generating them, such as openapi-generator genrules for REST clients
+type Codegen struct {
+	Prefix string
+	Label  *template.Template
+}
+
+// CodegenPackage is the data of codegen label templates
+type CodegenPackage struct {
+	Package string // Java package, such as com.company.rest.billing
+	Name    string // Java package as rule name, com_company_rest_billing
+	Segment string // last segment of the Java package, billing
+}
+
+// parse comma separated prefix=template pairs, such as
+// com.company.rest=//rest:{{.Segment}}_client. A prefix may end in .*, such as
+// com.corp.db.generated.*=//db:jooq. Longer prefixes come first.
+func parseCodegens(s string) ([]Codegen, error) {
+	var cs []Codegen
+	for _, pair := range strings.Split(s, ",") {
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("want package=label but got %q",
+				pair)
+		}
+		t, err := template.New(kv[0]).Parse(kv[1])
+		if err != nil {
+			return nil, err
+		}
+		cs = append(cs, Codegen{strings.TrimSuffix(kv[0], ".*"), t})
+	}
+	sort.SliceStable(cs, func(i, j int) bool {
+		return len(cs[i].Prefix) > len(cs[j].Prefix)
+	})
+	return cs, nil
+}
+
+// label of the rule generating a Java package, false if no prefix matches
+func codegenLabel(cs []Codegen, javaPackage string) (string, bool) {
+	for _, c := range cs {
+		if javaPackage != c.Prefix &&
+			!strings.HasPrefix(javaPackage, c.Prefix+".") {
+			continue
+		}
+		p := CodegenPackage{
+			Package: javaPackage,
+			Name:    strings.Replace(javaPackage, ".", "_", -1),
+			Segment: javaPackage[strings.LastIndex(javaPackage, ".")+1:],
+		}
+		var buf bytes.Buffer
+		if err := c.Label.Execute(&buf, p); err != nil {
+			return "", false
+		}
+		return buf.String(), true
+	}
+	return "", false
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCodegenLabel(t *testing.T) {
+	cs, err := parseCodegens("com.company.rest=//rest:{{.Segment}}_client," +
+		"com.company.rest.legacy=//legacy:{{.Name}}," +
+		"com.corp.db.generated.*=//db:jooq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for pkg, want := range map[string]string{
+		"com.company.rest.billing":       "//rest:billing_client",
+		"com.company.rest.legacy.orders": "//legacy:com_company_rest_legacy_orders",
+		"com.company.restful":            "",
+		"org.other":                      "",
+		"com.corp.db.generated.tables":   "//db:jooq",
+	} {
+		got, ok := codegenLabel(cs, pkg)
+		if want != got || ok != (want != "") {
+			t.Fatalf("%s: want %q but got %q\n", pkg, want, got)
+		}
+	}
+	if _, err := parseCodegens("com.company.rest"); err == nil {
+		t.Fatalf("want error for missing label\n")
+	}
+}
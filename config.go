@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// configFile is read from the workspace unless -config names another one
+const configFile = ".kaizen.yaml"
+
+// flags a config cannot set, as they locate the config itself
+var unconfigurable = []string{"config", "workspace"}
+
+// parse the YAML subset of a config into flag values by flag name. A
+// setting is a scalar, a list becoming comma separated values, or a map
+// becoming comma separated key=value pairs, as the flags take them:
+//
+//	cachefile: .cache/healdb
+//	providers: [srcs, index]
+//	source-layouts:
+//	  - src/main/java
+//	  - java
+//	default-attributes:
+//	  visibility: //visibility:public
+func parseConfig(r io.Reader) (map[string]string, error) {
+	config := make(map[string]string)
+	var key string       // setting of the current block
+	var items []string   // list items or map pairs of the block
+	var list, pairs bool // kind of block
+	end := func() {
+		if key != "" && (list || pairs) {
+			config[key] = strings.Join(items, ",")
+		}
+		key, items, list, pairs = "", nil, false, false
+	}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := uncomment(scanner.Text())
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		if strings.HasPrefix(line, "\t") {
+			return nil, fmt.Errorf("line %d: indent with spaces, "+
+				"not tabs", n)
+		}
+		if trimmed == line {
+			end()
+			k, v, ok := splitKey(line)
+			if !ok {
+				return nil, fmt.Errorf("line %d: want setting: "+
+					"value but got %q", n, line)
+			}
+			if _, dup := config[k]; dup {
+				return nil, fmt.Errorf("line %d: %s set twice",
+					n, k)
+			}
+			switch {
+			case v == "":
+				key = k
+			case strings.HasPrefix(v, "["):
+				if !strings.HasSuffix(v, "]") {
+					return nil, fmt.Errorf("line %d: "+
+						"unterminated list", n)
+				}
+				var vs []string
+				for _, s := range strings.Split(v[1:len(v)-1],
+					",") {
+					if s = strings.TrimSpace(s); s != "" {
+						vs = append(vs, unquote(s))
+					}
+				}
+				config[k] = strings.Join(vs, ",")
+			default:
+				config[k] = unquote(v)
+			}
+			continue
+		}
+		if key == "" {
+			return nil, fmt.Errorf("line %d: unexpected indent", n)
+		}
+		if strings.HasPrefix(trimmed, "-") {
+			if pairs {
+				return nil, fmt.Errorf("line %d: list item in "+
+					"map %s", n, key)
+			}
+			list = true
+			items = append(items,
+				unquote(strings.TrimSpace(trimmed[1:])))
+			continue
+		}
+		k, v, ok := splitKey(trimmed)
+		if !ok || list {
+			return nil, fmt.Errorf("line %d: want key: value in "+
+				"map %s but got %q", n, key, trimmed)
+		}
+		pairs = true
+		items = append(items, k+"="+unquote(v))
+	}
+	end()
+	return config, scanner.Err()
+}
+
+// line without its comment, a # at the start or after a blank outside of
+// quotes
+func uncomment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (i == 0 || line[i-1] == ' '):
+			return strings.TrimRight(line[:i], " ")
+		}
+	}
+	return strings.TrimRight(line, " ")
+}
+
+// split key: value, the key possibly quoted as in "@maven//:fat": com.fat
+func splitKey(s string) (string, string, bool) {
+	var k, rest string
+	if strings.HasPrefix(s, `"`) || strings.HasPrefix(s, "'") {
+		i := strings.IndexByte(s[1:], s[0])
+		if i < 0 {
+			return "", "", false
+		}
+		k, rest = s[1:i+1], s[i+2:]
+		if !strings.HasPrefix(rest, ":") {
+			return "", "", false
+		}
+		rest = rest[1:]
+	} else {
+		i := strings.Index(s, ": ")
+		switch {
+		case i > 0:
+			k, rest = s[:i], s[i+1:]
+		case strings.HasSuffix(s, ":"):
+			k = strings.TrimSuffix(s, ":")
+		default:
+			return "", "", false
+		}
+	}
+	if k == "" {
+		return "", "", false
+	}
+	return k, strings.TrimSpace(rest), true
+}
+
+// value without YAML quotes
+func unquote(s string) string {
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return strings.Replace(s[1:len(s)-1], "''", "'", -1)
+	}
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		if u, err := strconv.Unquote(s); err == nil {
+			return u
+		}
+	}
+	return s
+}
+
+// set flags not given on the command line from the config file, the
+// workspace's .kaizen.yaml if name is empty. Only a named config must
+// exist.
+func loadConfig(flags *flag.FlagSet, name string, workspace string) error {
+	if name == "" {
+		name = filepath.Join(workspace, configFile)
+		if _, err := os.Stat(name); os.IsNotExist(err) {
+			return nil
+		}
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	config, err := parseConfig(f)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	if err := applyConfig(flags, config); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
+
+// set flags from a config, the command line wins
+func applyConfig(flags *flag.FlagSet, config map[string]string) error {
+	explicit := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	var names []string
+	for k := range config {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		if flags.Lookup(k) == nil || contains(unconfigurable, k) {
+			return fmt.Errorf("unknown setting %s", k)
+		}
+		if explicit[k] {
+			continue
+		}
+		if err := flags.Set(k, config[k]); err != nil {
+			return fmt.Errorf("%s: %v", k, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const fixtureConfig = `# kaizen settings
+cachefile: .cache/healdb # not the default
+store: true
+providers: [srcs, "index"]
+source-layouts:
+  - src/main/java
+  - java
+package-filter:
+  - "@maven//:fat=com.fat"
+  - "@maven//:fat=!com.fat.shaded"
+default-attributes:
+  visibility: //visibility:public
+  'tags': "['generated']"
+`
+
+func TestParseConfig(t *testing.T) {
+	got, err := parseConfig(strings.NewReader(fixtureConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"cachefile":      ".cache/healdb",
+		"store":          "true",
+		"providers":      "srcs,index",
+		"source-layouts": "src/main/java,java",
+		"package-filter": "@maven//:fat=com.fat," +
+			"@maven//:fat=!com.fat.shaded",
+		"default-attributes": "visibility=//visibility:public," +
+			"tags=['generated']",
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("want %v but got %v\n", want, got)
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	for _, s := range []string{
+		"providers",
+		"  - srcs",
+		"providers: [srcs",
+		"providers:\n\t- srcs",
+		"store: true\nstore: false",
+		"m:\n  a: b\n  - c",
+	} {
+		if _, err := parseConfig(strings.NewReader(s)); err == nil {
+			t.Fatalf("%q: want error\n", s)
+		}
+	}
+}
+
+func TestApplyConfig(t *testing.T) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	cachefile := flags.String("cachefile", ".healdb", "")
+	store := flags.Bool("store", false, "")
+	providers := flags.String("providers", "srcs,index", "")
+	flags.String("workspace", ".", "")
+	if err := flags.Parse([]string{"-providers", "index"}); err != nil {
+		t.Fatal(err)
+	}
+	err := applyConfig(flags, map[string]string{
+		"cachefile": ".cache/healdb",
+		"store":     "true",
+		"providers": "srcs",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *cachefile != ".cache/healdb" || !*store || *providers != "index" {
+		t.Fatalf("want config below command line but got %s %t %s\n",
+			*cachefile, *store, *providers)
+	}
+	for _, k := range []string{"workspace", "unknown"} {
+		err := applyConfig(flags, map[string]string{k: "x"})
+		if err == nil {
+			t.Fatalf("%s: want error\n", k)
+		}
+	}
+	flags.Int("jobs", 1, "")
+	err = applyConfig(flags, map[string]string{"jobs": "x"})
+	if err == nil {
+		t.Fatalf("want error for bad value\n")
+	}
+}
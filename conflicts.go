@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+// Conflict describes two external dependencies that carry classes of the same
+// Java packages, such as guava and guava-jdk5
+type Conflict struct {
+	Keep     index.Dependency
+	Exclude  index.Dependency
+	Packages []string
+}
+
+// set of Java packages provided by a dependency
+func packages(d index.Dependency) map[string]int {
+	m := make(map[string]int)
+	for _, c := range d.Named(index.Class) {
+		m[index.StripLast(c)]++
+	}
+	return m
+}
+
+// find pairs of external dependencies sharing Java packages. The dependency
+// providing more classes in the shared packages is kept.
+func conflicts(deps []index.Dependency) []Conflict {
+	var exts []index.Dependency
+	for _, d := range deps {
+		// tests jars share the packages of their artifact
+		if d.Kind.External() && !d.TestOnly {
+			exts = append(exts, d)
+		}
+	}
+	// deterministic output
+	sort.Slice(exts, func(i, j int) bool {
+		return exts[i].Name < exts[j].Name
+	})
+	pkgs := make([]map[string]int, len(exts))
+	for i, d := range exts {
+		pkgs[i] = packages(d)
+	}
+	var cs []Conflict
+	for i := 0; i < len(exts); i++ {
+		for j := i + 1; j < len(exts); j++ {
+			var shared []string
+			ni, nj := 0, 0
+			for p, n := range pkgs[i] {
+				if m, ok := pkgs[j][p]; ok {
+					shared = append(shared, p)
+					ni += n
+					nj += m
+				}
+			}
+			if len(shared) == 0 {
+				continue
+			}
+			sort.Strings(shared)
+			c := Conflict{exts[i], exts[j], shared}
+			if nj > ni {
+				c.Keep, c.Exclude = exts[j], exts[i]
+			}
+			cs = append(cs, c)
+		}
+	}
+	return cs
+}
+
+// strip version (and classifier) from Maven coordinates
+func groupArtifact(coordinates string) string {
+	parts := strings.Split(coordinates, ":")
+	if len(parts) < 2 {
+		return coordinates
+	}
+	return strings.Join(parts[0:2], ":")
+}
+
+// maven_install label of an artifact, @maven//:group_artifact
+func mavenLabel(coordinates string) string {
+	return repoLabel("maven", coordinates)
+}
+
+// return the artifact spec changes resolving a conflict, either excluding the
+// conflicting artifact or pinning it to the kept one
+func exclusion(c Conflict) []string {
+	keep, exclude := c.Keep.Artifact, c.Exclude.Artifact
+	if keep == "" {
+		keep = c.Keep.Name
+	}
+	if exclude == "" {
+		exclude = c.Exclude.Name
+	}
+	lines := []string{
+		fmt.Sprintf("# %s duplicates %d package(s) of %s: %s",
+			exclude, len(c.Packages), keep,
+			strings.Join(c.Packages, ", ")),
+	}
+	if c.Exclude.Artifact == "" {
+		lines = append(lines,
+			"# unknown Maven coordinates, rerun -update")
+		return lines
+	}
+	lines = append(lines,
+		fmt.Sprintf(`excluded_artifacts = [%q]`,
+			groupArtifact(c.Exclude.Artifact)))
+	if c.Keep.Artifact != "" {
+		keep := mavenLabel(c.Keep.Artifact)
+		if c.Keep.Kind == index.RulesJvmExternal {
+			keep = c.Keep.Name
+		}
+		lines = append(lines,
+			fmt.Sprintf(`override_targets = {%q: %q}`,
+				groupArtifact(c.Exclude.Artifact), keep))
+	}
+	return lines
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+func TestConflicts(t *testing.T) {
+	deps := []index.Dependency{
+		{
+			Name:      "//external:guava_jdk5",
+			Resources: classes("com.google.common.base.Optional"),
+			Artifact:  "com.google.guava:guava-jdk5:17.0",
+			Kind:      index.MavenJar,
+		},
+		{
+			Name: "//external:guava",
+			Resources: classes(
+				"com.google.common.base.Optional",
+				"com.google.common.base.Strings",
+				"com.google.common.collect.Lists",
+			),
+			Artifact: "com.google.guava:guava:20.0",
+			Kind:     index.MavenJar,
+		},
+		{
+			Name:      "ui_web",
+			Resources: classes("com.google.common.base.Local"),
+			Kind:      index.Source,
+		},
+	}
+	cs := conflicts(deps)
+	if len(cs) != 1 {
+		t.Fatalf("want 1 conflict but got %+v\n", cs)
+	}
+	want := "//external:guava_jdk5"
+	got := cs[0].Exclude.Name
+	if want != got {
+		t.Fatalf("want %s but got %s\n", want, got)
+	}
+	lines := exclusion(cs[0])
+	if len(lines) != 3 {
+		t.Fatalf("want 3 lines but got %+v\n", lines)
+	}
+	want = `excluded_artifacts = ["com.google.guava:guava-jdk5"]`
+	if want != lines[1] {
+		t.Fatalf("want %s but got %s\n", want, lines[1])
+	}
+	want = `override_targets = {"com.google.guava:guava-jdk5": ` +
+		`"@maven//:com_google_guava_guava"}`
+	if want != lines[2] {
+		t.Fatalf("want %s but got %s\n", want, lines[2])
+	}
+}
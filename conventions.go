@@ -0,0 +1,121 @@
+package main
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+// Conventions observed in the handwritten BUILD files of a workspace
+type Conventions struct {
+	Naming      string // naming strategy, empty if undecided
+	ShortLabels bool   // //a/b rather than //a/b:b
+}
+
+var (
+	REJavaLibrary = regexp.MustCompile(
+		`java_library\(\s*name\s*=\s*"([^"]+)"`)
+	REQuotedLabel = regexp.MustCompile(`"//([^":]+)(:[^"]*)?"`)
+)
+
+// vote on conventions found in one BUILD file of package pkg
+func observe(pkg string, build string, votes map[string]int) {
+	if pkg == "" {
+		// root package rules carry no information about naming
+		return
+	}
+	for _, m := range REJavaLibrary.FindAllStringSubmatch(build, -1) {
+		switch m[1] {
+		case filepath.Base(pkg):
+			votes["segment"]++
+		case index.RuleName(pkg):
+			votes["path"]++
+		}
+	}
+	for _, m := range REQuotedLabel.FindAllStringSubmatch(build, -1) {
+		switch m[2] {
+		case "":
+			votes["short"]++
+		case ":" + filepath.Base(m[1]):
+			votes["long"]++
+		}
+	}
+}
+
+// infer conventions from all BUILD files of a workspace
+func learn(workspace string) Conventions {
+	votes := make(map[string]int)
+	f := func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() && path != workspace &&
+			(strings.HasPrefix(info.Name(), ".") ||
+				strings.HasPrefix(info.Name(), "bazel-")) {
+			return filepath.SkipDir
+		}
+		if info.Name() != "BUILD" && info.Name() != "BUILD.bazel" {
+			return nil
+		}
+		buf, err := ioutil.ReadFile(path)
+		if err != nil {
+			log.Printf("skipping %s: %v\n", path, err)
+			return nil
+		}
+		pkg, _ := filepath.Rel(workspace, filepath.Dir(path))
+		if pkg == "." {
+			pkg = ""
+		}
+		observe(filepath.ToSlash(pkg), string(buf), votes)
+		return nil
+	}
+	filepath.Walk(workspace, f)
+	log.Printf("observed conventions %+v\n", votes)
+	var c Conventions
+	if votes["segment"] > votes["path"] {
+		c.Naming = "segment"
+	} else if votes["path"] > votes["segment"] {
+		c.Naming = "path"
+	}
+	c.ShortLabels = votes["short"] > votes["long"]
+	return c
+}
+
+// format a label according to conventions
+func (a Conventions) label(l string) string {
+	if !strings.HasPrefix(l, "//") {
+		return l
+	}
+	pkg := edit.LabelPackage(l)
+	if pkg == "" {
+		return l
+	}
+	long := "//" + pkg + ":" + filepath.Base(pkg)
+	if a.ShortLabels && l == long {
+		return "//" + pkg
+	}
+	if !a.ShortLabels && l == "//"+pkg {
+		return long
+	}
+	return l
+}
+
+// format all labels of an edit according to conventions
+func (a Conventions) format(e edit.Edit) edit.Edit {
+	fs := strings.Fields(e.Command)
+	if len(fs) > 2 && (fs[0] == "add" || fs[0] == "remove") &&
+		edit.LabelAttributes[fs[1]] {
+		for i := 2; i < len(fs); i++ {
+			fs[i] = a.label(fs[i])
+		}
+		e.Command = strings.Join(fs, " ")
+	}
+	e.Target = a.label(e.Target)
+	return e
+}
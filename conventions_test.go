@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+)
+
+func TestObserve(t *testing.T) {
+	votes := make(map[string]int)
+	observe("ui/web", `java_library(
+    name = "web",
+    deps = [
+        "//ui/common",
+        "//api:api",
+        "//services/billing",
+    ],
+)`, votes)
+	if votes["segment"] != 1 || votes["path"] != 0 {
+		t.Fatalf("want segment naming but got %+v\n", votes)
+	}
+	if votes["short"] != 2 || votes["long"] != 1 {
+		t.Fatalf("want 2 short and 1 long label but got %+v\n", votes)
+	}
+}
+
+func TestConventionsFormat(t *testing.T) {
+	e := edit.Edit{Command: "add deps //api:api //:ui_web @maven//:guava",
+		Target: "//ui/web:web"}
+	want := edit.Edit{Command: "add deps //api //:ui_web @maven//:guava",
+		Target: "//ui/web"}
+	got := Conventions{ShortLabels: true}.format(e)
+	if want != got {
+		t.Fatalf("want %s but got %s\n", want, got)
+	}
+	got = Conventions{}.format(want)
+	if e != got {
+		t.Fatalf("want %s but got %s\n", e, got)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type diffLine struct {
+	op   byte // ' ', '-', or '+'
+	text string
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// line based diff via longest common subsequence, BUILD files are small
+func diffLines(a, b []string) []diffLine {
+	// lcs[i][j] is the length of the LCS of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var ds []diffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ds = append(ds, diffLine{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ds = append(ds, diffLine{'-', a[i]})
+			i++
+		default:
+			ds = append(ds, diffLine{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ds = append(ds, diffLine{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ds = append(ds, diffLine{'+', b[j]})
+	}
+	return ds
+}
+
+// unified diff of two versions of a file, empty if both are identical
+func unified(name string, before, after string) string {
+	if before == after {
+		return ""
+	}
+	const context = 3
+	ds := diffLines(splitLines(before), splitLines(after))
+
+	// number of lines in before and after preceding each diff line
+	pa := make([]int, len(ds)+1)
+	pb := make([]int, len(ds)+1)
+	for k, d := range ds {
+		pa[k+1], pb[k+1] = pa[k], pb[k]
+		if d.op != '+' {
+			pa[k+1]++
+		}
+		if d.op != '-' {
+			pb[k+1]++
+		}
+	}
+
+	// [start, end) ranges into ds, merging overlapping context
+	var hunks [][2]int
+	for k, d := range ds {
+		if d.op == ' ' {
+			continue
+		}
+		start := k - context
+		if start < 0 {
+			start = 0
+		}
+		end := k + 1 + context
+		if end > len(ds) {
+			end = len(ds)
+		}
+		if len(hunks) > 0 && start <= hunks[len(hunks)-1][1] {
+			hunks[len(hunks)-1][1] = end
+		} else {
+			hunks = append(hunks, [2]int{start, end})
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- a/%s\n+++ b/%s\n", name, name)
+	for _, h := range hunks {
+		na := pa[h[1]] - pa[h[0]]
+		nb := pb[h[1]] - pb[h[0]]
+		sa, sb2 := pa[h[0]], pb[h[0]]
+		if na > 0 {
+			sa++
+		}
+		if nb > 0 {
+			sb2++
+		}
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", sa, na, sb2, nb)
+		for _, d := range ds[h[0]:h[1]] {
+			fmt.Fprintf(&sb, "%c%s\n", d.op, d.text)
+		}
+	}
+	return sb.String()
+}
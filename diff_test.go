@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	before := `java_library(
+    name = "ui",
+    deps = [
+        "//:a",
+    ],
+)
+`
+	after := `java_library(
+    name = "ui",
+    deps = [
+        "//:a",
+        "//:b",
+    ],
+)
+`
+	want := `--- a/BUILD
++++ b/BUILD
+@@ -2,5 +2,6 @@
+     name = "ui",
+     deps = [
+         "//:a",
++        "//:b",
+     ],
+ )
+`
+	got := unified("BUILD", before, after)
+	if want != got {
+		t.Fatalf("want\n%s\nbut got\n%s\n", want, got)
+	}
+}
+
+func TestUnifiedNewFile(t *testing.T) {
+	want := "--- a/BUILD\n+++ b/BUILD\n@@ -0,0 +1,1 @@\n+x\n"
+	got := unified("BUILD", "", "x\n")
+	if want != got {
+		t.Fatalf("want\n%s\nbut got\n%s\n", want, got)
+	}
+}
+
+func TestUnifiedIdentical(t *testing.T) {
+	if got := unified("BUILD", "x\n", "x\n"); got != "" {
+		t.Fatalf("want empty diff but got %s\n", got)
+	}
+}
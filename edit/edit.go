@@ -0,0 +1,208 @@
+// Package edit creates, validates, and prints buildozer commands.
+package edit
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+// Edit is a single buildozer command for a target
+type Edit struct {
+	Command string `json:"command"` // such as 'add deps //:a'
+	Target  string `json:"target"`  // such as '//:b' or '__pkg__'
+}
+
+// String returns the buildozer representation, command and target quoted
+// for the shell
+func (a Edit) String() string {
+	return fmt.Sprintf("buildozer '%s' '%s'", a.Command, a.Target)
+}
+
+// AddDeps adds deps to a rule
+func AddDeps(rule string, deps ...string) Edit {
+	return Edit{
+		fmt.Sprintf("add deps %s", strings.Join(deps, " ")),
+		rule,
+	}
+}
+
+// NewAlias aliases an external artifact in the root package
+func NewAlias(name string, actual string) []Edit {
+	return []Edit{
+		{fmt.Sprintf("new alias %s", name), "__pkg__"},
+		{fmt.Sprintf("set actual %q", actual), name},
+	}
+}
+
+// NewJavaLibrary generates a library from the sources of a module, testonly
+// for test sources
+func NewJavaLibrary(d index.Dependency) []Edit {
+	es := []Edit{
+		{fmt.Sprintf("new java_library %s", d.Name), "__pkg__"},
+		{fmt.Sprintf(`set srcs glob(["%s**/*.java"])`,
+			d.ExternalReference), d.Name},
+	}
+	if d.TestOnly {
+		es = append(es, Edit{"set testonly 1", d.Name})
+	}
+	return es
+}
+
+// NewKotlinLibrary generates a Kotlin library from the Java and Kotlin
+// sources of a module
+func NewKotlinLibrary(d index.Dependency) []Edit {
+	return []Edit{
+		{"new_load @io_bazel_rules_kotlin//kotlin:jvm.bzl kt_jvm_library",
+			"__pkg__"},
+		{fmt.Sprintf("new kt_jvm_library %s", d.Name), "__pkg__"},
+		{fmt.Sprintf(`set srcs glob(["%s**/*.kt","%s**/*.java"])`,
+			d.ExternalReference, d.ExternalReference), d.Name},
+	}
+}
+
+// NewScalaLibrary generates a Scala library from the Scala and Java sources
+// of a module
+func NewScalaLibrary(d index.Dependency) []Edit {
+	return []Edit{
+		{"new_load @io_bazel_rules_scala//scala:scala.bzl scala_library",
+			"__pkg__"},
+		{fmt.Sprintf("new scala_library %s", d.Name), "__pkg__"},
+		{fmt.Sprintf(`set srcs glob(["%s**/*.scala","%s**/*.java"])`,
+			d.ExternalReference, d.ExternalReference), d.Name},
+	}
+}
+
+// NewWrapper generates a wrapper library exporting an external artifact, the
+// package of the label names it
+func NewWrapper(label string, actual string) []Edit {
+	pkg := LabelPackage(label)
+	name := path.Base(pkg)
+	return []Edit{
+		{"new java_library " + name, "//" + pkg + ":__pkg__"},
+		{"add exports " + actual, label},
+		{"add visibility //visibility:public", label},
+	}
+}
+
+// AddData makes a data file available to a test, either directly if the
+// test lives in the owning package, or via a filegroup in the owning package.
+// The filegroup is generated unless exists reports it, such as from an
+// earlier run.
+func AddData(test string, owner string, file string,
+	exists func(label string) bool) []Edit {
+	if LabelPackage(test) == owner {
+		return []Edit{{"add data " + file, test}}
+	}
+	group := index.RuleName(file)
+	label := "//" + owner + ":" + group
+	var es []Edit
+	if !exists(label) {
+		es = append(es, Edit{"new filegroup " + group,
+			"//" + owner + ":__pkg__"})
+	}
+	return append(es,
+		Edit{"add srcs " + file, label},
+		Edit{"add visibility //" + LabelPackage(test) + ":__pkg__",
+			label},
+		Edit{"add data " + label, test})
+}
+
+// NewBazelDep declares a bazel module in MODULE.bazel
+func NewBazelDep(name string, version string) []Edit {
+	return []Edit{
+		{"new bazel_dep " + name, "//MODULE.bazel:__pkg__"},
+		{fmt.Sprintf("set version %q", version),
+			"//MODULE.bazel:" + name},
+	}
+}
+
+// AddArtifact adds Maven coordinates to the artifacts of a maven_install
+// rule or maven.install tag
+func AddArtifact(install string, artifact string) Edit {
+	return Edit{"add artifacts " + artifact, install}
+}
+
+// LabelPackage is the bazel package of a target, relative targets such as
+// __pkg__ or :name refer to the root package of the workspace
+func LabelPackage(target string) string {
+	if !strings.HasPrefix(target, "//") {
+		return ""
+	}
+	s := strings.TrimPrefix(target, "//")
+	if i := strings.Index(s, ":"); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// InPackage moves edits generating a rule into another package: __pkg__ and
+// the rule become labels of the package. Paths in the edits must be
+// relative to it.
+func InPackage(edits []Edit, rule string, pkg string) []Edit {
+	var es []Edit
+	for _, e := range edits {
+		switch e.Target {
+		case "__pkg__":
+			e.Target = "//" + pkg + ":__pkg__"
+		case rule:
+			e.Target = "//" + pkg + ":" + rule
+		}
+		es = append(es, e)
+	}
+	return es
+}
+
+// Dedupe drops repeated edits, keeping the first one
+func Dedupe(edits []Edit) []Edit {
+	seen := make(map[Edit]bool)
+	var es []Edit
+	for _, e := range edits {
+		if !seen[e] {
+			es = append(es, e)
+			seen[e] = true
+		}
+	}
+	return es
+}
+
+// Phases splits edits into those generating rules, and those referring to
+// them.
+// Order within each phase is kept.
+func Phases(edits []Edit) (gen []Edit, rest []Edit) {
+	created := make(map[string]bool)
+	for _, e := range edits {
+		fs := strings.Fields(e.Command)
+		if len(fs) >= 3 && fs[0] == "new" {
+			created[fs[2]] = true
+			// new rule in another package
+			if strings.HasSuffix(e.Target, ":__pkg__") {
+				pkg := strings.TrimSuffix(e.Target, "__pkg__")
+				created[pkg+fs[2]] = true
+			}
+		}
+	}
+	for _, e := range edits {
+		if strings.HasPrefix(e.Command, "new ") ||
+			strings.HasPrefix(e.Command, "new_load ") ||
+			created[e.Target] {
+			gen = append(gen, e)
+		} else {
+			rest = append(rest, e)
+		}
+	}
+	return
+}
+
+// Stdout carries machine-consumable commands only, as it is commonly piped
+// into a shell. Everything meant for humans goes into the log.
+var Stdout io.Writer = os.Stdout
+
+// Emit prints a command on Stdout
+func Emit(s string) {
+	fmt.Fprintln(Stdout, s)
+}
@@ -0,0 +1,35 @@
+package edit
+
+import (
+	"testing"
+)
+
+func TestPhases(t *testing.T) {
+	edits := []Edit{
+		{"add deps //:a", "//ui:ui"},
+		{"add deps //:b", "//ui:ui"},
+		{"new java_library b", "__pkg__"},
+		{"set srcs []", "b"},
+	}
+	gen, rest := Phases(edits)
+	if len(gen) != 2 || gen[0] != edits[2] || gen[1] != edits[3] {
+		t.Fatalf("want generation of b first but got %+v\n", gen)
+	}
+	if len(rest) != 2 || rest[0] != edits[0] || rest[1] != edits[1] {
+		t.Fatalf("want add deps last but got %+v\n", rest)
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	edits := []Edit{
+		{"new java_library b", "__pkg__"},
+		{"add deps //:b", "//ui:ui"},
+		{"new java_library b", "__pkg__"},
+		{"add deps //:b", "//ui:ui"},
+		{"add deps //:b", "//api:api"},
+	}
+	got := Dedupe(edits)
+	if len(got) != 3 || got[2] != edits[4] {
+		t.Fatalf("want 3 distinct edits but got %+v\n", got)
+	}
+}
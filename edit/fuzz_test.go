@@ -0,0 +1,18 @@
+package edit
+
+import (
+	"strings"
+	"testing"
+)
+
+func FuzzValidate(f *testing.F) {
+	f.Add("add deps //:a", "//:b")
+	f.Add(`set srcs glob(["a/**/*.java"])`, "a")
+	f.Add("new java_library a", "__pkg__")
+	f.Fuzz(func(t *testing.T, command, target string) {
+		e := Edit{command, target}
+		if Validate(e) == nil && strings.ContainsAny(command, "'\n") {
+			t.Fatalf("want quotes rejected in %s\n", e)
+		}
+	})
+}
@@ -0,0 +1,145 @@
+package edit
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+)
+
+var (
+	REIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	// target names bazel allows, less the characters the shell sees
+	// in, such as ; $ & ( ) { } | < > * ? [ ] ! #
+	RETargetName = regexp.MustCompile(
+		`^[A-Za-z0-9%@^_+,~.=-][A-Za-z0-9%@^_+,~./=-]*$`)
+	// canonical repository names of bzlmod start with @@
+	RELabel = regexp.MustCompile(
+		`^(@@?[A-Za-z0-9._~+-]*)?//[A-Za-z0-9/._+~-]*` +
+			`(:[A-Za-z0-9%@^_+,~./=-]+)?$`)
+)
+
+// LabelAttributes are the attributes holding labels
+var LabelAttributes = map[string]bool{
+	"data":             true,
+	"deps":             true,
+	"exported_plugins": true,
+	"exports":          true,
+	"plugins":          true,
+	"runtime_deps":     true,
+	"visibility":       true,
+}
+
+// ValidLabel reports whether s is a label or a target name
+func ValidLabel(s string) bool {
+	return RELabel.MatchString(s) ||
+		RETargetName.MatchString(strings.TrimPrefix(s, ":"))
+}
+
+// check that brackets and double quotes of a Starlark expression pair up.
+// Escaped quotes do not end a string.
+func balanced(s string) bool {
+	var stack []rune
+	closing := map[rune]rune{')': '(', ']': '[', '}': '{'}
+	quoted, escaped := false, false
+	for _, r := range s {
+		if quoted {
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\':
+				escaped = true
+			case r == '"':
+				quoted = false
+			}
+			continue
+		}
+		switch r {
+		case '"':
+			quoted = true
+		case '(', '[', '{':
+			stack = append(stack, r)
+		case ')', ']', '}':
+			if len(stack) == 0 || stack[len(stack)-1] != closing[r] {
+				return false
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	return !quoted && len(stack) == 0
+}
+
+// Validate checks a buildozer command before it is printed or applied.
+// buildozer splits commands on whitespace, and commands and targets are
+// printed in single quotes, so neither may appear inside an argument. This
+// is a lexical check of what kaizen generates, not a parse by buildtools:
+// labels and names must be plain, values must pair up their brackets and
+// quotes.
+func Validate(e Edit) error {
+	if strings.ContainsAny(e.Command, "'\n") {
+		return fmt.Errorf("%s: command contains quote or newline", e)
+	}
+	if !ValidLabel(e.Target) {
+		return fmt.Errorf("%s: invalid target %q", e, e.Target)
+	}
+	fs := strings.Fields(e.Command)
+	if len(fs) == 0 {
+		return fmt.Errorf("%s: empty command", e)
+	}
+	switch fs[0] {
+	case "add", "remove", "replace":
+		if len(fs) < 3 || fs[0] == "replace" && len(fs) != 4 {
+			return fmt.Errorf("%s: want attribute and values", e)
+		}
+		if !REIdentifier.MatchString(fs[1]) {
+			return fmt.Errorf("%s: invalid attribute %q", e, fs[1])
+		}
+		if LabelAttributes[fs[1]] {
+			for _, l := range fs[2:] {
+				if !ValidLabel(l) {
+					return fmt.Errorf("%s: invalid label %q",
+						e, l)
+				}
+			}
+		}
+	case "new":
+		if len(fs) != 3 && len(fs) != 5 {
+			return fmt.Errorf("%s: want kind and name", e)
+		}
+		if !REIdentifier.MatchString(fs[1]) {
+			return fmt.Errorf("%s: invalid rule kind %q", e, fs[1])
+		}
+		if !RETargetName.MatchString(fs[2]) ||
+			strings.Contains(fs[2], "/") {
+			return fmt.Errorf("%s: invalid rule name %q", e, fs[2])
+		}
+	case "set", "set_if_absent":
+		if len(fs) < 3 {
+			return fmt.Errorf("%s: want attribute and value", e)
+		}
+		if !REIdentifier.MatchString(fs[1]) {
+			return fmt.Errorf("%s: invalid attribute %q", e, fs[1])
+		}
+		// each value is parsed on its own
+		for _, v := range fs[2:] {
+			if !balanced(v) {
+				return fmt.Errorf("%s: unbalanced value %s",
+					e, v)
+			}
+		}
+	}
+	return nil
+}
+
+// Valid drops edits that would corrupt a BUILD file
+func Valid(edits []Edit) []Edit {
+	var es []Edit
+	for _, e := range edits {
+		if err := Validate(e); err != nil {
+			log.Printf("skipping invalid command: %v\n", err)
+			continue
+		}
+		es = append(es, e)
+	}
+	return es
+}
@@ -0,0 +1,106 @@
+package edit
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+func TestValidateGenerated(t *testing.T) {
+	d := index.Dependency{
+		Name:              "ui_web",
+		ExternalReference: "ui/web/src/main/java/",
+	}
+	edits := append(NewJavaLibrary(d),
+		AddDeps("//ui/web:web", "//:ui_web", "//external:guava"),
+		AddDeps("//:com.company.api", "@maven//:com_google_guava"),
+		Edit{"replace deps //core:gone //core:core", "//app:app"})
+	for _, e := range edits {
+		if err := Validate(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestValidateInvalid(t *testing.T) {
+	for _, e := range []Edit{
+		{"add deps //:it's", "//:a"},
+		{"add deps //:a b:c:d", "//:a"},
+		{"new java_library a/b", "__pkg__"},
+		{`set srcs glob(["my dir/**/*.java"])`, "a"},
+		{`set srcs glob(["a/**/*.java"]`, "a"},
+		{"add deps //:a", "//ui web:a"},
+		{"add deps @@@maven//:a", "//:a"},
+		{"replace deps //core:gone", "//app:app"},
+		{"replace deps //core:gone //core:a:b", "//app:app"},
+		{"", "//:a"},
+	} {
+		if err := Validate(e); err == nil {
+			t.Fatalf("want error for %s\n", e)
+		}
+	}
+}
+
+func TestValidateAlias(t *testing.T) {
+	edits := NewAlias("junit", "@junit//jar")
+	want := Edit{`set actual "@junit//jar"`, "junit"}
+	if edits[1] != want {
+		t.Fatalf("want %s but got %s\n", want, edits[1])
+	}
+	for _, e := range edits {
+		if err := Validate(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// commands are printed for sh, which must hand buildozer the command and
+// target unchanged
+func TestValidateRoundTrip(t *testing.T) {
+	for _, e := range []Edit{
+		{"new bazel_dep rules_jvm_external", "//MODULE.bazel:__pkg__"},
+		{`set version "6.5"`, "//MODULE.bazel:rules_jvm_external"},
+		{"add artifacts org.slf4j:slf4j-api:2.0.13",
+			"//MODULE.bazel:%maven.install"},
+		{"add artifacts org.slf4j:slf4j-api:2.0.13", "//MODULE.bazel:%3"},
+		{"new alias guava~1.0+x", "//third_party:__pkg__"},
+		{`set actual "@rules_jvm_external~~maven~maven//:guava"`,
+			"//third_party:guava"},
+		{`set actual "@@rules_jvm_external++maven+maven//:guava"`,
+			"//third_party:guava"},
+		{"add deps @maven+//:guava @@maven~//:a%b", "//app:app"},
+		{`set tags ["100%","a+b~c@d"]`, "//app:app"},
+		{`set tags ["say\"(hi"]`, "//app:app"},
+	} {
+		if err := Validate(e); err != nil {
+			t.Fatal(err)
+		}
+		s := strings.TrimPrefix(e.String(), "buildozer ")
+		out, err := exec.Command("sh", "-c",
+			"printf '%s\\n' "+s).Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := e.Command + "\n" + e.Target + "\n"
+		if want != string(out) {
+			t.Fatalf("want %q but got %q\n", want, out)
+		}
+	}
+	// shell metacharacters never reach sh
+	for _, e := range []Edit{
+		{"add deps //:x", "//a:b;touch${IFS}/tmp/pwn"},
+		{"add deps //a:$(id)", "//app:app"},
+		{"add deps //a:`id`", "//app:app"},
+		{"add deps //a:b|sh", "//app:app"},
+		{"new java_library a&b", "__pkg__"},
+		{"add deps //:a", "//a:b>c"},
+		{"add deps //:a", "//a:*"},
+		{`set tags ["a\"]`, "//app:app"},
+	} {
+		if err := Validate(e); err == nil {
+			t.Fatalf("want error for %s\n", e)
+		}
+	}
+}
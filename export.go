@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+// exportRecord is a line of an export
+type exportRecord struct {
+	Class    string     `json:"class"`
+	Package  string     `json:"package"`
+	Provider string     `json:"provider"`
+	Kind     index.Kind `json:"kind"`
+}
+
+// header of csv and tsv exports
+var exportHeader = []string{"class", "package", "provider", "kind"}
+
+// write class to provider mappings as csv or tsv with a header line, or as
+// jsonl, one document per line
+func export(w io.Writer, ms []Match, format string) error {
+	var write func(r exportRecord) error
+	switch format {
+	case "csv", "tsv":
+		cw := csv.NewWriter(w)
+		if format == "tsv" {
+			cw.Comma = '\t'
+		}
+		defer cw.Flush()
+		if err := cw.Write(exportHeader); err != nil {
+			return err
+		}
+		write = func(r exportRecord) error {
+			return cw.Write([]string{r.Class, r.Package, r.Provider,
+				string(r.Kind)})
+		}
+	case "jsonl":
+		enc := json.NewEncoder(w)
+		write = func(r exportRecord) error {
+			return enc.Encode(r)
+		}
+	default:
+		return fmt.Errorf("unknown export format %q, want csv, tsv, "+
+			"or jsonl", format)
+	}
+	for _, m := range ms {
+		err := write(exportRecord{m.Class, index.StripLast(m.Class),
+			m.Provider, m.Kind})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
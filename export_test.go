@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+func TestExport(t *testing.T) {
+	ms := []Match{
+		{"com.google.common.base.Strings", "@maven//:guava",
+			index.RulesJvmExternal},
+		{"org.company.framework.A", "framework", index.Source},
+	}
+	for _, tt := range []struct {
+		format string
+		want   string
+	}{
+		{"csv", "class,package,provider,kind\n" +
+			"com.google.common.base.Strings,com.google.common.base," +
+			"@maven//:guava,rules_jvm_external\n" +
+			"org.company.framework.A,org.company.framework," +
+			"framework,source\n"},
+		{"tsv", "class\tpackage\tprovider\tkind\n" +
+			"com.google.common.base.Strings\tcom.google.common.base\t" +
+			"@maven//:guava\trules_jvm_external\n" +
+			"org.company.framework.A\torg.company.framework\t" +
+			"framework\tsource\n"},
+		{"jsonl", `{"class":"com.google.common.base.Strings",` +
+			`"package":"com.google.common.base",` +
+			`"provider":"@maven//:guava","kind":"rules_jvm_external"}` +
+			"\n" + `{"class":"org.company.framework.A",` +
+			`"package":"org.company.framework",` +
+			`"provider":"framework","kind":"source"}` + "\n"},
+	} {
+		var buf bytes.Buffer
+		if err := export(&buf, ms, tt.format); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); tt.want != got {
+			t.Fatalf("%s: want %q but got %q\n", tt.format, tt.want,
+				got)
+		}
+	}
+	if err := export(&bytes.Buffer{}, ms, "xlsx"); err == nil {
+		t.Fatalf("want error for unknown format\n")
+	}
+}
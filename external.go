@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/bazel"
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+// list all classes in external dependencies, or in a sample of percent of
+// them. Unreadable jars are skipped and returned separately. Jars are read
+// by jobs goroutines, bazel runs one command at a time anyway.
+func externalDependencyProvider(workspace string, percent int,
+	cs Classifiers, prev index.Previous,
+	jobs int) ([]index.Dependency, []string, error) {
+	if bzlmodOnly(workspace) {
+		log.Printf("bzlmod workspace, no maven_jar dependencies\n")
+		return nil, nil, nil
+	}
+	var found []index.Dependency
+	var unchanged []bool
+	var unreadable []string
+	base, err := bazel.OutputBase(workspace)
+	if err != nil {
+		return nil, nil, err
+	}
+	external, err := bazel.ExternalDependencies(workspace)
+	if err != nil {
+		return nil, nil, err
+	}
+	var names []string
+	for _, dep := range external {
+		if index.Sampled(dep, percent) {
+			names = append(names, dep)
+		}
+	}
+	// prefer the jars bazel reports over the external/<name>/jar layout
+	jars := bazel.JarFiles(names, workspace)
+	for _, dep := range names {
+		log.Printf("processing dependency %s\n", dep)
+		repo := strings.TrimPrefix(dep, "//external:")
+		dir := filepath.Join(base, "external", repo, "jar")
+		jar, ok := jars[repo]
+		if ok {
+			jar = jarPath(jar)
+		} else {
+			jar, ok = externalJar(dep, dir, workspace, cs)
+		}
+		// Some external dependencies may be declared, but not
+		// used
+		if ok {
+			d := index.Dependency{
+				Name:              dep,
+				ExternalReference: jar,
+				Kind:              index.MavenJar,
+				Stamp:             index.Stamp([]string{jar}),
+			}
+			old, ok := prev.Unchanged(d)
+			if ok {
+				d = old
+			}
+			found = append(found, d)
+			unchanged = append(unchanged, ok)
+		} else {
+			log.Printf("skip non-existent dependency %v, not "+
+				"fetched yet?\n", dep)
+		}
+	}
+	errs := make([]error, len(found))
+	index.Parallel(len(found), jobs, func(i int) {
+		if !unchanged[i] {
+			found[i].Resources, errs[i] = index.Content(
+				found[i].ExternalReference)
+		}
+	})
+	var deps []index.Dependency
+	for i, d := range found {
+		if errs[i] != nil {
+			log.Printf("warning: skip unreadable jar %s: %v\n",
+				d.ExternalReference, errs[i])
+			unreadable = append(unreadable, d.ExternalReference)
+			continue
+		}
+		if !unchanged[i] {
+			d.Artifact = bazel.Artifact(d.Name, workspace)
+		}
+		deps = append(deps, d)
+	}
+	return deps, unreadable, nil
+}
+
+// hasJar reports whether dir contains a local jar
+func hasJar(dir string) bool {
+	jars, _ := filepath.Glob(filepath.Join(dir, "*.jar"))
+	return len(jars) > 0
+}
+
+// jar of an external dependency. With remote execution and
+// --remote_download_minimal, the jar may be missing locally, so its repository
+// is fetched unless offline.
+func externalJar(dep string, dir string, workspace string,
+	cs Classifiers) (string, bool) {
+	if !hasJar(dir) {
+		if bazel.Offline {
+			return "", false
+		}
+		log.Printf("no local jar for %s, fetching\n", dep)
+		if !bazel.Fetch(dep, workspace) || !hasJar(dir) {
+			return "", false
+		}
+	}
+	jar, err := oneJarFrom(dir, cs)
+	if err != nil {
+		log.Printf("warning: skip %s: %v\n", dep, err)
+		return "", false
+	}
+	return jar, true
+}
+
+// return the *.jar file to index, classifier siblings are excluded or ranked
+func oneJarFrom(dir string, cs Classifiers) (string, error) {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	var all []string
+	for _, fi := range fis {
+		if strings.HasSuffix(fi.Name(), ".jar") {
+			all = append(all, fi.Name())
+		}
+	}
+	jars := cs.rank(all)
+	if len(jars) == 0 {
+		return "", fmt.Errorf("want a jar file in %s but got %+v", dir,
+			all)
+	}
+	if len(jars) > 1 {
+		log.Printf("choosing %s of %+v in %s\n", jars[0], jars, dir)
+	}
+	return filepath.Join(dir, jars[0]), nil
+}
+
+// resolve symlinks into the repository cache, keeping the path bazel reports
+// if the target is out of reach, such as in a sandbox
+func jarPath(p string) string {
+	resolved, err := filepath.EvalSymlinks(p)
+	if err != nil {
+		log.Printf("cannot resolve %s: %v\n", p, err)
+		return p
+	}
+	return resolved
+}
+
+func canRead(dir string) bool {
+	_, err := os.Stat(dir)
+	// no need for os.IsNotExist() dance as all we care is if it's there
+	if err != nil {
+		return false
+	}
+	return true
+}
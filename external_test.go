@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/bazel"
+)
+
+func TestOneJarFrom(t *testing.T) {
+	dir := t.TempDir()
+	fixtureJar(t, filepath.Join(dir, "junit-4.10.jar"), "org.junit.Test")
+	fixtureJar(t, filepath.Join(dir, "junit-4.10-sources.jar"))
+	fixtureJar(t, filepath.Join(dir, "junit-4.10-javadoc.jar"))
+	fixtureJar(t, filepath.Join(dir, "junit-4.10-shaded.jar"))
+	cs := parseClassifiers("sources,javadoc,tests", "")
+	want := filepath.Join(dir, "junit-4.10.jar")
+	got, err := oneJarFrom(dir, cs)
+	if err != nil || want != got {
+		t.Fatalf("want %s but got %s (%v)\n", want, got, err)
+	}
+	cs = parseClassifiers("sources,javadoc,tests", "shaded")
+	want = filepath.Join(dir, "junit-4.10-shaded.jar")
+	got, err = oneJarFrom(dir, cs)
+	if err != nil || want != got {
+		t.Fatalf("want %s but got %s (%v)\n", want, got, err)
+	}
+	// unreadable, or no jar left to index
+	if _, err := oneJarFrom(filepath.Join(dir, "gone"), cs); err == nil {
+		t.Fatalf("want error for missing directory\n")
+	}
+	cs = parseClassifiers("sources,javadoc,tests,shaded", "")
+	os.Remove(filepath.Join(dir, "junit-4.10.jar"))
+	if _, err := oneJarFrom(dir, cs); err == nil {
+		t.Fatalf("want error for classifier jars only\n")
+	}
+	if _, ok := externalJar("//external:junit", dir, t.TempDir(),
+		cs); ok {
+		t.Fatalf("want no jar for classifier jars only\n")
+	}
+}
+
+func TestExternalDependencies(t *testing.T) {
+	needBazel(t)
+	ws := fixtureWorkspace(t)
+	// fetch maven_jars into output_base
+	cmd := exec.Command("bazel", "fetch", "//external:all")
+	cmd.Dir = ws
+	if err := cmd.Run(); err != nil {
+		t.Skipf("cannot fetch external dependencies: %v", err)
+	}
+	want := 2
+	deps, _, err := externalDependencyProvider(ws, 100, Classifiers{},
+		nil, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := len(deps)
+	if want != got {
+		t.Fatalf("expected %v but got %v\n", want, got)
+	}
+}
+
+func TestExternalJarOffline(t *testing.T) {
+	bazel.Offline = true
+	defer func() { bazel.Offline = false }()
+	dir := t.TempDir()
+	if _, ok := externalJar("//external:junit", dir, dir,
+		Classifiers{}); ok {
+		t.Fatalf("want no jar offline in empty %s\n", dir)
+	}
+	want := filepath.Join(dir, "junit-4.10.jar")
+	fixtureJar(t, want, "org.junit.Test")
+	got, ok := externalJar("//external:junit", dir, dir,
+		Classifiers{})
+	if !ok || want != got {
+		t.Fatalf("want %s but got %s\n", want, got)
+	}
+}
+
+func TestJarPath(t *testing.T) {
+	dir := t.TempDir()
+	want := filepath.Join(dir, "junit-4.10.jar")
+	fixtureJar(t, want, "org.junit.Test")
+	link := filepath.Join(dir, "junit.jar")
+	if err := os.Symlink(want, link); err != nil {
+		t.Fatal(err)
+	}
+	resolved, _ := filepath.EvalSymlinks(want)
+	if got := jarPath(link); resolved != got {
+		t.Fatalf("want %s but got %s\n", resolved, got)
+	}
+	missing := filepath.Join(dir, "missing.jar")
+	if got := jarPath(missing); missing != got {
+		t.Fatalf("want %s but got %s\n", missing, got)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io/ioutil"
+	"log"
+	"os"
+)
+
+// fixes applied this often that are due again point to a deeper problem
+const recurringFix = 2
+
+// Fix is a dep applied to a rule for a missing class
+type Fix struct {
+	Dep     string
+	Applied int // times applied
+}
+
+// Fixes remembers the fixes -apply applied, next to the cache in
+// .healdb.fixes. A class missing again in the same rule resolves to the
+// same dep without asking any provider.
+type Fixes struct {
+	filename string
+	m        map[string]Fix // by rule and class, see fixKey
+}
+
+func fixKey(rule string, class string) string {
+	return rule + " " + class
+}
+
+// fixes remembered in a file, none if it does not exist
+func readFixes(filename string) *Fixes {
+	a := &Fixes{filename, make(map[string]Fix)}
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("ignoring fixes %s: %v\n", filename, err)
+		}
+		return a
+	}
+	if err := gob.NewDecoder(bytes.NewReader(buf)).Decode(&a.m); err != nil {
+		log.Printf("ignoring fixes %s: %v\n", filename, err)
+		a.m = make(map[string]Fix)
+	}
+	return a
+}
+
+// fix applied before for a class missing in a rule, never for nil Fixes
+func (a *Fixes) lookup(rule string, class string) (Fix, bool) {
+	if a == nil {
+		return Fix{}, false
+	}
+	f, ok := a.m[fixKey(rule, class)]
+	return f, ok
+}
+
+// remember the deps applied by suggestions for missing classes
+func (a *Fixes) record(ss []Suggestion) {
+	if a == nil {
+		return
+	}
+	for _, s := range ss {
+		if s.Rule == "" || s.Class == "" || s.Dep == "" ||
+			(s.Action != AddDep && s.Action != CreateRule) {
+			continue
+		}
+		k := fixKey(s.Rule, s.Class)
+		f := a.m[k]
+		if f.Dep != s.Dep {
+			f = Fix{Dep: s.Dep}
+		}
+		f.Applied++
+		a.m[k] = f
+	}
+}
+
+func (a *Fixes) write() error {
+	if a == nil {
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(a.m); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(a.filename, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return own(a.filename)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+	"github.com/jhinrichsen/bazel-kaizen/parser"
+)
+
+func TestFixes(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), ".healdb.fixes")
+	fs := readFixes(filename)
+	applied := []Suggestion{
+		{Rule: "//ui/web:web", Class: "org.a.A", Dep: "@maven//:a",
+			Action: AddDep},
+		{Rule: "//ui/web:web_test", Action: AddData, Dep: "//data:d"},
+	}
+	fs.record(applied)
+	if err := fs.write(); err != nil {
+		t.Fatal(err)
+	}
+	fs = readFixes(filename)
+	if _, ok := fs.lookup("//ui/web:web_test", ""); ok || len(fs.m) != 1 {
+		t.Fatalf("want fixes of missing classes only but got %+v\n",
+			fs.m)
+	}
+
+	// no provider knows org.a.A anymore
+	fakeTools(t, `case "$*" in
+*testonly*) ;;
+*@maven//:a*) echo @maven//:a;;
+esac
+exit 0
+`, "exit 0\n")
+	h := Healer{Workspace: t.TempDir(), Providers: []string{"index"},
+		Fixes: fs}
+	ps := parser.BuildProblems{BazelRule: "//ui/web:web",
+		MissingClass: []index.JavaClass{{Name: "org.a.A"}}}
+	ss, err := h.heal(ps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ss) != 1 || ss[0].Dep != "@maven//:a" ||
+		ss[0].Provider != "fixes" ||
+		strings.Contains(ss[0].Reason, "deeper") {
+		t.Fatalf("want remembered fix but got %+v\n", ss)
+	}
+	fs.record(ss)
+	ss, err = h.heal(ps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ss) != 1 || !strings.Contains(ss[0].Reason, "deeper") {
+		t.Fatalf("want recurring fix flagged but got %+v\n", ss)
+	}
+}
+
+func TestFixesVerified(t *testing.T) {
+	fs := readFixes(filepath.Join(t.TempDir(), ".healdb.fixes"))
+	fs.record([]Suggestion{
+		{Rule: "//ui/web:web", Class: "org.a.A", Dep: "@maven//:gone",
+			Action: AddDep},
+		{Rule: "//ui/web:web", Class: "org.b.B", Dep: "@maven//:fat",
+			Action: AddDep},
+		{Rule: "//ui/web:web", Class: "org.c.C", Dep: "//testing:c",
+			Action: AddDep},
+	})
+	fakeTools(t, `case "$*" in
+*testonly*//testing:c*) echo //testing:c;;
+*testonly*) ;;
+*@maven//:fat*) echo @maven//:fat;;
+*//testing:c*) echo //testing:c;;
+esac
+exit 0
+`, "exit 0\n")
+	h := Healer{Workspace: t.TempDir(), Providers: []string{"index"},
+		Fixes: fs, PackageFilters: map[string]PackageFilter{
+			"@maven//:fat": {Exclude: []string{"org.b"}}},
+		Deps: []index.Dependency{{Name: "b", Kind: index.MavenJar,
+			Resources: classes("org.b.B")}}}
+	ss, err := h.heal(parser.BuildProblems{BazelRule: "//ui/web:web",
+		MissingClass: []index.JavaClass{{Name: "org.a.A"},
+			{Name: "org.b.B"}, {Name: "org.c.C"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range ss {
+		if s.Provider == "fixes" {
+			t.Fatalf("want no remembered fix but got %+v\n", s)
+		}
+	}
+	if len(ss) != 1 || ss[0].Class != "org.b.B" || ss[0].Dep != "//:b" {
+		t.Fatalf("want org.b.B resolved anew but got %+v\n", ss)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+// Test fixtures are generated at test time instead of being checked in, so
+// tests are hermetic and new scenarios are a matter of a few lines.
+
+// write files below dir, creating directories as needed
+func fixtureFiles(t *testing.T, dir string, files map[string]string) {
+	for f, content := range files {
+		p := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// write a jar containing a manifest and empty class files
+func fixtureJar(t *testing.T, filename string, classes ...string) {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	entries := []string{"META-INF/MANIFEST.MF"}
+	for _, c := range classes {
+		entries = append(entries,
+			strings.Replace(c, ".", "/", -1)+".class")
+	}
+	for _, e := range entries {
+		if _, err := w.Create(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// tiny workspace with two source modules and two maven_jar dependencies
+func fixtureWorkspace(t *testing.T) string {
+	dir := t.TempDir()
+	fixtureFiles(t, dir, map[string]string{
+		"WORKSPACE": `maven_jar(
+    name = "junit",
+    artifact = "junit:junit:4.10",
+)
+
+maven_jar(
+    name = "com_google_guava_guava",
+    artifact = "com.google.guava:guava:20.0",
+)
+`,
+		"BUILD": "",
+		"ui/web/src/main/java/ui/Fx.java": `package ui;
+import org.company.framework.A;
+public class Fx {}
+`,
+		"framework/src/main/java/org/company/framework/A.java": `package org.company.framework;
+public class A {}
+`,
+		"framework/src/main/java/org/company/framework/B.java": `package org.company.framework;
+public class B {}
+`,
+	})
+	return dir
+}
+
+// skip tests that need a bazel installation
+func needBazel(t *testing.T) {
+	if _, err := exec.LookPath("bazel"); err != nil {
+		t.Skip("bazel not installed")
+	}
+}
+
+// class resources, including their packages
+func classes(names ...string) []index.Resource {
+	return index.Resources(names, nil)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+)
+
+// Log content in CI is messy, and must never crash the tool.
+
+func FuzzDepsForm(f *testing.F) {
+	f.Add(`deps = ["//:b"],`)
+	f.Add(`deps = select({"//:x": ["//:b"]}) + ["//:c"],`)
+	f.Add(`deps = select({"//:x": [")"]}),`)
+	f.Fuzz(func(t *testing.T, s string) {
+		form := depsForm(s)
+		switch form {
+		case "", "list", "concat", "select":
+		default:
+			t.Fatalf("unknown form %q\n", form)
+		}
+	})
+}
+
+func FuzzUnified(f *testing.F) {
+	f.Add("a\nb\nc\n", "a\nc\nd\n")
+	f.Fuzz(func(t *testing.T, before, after string) {
+		d := unified("BUILD", before, after)
+		if (d == "") != (before == after) {
+			t.Fatalf("want diff iff different, got %q\n", d)
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/bazel"
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+// label of a java_library output jar relative to bazel-bin, such as
+// ui/web/libweb.jar for //ui/web:web. Source, header, and native header jars
+// are not class providers.
+func jarLabel(rel string) (string, bool) {
+	rel = filepath.ToSlash(rel)
+	base := path.Base(rel)
+	if !strings.HasPrefix(base, "lib") || !strings.HasSuffix(base, ".jar") {
+		return "", false
+	}
+	for _, suffix := range []string{"-src.jar", "-hjar.jar", "-ijar.jar",
+		"-native-header.jar", "-gen.jar"} {
+		if strings.HasSuffix(base, suffix) {
+			return "", false
+		}
+	}
+	pkg := path.Dir(rel)
+	if pkg == "." {
+		pkg = ""
+	}
+	n := strings.TrimSuffix(strings.TrimPrefix(base, "lib"), ".jar")
+	return "//" + pkg + ":" + n, true
+}
+
+// bazel-bin directory of the current configuration
+func bazelBin(workspace string) (string, error) {
+	if len(bazel.Startup) > 0 || len(bazel.Configs) > 0 {
+		// the convenience symlink may belong to another configuration
+		return bazel.Info("bazel-bin", workspace)
+	}
+	return filepath.EvalSymlinks(filepath.Join(workspace, "bazel-bin"))
+}
+
+// index jars of the current build in bazel-bin, so that generated classes of
+// succeeded targets resolve without a cache update
+func generatedDependencies(workspace string) []index.Dependency {
+	bin, err := bazelBin(workspace)
+	if err != nil {
+		log.Printf("no bazel-bin: %v\n", err)
+		return nil
+	}
+	var deps []index.Dependency
+	f := func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() && strings.HasSuffix(p, ".runfiles") {
+			return filepath.SkipDir
+		}
+		rel, _ := filepath.Rel(bin, p)
+		label, ok := jarLabel(rel)
+		if !ok || info.IsDir() {
+			return nil
+		}
+		rs, err := index.Content(p)
+		if err != nil {
+			log.Printf("warning: skip unreadable jar %s: %v\n", p, err)
+			return nil
+		}
+		deps = append(deps, index.Dependency{
+			Name:              label,
+			ExternalReference: p,
+			Resources:         rs,
+			Kind:              index.Built,
+		})
+		return nil
+	}
+	filepath.Walk(bin, f)
+	log.Printf("found %d jars in bazel-bin\n", len(deps))
+	if len(deps) == 0 {
+		log.Printf("remote builds with --remote_download_minimal " +
+			"keep jars remote, build with " +
+			"--remote_download_outputs=all for -bazel-bin\n")
+	}
+	return deps
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestJarLabel(t *testing.T) {
+	for rel, want := range map[string]string{
+		"ui/web/libweb.jar":          "//ui/web:web",
+		"libroot.jar":                "//:root",
+		"ui/web/libweb-hjar.jar":     "",
+		"ui/web/libweb-src.jar":      "",
+		"ui/web/web_deploy.jar":      "",
+		"ui/web/libweb.jar-2.params": "",
+	} {
+		got, ok := jarLabel(rel)
+		if want != got || ok != (want != "") {
+			t.Fatalf("%s: want %q but got %q\n", rel, want, got)
+		}
+	}
+}
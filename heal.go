@@ -0,0 +1,632 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"text/template"
+
+	"github.com/jhinrichsen/bazel-kaizen/bazel"
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+	"github.com/jhinrichsen/bazel-kaizen/index"
+	"github.com/jhinrichsen/bazel-kaizen/parser"
+)
+
+// Healer resolves build problems into edits
+type Healer struct {
+	Workspace     string
+	Deps          []index.Dependency
+	Threshold     index.Confidence
+	Wrapper       *template.Template // -third-party, nil if unused
+	Generators    []Codegen
+	KotlinPlugins string
+	Conventions   *Conventions // -learn, nil if unused
+	AllImports    bool         // resolve all imports of failing sources
+	Providers     []string     // resolution chain, defaultProviders if empty
+	Store         *index.Store // -store, looked up in addition to Deps
+	// deps attribute by rule kind or macro, depsAttributes if nil
+	DepsAttributes map[string]string
+	// packages dependencies are suggested for, by dependency name
+	PackageFilters map[string]PackageFilter
+	// external repositories preferred for ambiguous classes, in order
+	PreferRepos []string
+	// attributes of generated rules, such as visibility
+	DefaultAttributes []Attribute
+	Fixes             *Fixes // -remember, nil if unused
+	// -visibility policy, package if empty
+	Visibility string
+	Overrides  *Overrides // pinned deps, win over any provider
+	// classes no provider resolves are appended to, nil if unused
+	Unresolved *[]Suggestion
+}
+
+// suggestions fixing build problems: bazel's own commands, and one per
+// resolved class or runfile
+func (h Healer) heal(ps parser.BuildProblems) ([]Suggestion, error) {
+	// bazel knows best, its commands are used as they are, but for the
+	// deps of macros
+	var own []Suggestion
+	for _, e := range edit.Valid(edit.Dedupe(ps.Suggested)) {
+		log.Printf("using bazel's own fix %v\n", e)
+		own = append(own, Suggestion{Rule: e.Target, Action: Bazel,
+			Provider: "bazel",
+			Reason:   "suggested by bazel", Confidence: index.High,
+			Evidence: []string{e.String()}, Edits: []edit.Edit{e}})
+	}
+	own = h.forward(own)
+	if h.AllImports {
+		js := importedClasses(ps, h.Workspace)
+		log.Printf("resolving %d more imports of %d failing source "+
+			"files\n", len(js), len(ps.Sources))
+		ps.MissingClass = append(ps.MissingClass, js...)
+	}
+	kind := ""
+	if ps.BazelRule != "" {
+		kind = bazel.RuleKind(ps.BazelRule, h.Workspace)
+	}
+	// generated classes of Kotlin rules need processor plugins
+	var plugins map[string]string
+	if h.KotlinPlugins != "" && kind == "kt_jvm_library" {
+		plugins = parsePlugins(h.KotlinPlugins)
+	}
+
+	// Match missing dependencies against providers
+	// Performance: process one missing class per Java package only
+	packagesResolved := make(map[string]bool)
+	done := func(pkg string) {
+		packagesResolved[pkg] = true
+	}
+	var ss []Suggestion
+	// rules generated within this run
+	created := make(map[string]bool)
+	// .proto files of the workspace, scanned on first use
+	var protos []ProtoFile
+	protosScanned := false
+	suggest := func(s Suggestion) {
+		if len(s.Edits) == 0 {
+			return
+		}
+		if s.Confidence < h.Threshold {
+			log.Printf("dropping %s\n", s)
+			return
+		}
+		log.Printf("suggesting %s\n", s)
+		for _, e := range s.Evidence {
+			log.Printf("  evidence: %s\n", e)
+		}
+		ss = append(ss, s)
+	}
+	// one query for the rules any class may resolve to
+	var labels []string
+	seen := make(map[string]bool)
+	for _, p := range ps.MissingClass {
+		l, ok := codegenLabel(h.Generators, p.Package())
+		if ok && !seen[l] {
+			seen[l] = true
+			labels = append(labels, l)
+		}
+	}
+	rules := queryRules(h.Workspace, labels, ps.MissingClass)
+	rules.attributes = h.DepsAttributes
+	idx := &indexProvider{h: h, rule: ps.BazelRule,
+		classpath: ps.Classpath, rules: rules, created: created}
+	providers, err := h.providers(idx, rules)
+	if err != nil {
+		log.Printf("cannot resolve classes: %v\n", err)
+	}
+	var unresolved []index.JavaClass
+	for _, p := range ps.MissingClass {
+		if packagesResolved[p.Package()] {
+			log.Printf("skipping resolution of class %s as "+
+				"package %s has already been resolved\n",
+				p.Name, p.Package())
+			continue
+		}
+		log.Printf("resolving missing dependency %v\n", p.Name)
+		o, ok, err := h.Overrides.lookup(ps.BazelRule, p.Name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			suggest(o.suggestion(ps.BazelRule, p))
+			done(p.Package())
+			continue
+		}
+		if f, ok := h.Fixes.lookup(ps.BazelRule, p.Name); ok {
+			if s, ok := idx.reuse(p, f); ok {
+				suggest(s)
+				done(p.Package())
+				continue
+			}
+		}
+		resolved := false
+		for _, pr := range providers {
+			found := pr.Lookup(p)
+			for _, s := range found {
+				s.Rule, s.Class, s.Location = ps.BazelRule, p.Name,
+					p.Location
+				s.Evidence = append(append([]string{}, p.Log...),
+					s.Evidence...)
+				switch {
+				case s.Action != "":
+				case len(s.Edits) > 0:
+					s.Action = CreateRule
+				default:
+					s.Action = AddDep
+				}
+				s.Edits = append(append([]edit.Edit{}, s.Edits...),
+					edit.AddDeps(ps.BazelRule, s.Dep))
+				suggest(s)
+			}
+			if idx.err != nil {
+				return nil, idx.err
+			}
+			if len(found) > 0 {
+				resolved = true
+				break
+			}
+		}
+		if resolved {
+			done(p.Package())
+			continue
+		}
+		// generated from a .proto file?
+		if !protosScanned {
+			protos = protoFiles(h.Workspace)
+			protosScanned = true
+		}
+		if es := healProto(ps.BazelRule, p.Package(), protos,
+			h.Workspace); len(es) > 0 {
+			suggest(Suggestion{Rule: ps.BazelRule, Action: AddDep,
+				Provider: "proto", Class: p.Name,
+				Location: p.Location, Evidence: p.Log,
+				Reason: fmt.Sprintf("package %s is generated "+
+					"from .proto files", p.Package()),
+				Confidence: index.Medium, Edits: es})
+			done(p.Package())
+			continue
+		}
+		if len(ps.Classpath) > 0 {
+			log.Printf("class %s is nowhere in the workspace\n",
+				p.Name)
+		}
+		// generated by an annotation processor of a Kotlin rule?
+		if e, processor, ok := bdAddProcessor(ps.BazelRule, p.Name,
+			plugins); ok {
+			suggest(Suggestion{Rule: ps.BazelRule, Action: AddPlugin,
+				Provider: "kotlin-plugins",
+				Dep:      strings.TrimPrefix(e.Command, "add plugins "),
+				Class:    p.Name, Location: p.Location, Evidence: p.Log,
+				Reason: fmt.Sprintf("class %s is generated by %s",
+					p.Name, processor),
+				Confidence: index.Medium, Edits: []edit.Edit{e}})
+			done(p.Package())
+			continue
+		}
+		unresolved = append(unresolved, p)
+	}
+	idx.report(unresolved)
+	// runfiles are found by path, not by class
+	for _, r := range ps.MissingRunfile {
+		suggest(Suggestion{Rule: r.Test, Action: AddData,
+			Provider: "runfiles",
+			Reason: fmt.Sprintf("data file %s is missing at test "+
+				"runtime", r.Path),
+			Confidence: index.High, Evidence: []string{r.Line},
+			Edits: healRunfiles([]parser.Runfile{r}, h.Workspace,
+				rules.Exists)})
+	}
+	policy := h.Visibility
+	if policy == "" {
+		policy = "package"
+	}
+	for _, v := range ps.Invisible {
+		for _, s := range healVisibility(v, policy, h.Workspace) {
+			suggest(s)
+		}
+	}
+	// deps on packages or targets that do not exist
+	for _, m := range ps.MissingTarget {
+		for _, s := range h.healMissingTarget(m, created) {
+			suggest(s)
+		}
+	}
+	// .bzl files packages cannot load, scanned on first use
+	var bzls []string
+	for i, l := range ps.Unloadable {
+		if i == 0 {
+			bzls = bzlFiles(h.Workspace)
+		}
+		for _, s := range healLoad(l, bzls) {
+			suggest(s)
+		}
+	}
+	// run scripts of binaries missing their main class
+	for _, m := range ps.MissingMain {
+		for _, s := range idx.mainClass(m) {
+			suggest(s)
+		}
+	}
+	polished, err := h.polish(ss, ps.BazelRule, kind)
+	if err != nil {
+		return nil, err
+	}
+	return append(own, polished...), nil
+}
+
+// suggestions for each failing rule
+func (h Healer) healAll(ps parser.BuildProblems) ([]Suggestion, error) {
+	ps = h.jarRules(ps)
+	var ss []Suggestion
+	for _, p := range ps.Rules() {
+		if len(ps.ByRule) > 1 {
+			log.Printf("healing %s\n", p.BazelRule)
+		}
+		healed, err := h.heal(p)
+		if err != nil {
+			return nil, err
+		}
+		ss = append(ss, healed...)
+	}
+	return ss, nil
+}
+
+// problems of the rules bazel knows: a jar lib<name>.jar is built by library
+// <name>, or by the test or binary lib<name>, see parser.ProgressRule
+func (h Healer) jarRules(ps parser.BuildProblems) parser.BuildProblems {
+	if len(ps.Alternatives) == 0 {
+		return ps
+	}
+	rules := &Rules{workspace: h.Workspace, kinds: make(map[string]string),
+		srcs: make(map[string][]string), asked: make(map[string]bool),
+		attributes: h.DepsAttributes}
+	byRule := make(map[string][]index.JavaClass)
+	for r, js := range ps.ByRule {
+		byRule[r] = js
+	}
+	for r, alt := range ps.Alternatives {
+		if rules.Exists(r) || !rules.Exists(alt) {
+			continue
+		}
+		log.Printf("using rule %s, %s does not exist\n", alt, r)
+		if ps.BazelRule == r {
+			ps.BazelRule = alt
+		}
+		if js, ok := byRule[r]; ok {
+			delete(byRule, r)
+			byRule[alt] = js
+		}
+	}
+	ps.ByRule = byRule
+	ps.Alternatives = nil
+	return ps
+}
+
+// move deps bazel suggests for a rule generated by a macro to the macro call,
+// into the parameter the macro forwards to deps. Deps of macros mapped to
+// no attribute are dropped, as polish does.
+func (h Healer) forward(own []Suggestion) []Suggestion {
+	attributes := h.DepsAttributes
+	if attributes == nil {
+		attributes = depsAttributes
+	}
+	targets := make(map[string]DepsTarget)
+	var forwarded []Suggestion
+	for _, s := range own {
+		if !addsDeps(s.Edits, s.Rule) {
+			forwarded = append(forwarded, s)
+			continue
+		}
+		t, ok := targets[s.Rule]
+		if !ok {
+			kind := bazel.RuleKind(s.Rule, h.Workspace)
+			t, ok = depsTarget(s.Rule, kind,
+				bazel.RuleDefinition(s.Rule, h.Workspace), attributes)
+			if !ok {
+				logNoDeps(s.Rule, kind)
+			}
+			targets[s.Rule] = t
+		}
+		if t.Attribute == "" {
+			continue
+		}
+		if t.Label != s.Rule || t.Attribute != "deps" {
+			s.Edits = retarget(s.Edits, s.Rule, t)
+			s.Reason = fmt.Sprintf("suggested by bazel, moved to %s "+
+				"of %s", t.Attribute, t.Label)
+		}
+		forwarded = append(forwarded, s)
+	}
+	return forwarded
+}
+
+// adapt the edits of suggestions to the workspace: aliases, plugins the
+// rule runs already, deps declared by select(), the deps attribute of the rule's kind,
+// and -learn conventions
+func (h Healer) polish(ss []Suggestion, rule string,
+	kind string) ([]Suggestion, error) {
+	if len(ss) == 0 {
+		return ss, nil
+	}
+	aliases, err := bazel.Aliases(h.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	var running map[string]bool
+	form := ""
+	target := DepsTarget{rule, "deps"}
+	if rule != "" {
+		build := bazel.RuleDefinition(rule, h.Workspace)
+		form = depsForm(build)
+		attributes := h.DepsAttributes
+		if attributes == nil {
+			attributes = depsAttributes
+		}
+		var ok bool
+		target, ok = depsTarget(rule, kind, build, attributes)
+		if !ok {
+			logNoDeps(rule, kind)
+		}
+	}
+	var polished []Suggestion
+	for _, s := range ss {
+		s.Edits = withAliases(s.Edits, aliases)
+		if a, ok := aliases[s.Dep]; ok {
+			s.Dep = a
+		}
+		if addsPlugins(s.Edits, rule) {
+			if running == nil {
+				running = bazel.RulePlugins(rule, h.Workspace)
+			}
+			s.Edits = dedupePlugins(s.Edits, rule, running)
+		}
+		if rule != "" {
+			s.Edits = selectAware(s.Edits, rule, form)
+			if target.Attribute == "" && addsDeps(s.Edits, rule) {
+				continue
+			}
+			s.Edits = retarget(s.Edits, rule, target)
+		}
+		if h.Conventions != nil {
+			for i := range s.Edits {
+				s.Edits[i] = h.Conventions.format(s.Edits[i])
+			}
+		}
+		s.Edits = edit.Valid(s.Edits)
+		if len(s.Edits) > 0 {
+			polished = append(polished, s)
+		}
+	}
+	return polished, nil
+}
+
+// indexProvider resolves classes against the class index, and creates rules
+// for modules and jars not built yet
+type indexProvider struct {
+	h         Healer
+	rule      string
+	classpath []string
+	rules     *Rules
+	created   map[string]bool // rules generated within this run
+	testOnly  *bool           // rule is testonly, queried on first use
+	jars      relocator
+	// indexed classes by simple name and by package, for typos
+	bySimpleName map[string][]string
+	byPackage    map[string][]string
+	err          error // ends healing, Lookup finds nothing after it
+}
+
+// ruleTestOnly reports whether the rule is testonly, queried on first use
+func (a *indexProvider) ruleTestOnly() bool {
+	if a.testOnly == nil {
+		t := bazel.TestOnly(a.rule, a.h.Workspace)
+		a.testOnly = &t
+	}
+	return *a.testOnly
+}
+
+// dependencies available to the rule, test only jars to test rules only
+func (a *indexProvider) available(deps []index.Dependency) []index.Dependency {
+	production := index.Production(deps)
+	if len(production) == len(deps) || a.ruleTestOnly() {
+		return deps
+	}
+	return production
+}
+
+// dependencies of the cache and the store that may provide a class
+func (a *indexProvider) candidates(p index.JavaClass) []index.Dependency {
+	deps := a.h.Deps
+	if a.h.Store != nil {
+		found, err := a.h.Store.Lookup(p)
+		if err != nil {
+			log.Printf("cannot look up %s: %v\n", p.Name, err)
+		}
+		deps = append(found, deps...)
+	}
+	return deps
+}
+
+func (a *indexProvider) Lookup(p index.JavaClass) []Suggestion {
+	h := a.h
+	if a.err != nil {
+		return nil
+	}
+	e, c := index.FindClass(p, preferRepos(h.PreferRepos,
+		filterPackages(h.PackageFilters, p,
+			a.available(a.candidates(p)))))
+	if e == nil {
+		log.Printf("not provided by internal (source) or "+
+			"external (maven_jar) dependency %s\n", p)
+		return nil
+	}
+	log.Printf("missing class %v provided by %+v\n", p.Name, e.Name)
+	s, err := a.suggestion(p, *e, c)
+	if err != nil {
+		a.err = err
+		return nil
+	}
+	return []Suggestion{s}
+}
+
+// suggestion depending on a dependency providing a class, generating its
+// rule if there is none yet
+func (a *indexProvider) suggestion(p index.JavaClass, e index.Dependency,
+	c index.Confidence) (Suggestion, error) {
+	h := a.h
+	e, ok := a.jars.relocate(h.Workspace, e)
+	evidence := e.Evidence(p)
+	if !ok {
+		evidence = fmt.Sprintf("%s %s provided class %s in %s, which "+
+			"is gone", e.Kind, e.Name, p.Name, e.ExternalReference)
+	}
+	reason := explain(p, e, a.rule, a.classpath)
+	s := Suggestion{Provider: "index", Confidence: c, Reason: reason,
+		Evidence: []string{evidence}}
+	// Treat external dependencies same as internal
+	name := strings.TrimPrefix(e.Name, "//external:")
+	// rule of the dependency, in the root package if a plain name
+	ref := name
+	pkg, local := modulePackage(h.Workspace, e)
+	if local {
+		ref = "//" + pkg + ":" + name
+	}
+	library := func(rule func(index.Dependency) []edit.Edit) []edit.Edit {
+		if local {
+			return inModule(rule, e, pkg, h.DefaultAttributes)
+		}
+		return rule(e)
+	}
+	switch {
+	case h.Wrapper != nil && e.Kind.External():
+		tp := thirdParty(e)
+		label, err := wrapperLabel(h.Wrapper, tp)
+		if err != nil {
+			return Suggestion{}, fmt.Errorf("bad -third-party "+
+				"template: %v", err)
+		}
+		if !a.created[label] && !a.rules.Exists(label) {
+			s.Edits = edit.NewWrapper(label, tp.Actual)
+			a.created[label] = true
+		}
+		s.Dep = label
+	case a.created[ref]:
+		s.Dep = ruleLabel(ref)
+	case a.rules.Exists(ref):
+		s.Dep = ref
+	case e.Kind == index.RulesJvmExternal:
+		s.Dep = e.Name
+	case e.Kind.External():
+		// jars have no sources to build from
+		s.Edits = edit.NewAlias(name, thirdParty(e).Actual)
+	case e.Kind == index.Source:
+		s.Edits = library(edit.NewJavaLibrary)
+	case e.Kind == index.KotlinSource:
+		s.Edits = library(edit.NewKotlinLibrary)
+	case e.Kind == index.ScalaSource:
+		s.Edits = library(edit.NewScalaLibrary)
+	default:
+		log.Printf("cannot generate a rule for %s dependency %s\n",
+			e.Kind, e.Name)
+	}
+	if s.Dep == "" && len(s.Edits) > 0 {
+		s.Edits = append(s.Edits, setAttributes(ref,
+			h.DefaultAttributes)...)
+		s.Dep = ruleLabel(ref)
+		a.created[ref] = true
+	}
+	return s, nil
+}
+
+// suggestion applying a remembered fix again, unless its dep is gone, a
+// package filter rules it out, or it is testonly and the rule is not. Then
+// the providers resolve the class anew. A fix due again and again does not
+// stick, something else is wrong.
+func (a *indexProvider) reuse(j index.JavaClass, f Fix) (Suggestion, bool) {
+	h, rule := a.h, a.rule
+	if !a.created[f.Dep] && !a.rules.Exists(f.Dep) {
+		log.Printf("remembered %s for class %s is gone\n", f.Dep, j.Name)
+		return Suggestion{}, false
+	}
+	names := []string{f.Dep}
+	if strings.HasPrefix(f.Dep, "//") {
+		names = append(names, labelName(f.Dep))
+	}
+	for _, n := range names {
+		if pf, ok := h.PackageFilters[n]; ok && !pf.allows(j.Package()) {
+			log.Printf("remembered %s: package %s filtered out\n",
+				f.Dep, j.Package())
+			return Suggestion{}, false
+		}
+	}
+	if !a.ruleTestOnly() && bazel.TestOnly(f.Dep, h.Workspace) {
+		log.Printf("remembered %s is testonly, %s is not\n", f.Dep,
+			rule)
+		return Suggestion{}, false
+	}
+	s := Suggestion{Rule: rule, Action: AddDep, Dep: f.Dep,
+		Class: j.Name, Location: j.Location, Provider: "fixes",
+		Confidence: index.High,
+		Reason: fmt.Sprintf("class %s resolved to %s before", j.Name,
+			f.Dep),
+		Evidence: append(append([]string{}, j.Log...),
+			fmt.Sprintf("fixes: applied %d time(s)", f.Applied)),
+		Edits: []edit.Edit{edit.AddDeps(rule, f.Dep)}}
+	if f.Applied >= recurringFix {
+		log.Printf("warning: %s keeps missing class %s although %s "+
+			"was added %d times, check for a deeper problem such "+
+			"as a removed dep, visibility, or a broken %s\n", rule,
+			j.Name, f.Dep, f.Applied, f.Dep)
+		s.Reason += fmt.Sprintf(", applied %d times already, possibly "+
+			"a deeper problem", f.Applied)
+	}
+	return s, true
+}
+
+// rounds of -loop before giving up
+const maxRounds = 20
+
+// build, heal, and apply until the target builds, or healing makes no more
+// progress
+func (h Healer) loop(target string, journal string) error {
+	seen := make(map[string]bool)
+	for round := 1; round <= maxRounds; round++ {
+		buf, ok, err := bazel.Build(target, h.Workspace)
+		if err != nil {
+			return err
+		}
+		if ok {
+			log.Printf("%s builds after %d round(s)\n", target,
+				round-1)
+			return nil
+		}
+		ps := parser.Problems(*bufio.NewScanner(bytes.NewReader(buf)))
+		ss, err := h.healAll(ps)
+		if err != nil {
+			return err
+		}
+		edits := commands(ss)
+		if len(edits) == 0 {
+			return fmt.Errorf("%s still fails, nothing to heal in "+
+				"round %d", target, round)
+		}
+		key := fmt.Sprint(edits)
+		if seen[key] {
+			return fmt.Errorf("%s still fails, round %d repeats "+
+				"earlier fixes", target, round)
+		}
+		seen[key] = true
+		log.Printf("round %d: applying %d commands\n", round,
+			len(edits))
+		if err := applyAll(edits, h.Workspace, journal); err != nil {
+			return err
+		}
+		h.Fixes.record(ss)
+		if err := h.Fixes.write(); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("%s still fails after %d rounds", target, maxRounds)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+	"github.com/jhinrichsen/bazel-kaizen/parser"
+)
+
+// put fake bazel and buildozer scripts first on PATH
+func fakeTools(t *testing.T, bazelScript string, buildozer string) {
+	dir := t.TempDir()
+	for name, script := range map[string]string{
+		"bazel":     bazelScript,
+		"buildozer": buildozer,
+	} {
+		err := ioutil.WriteFile(filepath.Join(dir, name),
+			[]byte("#!/bin/sh\n"+script), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestLoop(t *testing.T) {
+	ws := t.TempDir()
+	buildLog := filepath.Join(ws, "build.log")
+	err := ioutil.WriteFile(buildLog, []byte(`ERROR: /ws/BUILD:1:1: Building libui_web.jar (1 source file) failed
+ui/web/src/main/java/ui/Fx.java:3: error: package org.a does not exist
+import org.a.A;
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied := filepath.Join(ws, "applied")
+	// fails until buildozer ran
+	fakeTools(t, `case "$1" in
+build) test -f `+applied+` && exit 0; cat `+buildLog+`; exit 1;;
+esac
+exit 0
+`, `echo "$@" >> `+applied+"\n")
+	h := Healer{
+		Workspace: ws,
+		Deps: []index.Dependency{{Name: "a", ExternalReference: "a/src/main/java/",
+			Resources: classes("org.a.A"), Kind: index.Source}},
+	}
+	if err := h.loop("//:ui_web", ""); err != nil {
+		t.Fatal(err)
+	}
+	buf, err := ioutil.ReadFile(applied)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(buf), "add deps //:a") {
+		t.Fatalf("want add deps //:a applied but got %s\n", buf)
+	}
+}
+
+func TestLoopNoProgress(t *testing.T) {
+	// fails forever without anything to heal
+	fakeTools(t, `test "$1" = build && echo "ERROR: something else" && exit 1
+exit 0
+`, "exit 0\n")
+	h := Healer{Workspace: t.TempDir()}
+	if err := h.loop("//:ui_web", ""); err == nil {
+		t.Fatalf("want error for no progress\n")
+	}
+}
+
+func TestHealTestOnly(t *testing.T) {
+	fakeTools(t, `case "$*" in *testonly*//t:t*) echo //t:t;; esac`+"\n",
+		"exit 0\n")
+	h := Healer{
+		Workspace: t.TempDir(),
+		Deps: []index.Dependency{{Name: "@maven//:org_a_a_tests",
+			Kind: index.RulesJvmExternal, TestOnly: true,
+			Resources: classes("org.a.ATest")}},
+		Providers: []string{"index"},
+	}
+	for _, tt := range []struct {
+		rule string
+		want int
+	}{
+		{"//p:p", 0},
+		{"//t:t", 1},
+	} {
+		ss, err := h.heal(parser.BuildProblems{BazelRule: tt.rule,
+			MissingClass: []index.JavaClass{{Name: "org.a.ATest"}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ss) != tt.want {
+			t.Fatalf("%s: want %d suggestions but got %+v\n", tt.rule,
+				tt.want, ss)
+		}
+	}
+}
+
+func TestHealAllJarRules(t *testing.T) {
+	fakeTools(t, "exit 0\n", "exit 0\n")
+	for _, tt := range []struct {
+		build, want string
+	}{
+		{`java_library(name = "fixtures_tests", testonly = True)`,
+			"//ui/web:fixtures_tests"},
+		{`java_test(name = "libfixtures_tests")`,
+			"//ui/web:libfixtures_tests"},
+	} {
+		ws := t.TempDir()
+		fixtureFiles(t, ws, map[string]string{"ui/web/BUILD": tt.build})
+		h := Healer{
+			Workspace: ws,
+			Deps: []index.Dependency{{Name: "a",
+				ExternalReference: "a/src/main/java/",
+				Resources:         classes("org.a.A"),
+				Kind:              index.Source}},
+		}
+		ps := parser.BuildProblems{
+			BazelRule:    "//ui/web:fixtures_tests",
+			MissingClass: []index.JavaClass{{Name: "org.a.A"}},
+			Alternatives: map[string]string{
+				"//ui/web:fixtures_tests": "//ui/web:libfixtures_tests",
+			},
+		}
+		ss, err := h.healAll(ps)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ss) != 1 || ss[0].Rule != tt.want {
+			t.Fatalf("want %s but got %+v\n", tt.want, ss)
+		}
+	}
+}
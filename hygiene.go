@@ -0,0 +1,179 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+// PackageCount is how often a module imports from an external package
+type PackageCount struct {
+	Package  string
+	Provider string // dependency providing the package
+	Imports  int
+}
+
+// ModuleImports are the imports of a source module's Java sources, by the
+// dependencies they resolve to
+type ModuleImports struct {
+	Module   string
+	Packages []PackageCount // external packages, most imported first
+	// importing source files by discouraged dependency
+	Discouraged map[string][]string
+}
+
+// resolver resolves classes and packages to the dependencies providing
+// them, without logging each lookup as FindClass does
+type resolver struct {
+	classes  map[string]string
+	packages map[string]string
+	deps     map[string]index.Dependency
+}
+
+// the first dependency providing a class or package wins, as in FindClass
+func newResolver(deps []index.Dependency) resolver {
+	p := resolver{make(map[string]string), make(map[string]string),
+		make(map[string]index.Dependency)}
+	for _, d := range deps {
+		p.deps[d.Name] = d
+		for _, c := range d.Named(index.Class) {
+			if _, ok := p.classes[c]; !ok {
+				p.classes[c] = d.Name
+			}
+		}
+		for _, pkg := range d.Named(index.Package) {
+			if _, ok := p.packages[pkg]; !ok {
+				p.packages[pkg] = d.Name
+			}
+		}
+	}
+	return p
+}
+
+// dependency providing an import, wildcard imports resolve by package
+func (a resolver) resolve(j index.JavaClass) (index.Dependency, bool) {
+	name, ok := a.classes[j.Name]
+	if !ok {
+		name, ok = a.packages[j.Package()]
+	}
+	return a.deps[name], ok
+}
+
+// discouraged reports whether a dependency is one of patterns, matching its
+// name or label exactly, or by prefix ending in *
+func discouraged(d index.Dependency, patterns []string) bool {
+	for _, p := range patterns {
+		for _, s := range []string{d.Name, depLabel(d)} {
+			if s == p || strings.HasSuffix(p, "*") &&
+				strings.HasPrefix(s, strings.TrimSuffix(p, "*")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// count the imports of a module's sources by external package, and collect
+// imports of discouraged dependencies
+func moduleImports(module string, srcs []SourceFile, p resolver,
+	patterns []string) ModuleImports {
+	m := ModuleImports{Module: module,
+		Discouraged: make(map[string][]string)}
+	own := make(map[string]bool)
+	for _, sf := range srcs {
+		own[sf.Package] = true
+	}
+	counts := make(map[string]int)
+	provider := make(map[string]string)
+	for _, sf := range srcs {
+		seen := make(map[string]bool)
+		for _, j := range sf.Imports {
+			if strings.HasPrefix(j.Name, "java.") ||
+				own[j.Package()] {
+				continue
+			}
+			d, ok := p.resolve(j)
+			if !ok || d.Name == module {
+				continue
+			}
+			if d.Kind.External() {
+				counts[j.Package()]++
+				provider[j.Package()] = d.Name
+			}
+			if discouraged(d, patterns) && !seen[d.Name] {
+				seen[d.Name] = true
+				m.Discouraged[d.Name] = append(
+					m.Discouraged[d.Name], sf.Path)
+			}
+		}
+	}
+	for pkg, n := range counts {
+		m.Packages = append(m.Packages,
+			PackageCount{pkg, provider[pkg], n})
+	}
+	sort.Slice(m.Packages, func(i, j int) bool {
+		if m.Packages[i].Imports != m.Packages[j].Imports {
+			return m.Packages[i].Imports > m.Packages[j].Imports
+		}
+		return m.Packages[i].Package < m.Packages[j].Package
+	})
+	return m
+}
+
+// imports of all source modules of the index, or of the named ones,
+// parsing sources on jobs goroutines. Modules are ordered by name.
+func importStats(deps []index.Dependency, names []string,
+	patterns []string, jobs int) []ModuleImports {
+	var modules []index.Dependency
+	for _, d := range deps {
+		if d.Kind.External() || d.ExternalReference == "" ||
+			len(names) > 0 && !contains(names, d.Name) {
+			continue
+		}
+		switch d.Kind {
+		case index.Source, index.KotlinSource, index.ScalaSource:
+			modules = append(modules, d)
+		}
+	}
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].Name < modules[j].Name
+	})
+	p := newResolver(deps)
+	ms := make([]ModuleImports, len(modules))
+	index.Parallel(len(modules), jobs, func(i int) {
+		ms[i] = moduleImports(modules[i].Name,
+			parseSources(modules[i].ExternalReference), p, patterns)
+	})
+	return ms
+}
+
+// log the top most imported external packages and discouraged imports of
+// a module
+func (a ModuleImports) report(top int) {
+	total := 0
+	for _, c := range a.Packages {
+		total += c.Imports
+	}
+	log.Printf("%s: %d imports of %d external packages\n", a.Module,
+		total, len(a.Packages))
+	for i, c := range a.Packages {
+		if top > 0 && i == top {
+			break
+		}
+		log.Printf("%s: %5d %s (%s)\n", a.Module, c.Imports, c.Package,
+			c.Provider)
+	}
+	var ds []string
+	for d := range a.Discouraged {
+		ds = append(ds, d)
+	}
+	sort.Strings(ds)
+	for _, d := range ds {
+		for _, f := range a.Discouraged[d] {
+			log.Printf("%s: discouraged %s imported by %s\n",
+				a.Module, d, f)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+func TestImportStats(t *testing.T) {
+	ws := t.TempDir()
+	fixtureFiles(t, ws, map[string]string{
+		"framework/src/main/java/org/company/framework/A.java": `
+package org.company.framework;
+
+import java.util.List;
+import com.google.common.collect.Lists;
+import com.google.common.collect.Maps;
+import com.google.common.base.*;
+import org.apache.commons.lang.StringUtils;
+import org.company.framework.B;
+`,
+		"framework/src/main/java/org/company/framework/B.java": `
+package org.company.framework;
+
+import com.google.common.collect.Sets;
+`,
+	})
+	src := filepath.Join(ws, "framework/src/main/java") + "/"
+	deps := []index.Dependency{
+		{Name: "framework", Kind: index.Source, ExternalReference: src,
+			Resources: classes("org.company.framework.A",
+				"org.company.framework.B")},
+		{Name: "@maven//:guava", Kind: index.RulesJvmExternal,
+			Resources: classes("com.google.common.collect.Lists",
+				"com.google.common.collect.Maps",
+				"com.google.common.collect.Sets",
+				"com.google.common.base.Strings")},
+		{Name: "@maven//:commons_lang", Kind: index.RulesJvmExternal,
+			Resources: classes("org.apache.commons.lang.StringUtils")},
+	}
+	ms := importStats(deps, nil, []string{"@maven//:commons*"}, 2)
+	if len(ms) != 1 {
+		t.Fatalf("want 1 module but got %+v\n", ms)
+	}
+	want := []PackageCount{
+		{"com.google.common.collect", "@maven//:guava", 3},
+		{"com.google.common.base", "@maven//:guava", 1},
+		{"org.apache.commons.lang", "@maven//:commons_lang", 1},
+	}
+	if !reflect.DeepEqual(want, ms[0].Packages) {
+		t.Fatalf("want %+v but got %+v\n", want, ms[0].Packages)
+	}
+	a := filepath.Join(src, "org/company/framework/A.java")
+	wantDiscouraged := map[string][]string{"@maven//:commons_lang": {a}}
+	if !reflect.DeepEqual(wantDiscouraged, ms[0].Discouraged) {
+		t.Fatalf("want %v but got %v\n", wantDiscouraged,
+			ms[0].Discouraged)
+	}
+	if ms := importStats(deps, []string{"web"}, nil, 1); len(ms) != 0 {
+		t.Fatalf("want no modules but got %+v\n", ms)
+	}
+}
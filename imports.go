@@ -0,0 +1,48 @@
+package main
+
+import (
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+	"github.com/jhinrichsen/bazel-kaizen/parser"
+)
+
+// all imports of the failing source files, other than the JDK, the classes
+// javac already reported, and classes of the failing module itself. javac
+// stops early, so resolving them all at once saves rebuilds.
+func importedClasses(ps parser.BuildProblems,
+	workspace string) []index.JavaClass {
+	const sep = "/src/main/java/"
+	seen := make(map[string]bool)
+	for _, j := range ps.MissingClass {
+		seen[j.Name] = true
+	}
+	var js []index.JavaClass
+	for _, src := range ps.Sources {
+		sf, err := parseSource(filepath.Join(workspace, src))
+		if err != nil {
+			log.Printf("cannot analyze imports of %s: %v\n", src, err)
+			continue
+		}
+		root := ""
+		if i := strings.Index(src, sep); i >= 0 {
+			root = filepath.Join(workspace, src[:i+len(sep)])
+		}
+		for _, j := range sf.Imports {
+			if seen[j.Name] || strings.HasPrefix(j.Name, "java.") ||
+				j.Package() == sf.Package {
+				continue
+			}
+			seen[j.Name] = true
+			own := filepath.Join(root,
+				strings.Replace(j.Name, ".", "/", -1)+".java")
+			if root != "" && canRead(own) {
+				continue
+			}
+			js = append(js, j)
+		}
+	}
+	return js
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+	"github.com/jhinrichsen/bazel-kaizen/parser"
+)
+
+func TestImportedClasses(t *testing.T) {
+	ws := t.TempDir()
+	fixtureFiles(t, ws, map[string]string{
+		"ui/web/src/main/java/ui/Fx.java": `package ui;
+
+import java.util.List;
+import org.a.A;
+import org.b.B;
+import ui.model.Item;
+import ui.Sibling;
+
+public class Fx {}
+`,
+		"ui/web/src/main/java/ui/model/Item.java": "package ui.model;\n",
+	})
+	ps := parser.BuildProblems{
+		MissingClass: []index.JavaClass{{Name: "org.a.A"}},
+		Sources:      []string{"ui/web/src/main/java/ui/Fx.java"},
+	}
+	js := importedClasses(ps, ws)
+	if len(js) != 1 || js[0].Name != "org.b.B" {
+		t.Fatalf("want org.b.B but got %+v\n", js)
+	}
+}
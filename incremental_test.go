@@ -0,0 +1,47 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+func TestFromSourceIncremental(t *testing.T) {
+	ws := t.TempDir()
+	fixtureFiles(t, ws, map[string]string{
+		"ui/web/src/main/kotlin/Fx.kt":   "package ui.web\n\nclass Fx\n",
+		"core/src/main/java/core/A.java": "package core;\n",
+	})
+	deps, _ := index.FromSource(ws, index.Sources{},
+		namings["segment"], nil, 4)
+	// marked resources survive an unchanged module only
+	prev := make(index.Previous)
+	for _, d := range deps {
+		d.Resources = index.Resources([]string{"marked.M"}, nil)
+		prev[d.ExternalReference] = d
+	}
+	kt := filepath.Join(ws, "ui/web/src/main/kotlin/Fx.kt")
+	err := ioutil.WriteFile(kt,
+		[]byte("package ui.web\n\nclass Fx\n\nclass Fy\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deps, _ = index.FromSource(ws, index.Sources{},
+		namings["segment"], prev, 4)
+	for _, tt := range []struct {
+		name  string
+		class string
+	}{
+		{"core", "marked.M"},
+		{"web", "ui.web.Fy"},
+	} {
+		for _, d := range deps {
+			if d.Name == tt.name && !d.Provides(index.Class, tt.class) {
+				t.Fatalf("%s: want %s but got %+v\n", tt.name,
+					tt.class, d.Resources)
+			}
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package index
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Confidence of a suggested fix
+type Confidence int
+
+const (
+	// Low confidence, such as fuzzy or knowledge-base matches
+	Low Confidence = iota
+	// Medium confidence, such as package level or ambiguous matches
+	Medium
+	// High confidence, such as an exact class match in a single provider
+	High
+)
+
+var confidences = []string{"low", "medium", "high"}
+
+// String is the name of a confidence, such as high
+func (a Confidence) String() string {
+	return confidences[a]
+}
+
+// MarshalText encodes a confidence by name, such as high
+func (a Confidence) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText decodes a confidence by name
+func (a *Confidence) UnmarshalText(text []byte) error {
+	c, err := ParseConfidence(string(text))
+	*a = c
+	return err
+}
+
+// ParseConfidence parses a confidence by name, ignoring case
+func ParseConfidence(s string) (Confidence, error) {
+	for i, c := range confidences {
+		if strings.EqualFold(s, c) {
+			return Confidence(i), nil
+		}
+	}
+	return Low, fmt.Errorf("unknown confidence %q, want one of %v",
+		s, confidences)
+}
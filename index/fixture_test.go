@@ -0,0 +1,40 @@
+package index
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// write a jar containing a manifest and empty class files
+func fixtureJar(t *testing.T, filename string, classes ...string) {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	entries := []string{"META-INF/MANIFEST.MF"}
+	for _, c := range classes {
+		entries = append(entries,
+			strings.Replace(c, ".", "/", -1)+".class")
+	}
+	for _, e := range entries {
+		if _, err := w.Create(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// class resources, including their packages
+func classes(names ...string) []Resource {
+	return Resources(names, nil)
+}
@@ -0,0 +1,255 @@
+// Package index maps Java classes to the dependencies providing them: source
+// modules, jars, and rules_jvm_external artifacts.
+package index
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// JavaClass is a fully qualified class, such as org.junit.Test
+type JavaClass struct {
+	Module   string // Maven: relative module path
+	Layout   string // Maven: src/main/java
+	Name     string
+	Location string   // source file and line missing the class, if known
+	Log      []string // build log lines reporting the class
+}
+
+// Package of a class, org.junit for org.junit.Test
+func (a JavaClass) Package() string {
+	return StripLast(a.Name)
+}
+
+// Dependency provides classes, such as a source module or a jar
+type Dependency struct {
+	Name              string
+	ExternalReference string
+	Resources         []Resource
+	Artifact          string // Maven: group:artifact:version
+	Kind              Kind
+	TestOnly          bool   // tests classifier, provides to test rules only
+	Stamp             string // of the indexed files, see Stamp()
+	Module            string // sources: module directory, such as ui/web
+}
+
+// Stamp identifies the state of files by name, size and modification time,
+// "" if any of them is missing
+func Stamp(files []string) string {
+	sorted := append([]string{}, files...)
+	sort.Strings(sorted)
+	h := sha1.New()
+	for _, f := range sorted {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return ""
+		}
+		fmt.Fprintf(h, "%s %d %d\n", f, fi.Size(), fi.ModTime().UnixNano())
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Production dependencies, without test only ones
+func Production(deps []Dependency) []Dependency {
+	var ds []Dependency
+	for _, d := range deps {
+		if !d.TestOnly {
+			ds = append(ds, d)
+		}
+	}
+	return ds
+}
+
+// Kind of provider behind a dependency
+type Kind string
+
+const (
+	Source           Kind = "source"
+	KotlinSource     Kind = "kotlin_source"
+	ScalaSource      Kind = "scala_source"
+	MavenJar         Kind = "maven_jar"
+	RulesJvmExternal Kind = "rules_jvm_external"
+	JavaImport       Kind = "java_import"
+	Genrule          Kind = "genrule"
+	Built            Kind = "built" // output jar of a rule in bazel-bin
+)
+
+// External dependencies are backed by third party jars
+func (a Kind) External() bool {
+	switch a {
+	case MavenJar, RulesJvmExternal, JavaImport:
+		return true
+	}
+	return false
+}
+
+// StripLast removes the last '.' and the following segment
+func StripLast(s string) string {
+	parts := strings.Split(s, ".")
+	// strip class name
+	parts = parts[0 : len(parts)-1]
+	return strings.Join(parts, ".")
+}
+
+// Content lists the classes and files of a jar
+func Content(jar string) ([]Resource, error) {
+	r, err := zip.OpenReader(jar)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var classes, files []string
+	for _, f := range r.File {
+		// Files in jars are / separated, and end in .class
+		if strings.HasSuffix(f.Name, ".class") {
+			clazz := strings.TrimSuffix(
+				strings.Replace(f.Name, "/", ".", -1),
+				".class")
+			classes = append(classes, clazz)
+		} else if !strings.HasSuffix(f.Name, "/") &&
+			f.Name != "META-INF/MANIFEST.MF" {
+			files = append(files, f.Name)
+		}
+	}
+	return Resources(classes, files), nil
+}
+
+// Cache is the persistent class index
+type Cache struct {
+	Dependencies []Dependency
+	Names        map[string]string // generated rule name -> module dir
+	Sample       int               // percent of jars and modules indexed
+}
+
+// CountKinds counts dependencies per kind, such as 3 source, 12 maven_jar
+func CountKinds(deps []Dependency) string {
+	counts := make(map[Kind]int)
+	var kinds []string
+	for _, d := range deps {
+		if counts[d.Kind] == 0 {
+			kinds = append(kinds, string(d.Kind))
+		}
+		counts[d.Kind]++
+	}
+	sort.Strings(kinds)
+	var ss []string
+	for _, k := range kinds {
+		ss = append(ss, fmt.Sprintf("%d %s", counts[Kind(k)], k))
+	}
+	return strings.Join(ss, ", ")
+}
+
+// ReadCache reads a cache written by UpdateCache
+func ReadCache(filename string) (Cache, error) {
+	var c Cache
+	f, err := os.Open(filename)
+	if err != nil {
+		return c, err
+	}
+	defer f.Close()
+	dec := gob.NewDecoder(f)
+	err = dec.Decode(&c)
+	if err != nil {
+		return c, fmt.Errorf("cannot read cache %s, rerun -update: %v",
+			filename, err)
+	}
+	// caches written before kinds were recorded
+	for i, d := range c.Dependencies {
+		if d.Kind != "" {
+			continue
+		}
+		if strings.HasPrefix(d.Name, "//external:") {
+			c.Dependencies[i].Kind = MavenJar
+		} else {
+			c.Dependencies[i].Kind = Source
+		}
+	}
+	if c.Sample > 0 && c.Sample < 100 {
+		log.Printf("cache %s indexes a %d%% sample only, rerun -update "+
+			"without -sample for complete results\n", filename,
+			c.Sample)
+	}
+	return c, nil
+}
+
+// UpdateCache writes a cache, replacing an existing one
+func UpdateCache(filename string, c Cache) error {
+	// Gobify
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	log.Printf("updated cache %s\n", filename)
+	return nil
+}
+
+// rank of a dependency among several providing a class, lower is better:
+// sources of the workspace, then artifacts pinned by a rules_jvm_external
+// lock file, then other jars. Shaded and duplicated classes live in jars.
+func (a Kind) rank() int {
+	switch {
+	case !a.External():
+		return 0
+	case a == RulesJvmExternal:
+		return 1
+	}
+	return 2
+}
+
+// FindClass finds the dependency providing a class. Confidence is high if
+// exactly one dependency provides the class, medium if several do, or if
+// only another class of the same package is provided. Of several, the best
+// ranking wins, and the order of deps decides among equals, which is
+// ambiguous.
+func FindClass(j JavaClass, deps []Dependency) (*Dependency, Confidence) {
+	log.Printf("looking for dependency providing class %s\n", j.Name)
+	var found []Dependency
+	for _, d := range deps {
+		if d.Provides(Class, j.Name) {
+			found = append(found, d)
+		}
+	}
+	if len(found) == 1 {
+		return &found[0], High
+	}
+	if len(found) > 1 {
+		sort.SliceStable(found, func(i, k int) bool {
+			return found[i].Kind.rank() < found[k].Kind.rank()
+		})
+		var names []string
+		for _, d := range found {
+			names = append(names, d.Name)
+		}
+		if found[0].Kind.rank() == found[1].Kind.rank() {
+			log.Printf("warning: class %s is ambiguous, provided by "+
+				"%s, choosing %s\n", j.Name,
+				strings.Join(names, ", "), found[0].Name)
+		} else {
+			log.Printf("class %s is provided by %s, choosing %s of "+
+				"kind %s\n", j.Name, strings.Join(names, ", "),
+				found[0].Name, found[0].Kind)
+		}
+		return &found[0], Medium
+	}
+	for _, d := range deps {
+		if d.Provides(Package, j.Package()) {
+			log.Printf("package %s provided by %s\n",
+				j.Package(), d.Name)
+			return &d, Medium
+		}
+	}
+	return nil, Low
+}
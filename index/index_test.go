@@ -0,0 +1,132 @@
+package index
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func ExampleStripLast() {
+	fmt.Println(StripLast("a.b.c.d"))
+	// Output: a.b.c
+}
+
+func TestJarContent(t *testing.T) {
+	jar := filepath.Join(t.TempDir(), "junit-4.10.jar")
+	fixtureJar(t, jar, "org.junit.Test", "org.junit.Assert",
+		"org.junit.runner.JUnitCore")
+	rs, err := Content(jar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := Dependency{Resources: rs}
+	want := 3
+	got := len(d.Named(Class))
+	if want != got {
+		t.Fatalf("want %v but got %v\n", want, got)
+	}
+	if !d.Provides(Package, "org.junit.runner") {
+		t.Fatalf("want package org.junit.runner but got %+v\n",
+			d.Resources)
+	}
+}
+
+func TestPackage(t *testing.T) {
+	j := JavaClass{Name: "org.company.framework.A"}
+	want := "org.company.framework"
+	got := j.Package()
+	if want != got {
+		t.Fatalf("want %s but got %s\n", want, got)
+	}
+}
+
+func TestFindClass(t *testing.T) {
+	deps := []Dependency{
+		{Name: "a", Resources: classes("org.a.A", "org.b.B")},
+		{Name: "b", Resources: classes("org.b.B", "org.c.C")},
+	}
+	for _, tt := range []struct {
+		class string
+		name  string
+		c     Confidence
+	}{
+		{"org.a.A", "a", High},
+		{"org.b.B", "a", Medium},
+		{"org.c.D", "b", Medium},
+	} {
+		d, c := FindClass(JavaClass{Name: tt.class}, deps)
+		if d == nil || d.Name != tt.name || c != tt.c {
+			t.Fatalf("%s: want %s (%s) but got %+v (%s)\n",
+				tt.class, tt.name, tt.c, d, c)
+		}
+	}
+	if d, _ := FindClass(JavaClass{Name: "org.x.X"}, deps); d != nil {
+		t.Fatalf("want no provider but got %+v\n", d)
+	}
+}
+
+func TestFindClassRanking(t *testing.T) {
+	deps := []Dependency{
+		{Name: "shaded", Kind: MavenJar, Resources: classes("org.a.A")},
+		{Name: "@maven//:a", Kind: RulesJvmExternal,
+			Resources: classes("org.a.A", "org.b.B")},
+		{Name: "b", Kind: MavenJar, Resources: classes("org.b.B")},
+		{Name: "a", Kind: Source, Resources: classes("org.a.A")},
+		{Name: "@maven//:c", Kind: RulesJvmExternal,
+			Resources: classes("org.c.C")},
+		{Name: "@other//:c", Kind: RulesJvmExternal,
+			Resources: classes("org.c.C")},
+	}
+	for class, want := range map[string]string{
+		"org.a.A": "a",
+		"org.b.B": "@maven//:a",
+		// equals keep their order
+		"org.c.C": "@maven//:c",
+	} {
+		d, c := FindClass(JavaClass{Name: class}, deps)
+		if d == nil || d.Name != want || c != Medium {
+			t.Fatalf("%s: want %s but got %+v (%s)\n", class, want,
+				d, c)
+		}
+	}
+}
+
+func TestReadCacheKinds(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), ".healdb")
+	// cache written before kinds were recorded
+	err := UpdateCache(filename, Cache{Dependencies: []Dependency{
+		{Name: "//external:junit"},
+		{Name: "ui_web"},
+		{Name: "framework"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := ReadCache(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deps := c.Dependencies
+	for i, want := range []Kind{MavenJar, Source, Source} {
+		if want != deps[i].Kind {
+			t.Fatalf("%s: want %s but got %s\n", deps[i].Name, want,
+				deps[i].Kind)
+		}
+	}
+	want := "1 maven_jar, 2 source"
+	got := CountKinds(deps)
+	if want != got {
+		t.Fatalf("want %s but got %s\n", want, got)
+	}
+}
+
+func TestJarContentUnreadable(t *testing.T) {
+	jar := filepath.Join(t.TempDir(), "empty.jar")
+	if err := ioutil.WriteFile(jar, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Content(jar); err == nil {
+		t.Fatalf("want error for zero-length jar\n")
+	}
+}
@@ -0,0 +1,52 @@
+package index
+
+import (
+	"bufio"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	REKotlinPackage = regexp.MustCompile(`^package\s+([\w.]+)`)
+	// top level declarations start in the first column
+	REKotlinClass = regexp.MustCompile(`^(?:(?:public|internal|data|` +
+		`sealed|abstract|open|enum|annotation|value|inline|fun)\s+)*` +
+		`(?:class|interface|object)\s+(\w+)`)
+	REKotlinTopLevel = regexp.MustCompile(
+		`^(?:(?:public|internal|inline|suspend)\s+)*(?:fun|val|var)\s`)
+)
+
+// KotlinClasses are the JVM classes of a Kotlin source file. Unlike Java, a
+// Kotlin file may live in any directory, so the package comes from the
+// package declaration. Top level functions and properties compile into the
+// file facade class, FooKt for Foo.kt.
+func KotlinClasses(filename string, r io.Reader) []string {
+	pkg := ""
+	var names []string
+	facade := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := REKotlinPackage.FindStringSubmatch(line); m != nil {
+			pkg = m[1]
+		} else if m := REKotlinClass.FindStringSubmatch(line); m != nil {
+			names = append(names, m[1])
+		} else if REKotlinTopLevel.MatchString(line) {
+			facade = true
+		}
+	}
+	if facade {
+		base := strings.TrimSuffix(filepath.Base(filename), ".kt")
+		names = append(names, strings.ToUpper(base[:1])+base[1:]+"Kt")
+	}
+	var cs []string
+	for _, n := range names {
+		if pkg != "" {
+			n = pkg + "." + n
+		}
+		cs = append(cs, n)
+	}
+	return cs
+}
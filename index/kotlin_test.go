@@ -0,0 +1,27 @@
+package index
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKotlinClasses(t *testing.T) {
+	src := `package ui.web
+
+import org.a.A
+
+data class Item(val name: String)
+
+internal sealed class State {
+    object Idle : State()
+}
+
+fun render(item: Item) = item.name
+`
+	cs := KotlinClasses("ui/web/src/main/kotlin/render.kt",
+		strings.NewReader(src))
+	want := []string{"ui.web.Item", "ui.web.State", "ui.web.RenderKt"}
+	if strings.Join(want, " ") != strings.Join(cs, " ") {
+		t.Fatalf("want %v but got %v\n", want, cs)
+	}
+}
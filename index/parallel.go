@@ -0,0 +1,29 @@
+package index
+
+import (
+	"sync"
+)
+
+// Parallel calls f for 0..n-1 on up to jobs goroutines, and waits for all
+// of them
+func Parallel(n int, jobs int, f func(i int)) {
+	if jobs < 1 {
+		jobs = 1
+	}
+	is := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range is {
+				f(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		is <- i
+	}
+	close(is)
+	wg.Wait()
+}
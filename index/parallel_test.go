@@ -0,0 +1,20 @@
+package index
+
+import (
+	"testing"
+)
+
+func TestParallel(t *testing.T) {
+	for _, jobs := range []int{0, 1, 3, 20} {
+		squares := make([]int, 10)
+		Parallel(len(squares), jobs, func(i int) {
+			squares[i] = i * i
+		})
+		for i, got := range squares {
+			if i*i != got {
+				t.Fatalf("jobs %d: want %d but got %d\n", jobs,
+					i*i, got)
+			}
+		}
+	}
+}
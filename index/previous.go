@@ -0,0 +1,46 @@
+package index
+
+import (
+	"log"
+	"os"
+)
+
+// Previous is the previous cache of -incremental, dependencies by their
+// indexed jar or source directory. nil re-indexes everything.
+type Previous map[string]Dependency
+
+// ReadPrevious reads the dependencies of the cache -update is about to
+// replace, nil if there is no cache yet
+func ReadPrevious(cachefile string, store bool) (Previous, error) {
+	var c Cache
+	var err error
+	if store {
+		var st *Store
+		st, err = OpenStore(cachefile)
+		if err == nil {
+			c, err = st.Cache()
+		}
+	} else {
+		c, err = ReadCache(cachefile)
+	}
+	if os.IsNotExist(err) {
+		log.Printf("no cache %s yet, indexing everything\n", cachefile)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	prev := make(Previous)
+	for _, d := range c.Dependencies {
+		prev[d.ExternalReference] = d
+	}
+	return prev, nil
+}
+
+// Unchanged returns the previous dependency of d, if it indexed the very
+// same files
+func (a Previous) Unchanged(d Dependency) (Dependency, bool) {
+	old, ok := a[d.ExternalReference]
+	return old, ok && d.Stamp != "" && old.Stamp == d.Stamp &&
+		old.Name == d.Name && old.Kind == d.Kind
+}
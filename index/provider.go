@@ -0,0 +1,42 @@
+package index
+
+import (
+	"sync"
+)
+
+// DependencyProvider contributes dependencies of a workspace to the index,
+// such as the artifacts of an in-house artifact service
+type DependencyProvider func(workspace string) ([]Dependency, error)
+
+var (
+	providersMu sync.Mutex
+	providers   []DependencyProvider
+)
+
+// RegisterProvider adds a provider consulted on every index update, after
+// the built-in ones. Register from an init function, as database/sql
+// drivers do.
+func RegisterProvider(p DependencyProvider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers = append(providers, p)
+}
+
+// Provided dependencies of all registered providers, in order of
+// registration. Errors of one provider do not stop the others, the first one
+// is returned.
+func Provided(workspace string) ([]Dependency, error) {
+	providersMu.Lock()
+	ps := append([]DependencyProvider{}, providers...)
+	providersMu.Unlock()
+	var deps []Dependency
+	var first error
+	for _, p := range ps {
+		ds, err := p(workspace)
+		if err != nil && first == nil {
+			first = err
+		}
+		deps = append(deps, ds...)
+	}
+	return deps, first
+}
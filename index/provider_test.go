@@ -0,0 +1,28 @@
+package index
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRegisterProvider(t *testing.T) {
+	defer func(ps []DependencyProvider) { providers = ps }(providers)
+	providers = nil
+	RegisterProvider(func(workspace string) ([]Dependency, error) {
+		return []Dependency{{Name: "@nexus//:a", Kind: MavenJar}}, nil
+	})
+	RegisterProvider(func(workspace string) ([]Dependency, error) {
+		return nil, errors.New("offline")
+	})
+	RegisterProvider(func(workspace string) ([]Dependency, error) {
+		return []Dependency{{Name: workspace + ":b", Kind: Source}}, nil
+	})
+	deps, err := Provided("//ws")
+	if err == nil || err.Error() != "offline" {
+		t.Fatalf("want error offline but got %v\n", err)
+	}
+	if len(deps) != 2 || deps[0].Name != "@nexus//:a" ||
+		deps[1].Name != "//ws:b" {
+		t.Fatalf("want deps of both providers but got %+v\n", deps)
+	}
+}
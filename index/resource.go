@@ -0,0 +1,99 @@
+package index
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ResourceType tells what a resource of a dependency names
+type ResourceType int
+
+const (
+	// Class is a fully qualified class name, such as org.junit.Test
+	Class ResourceType = iota
+	// Package is a Java package, such as org.junit
+	Package
+	// File is a non-class file, such as META-INF/services/x.Y
+	File
+)
+
+var resourceTypes = []string{"class", "package", "file"}
+
+// String is the name of a resource type, such as class
+func (a ResourceType) String() string {
+	return resourceTypes[a]
+}
+
+// Resource provided by a dependency
+type Resource struct {
+	Type ResourceType
+	Name string
+}
+
+// Resources for a list of classes and files, including all packages of the
+// classes
+func Resources(classes, files []string) []Resource {
+	var rs []Resource
+	pkgs := make(map[string]bool)
+	for _, c := range classes {
+		rs = append(rs, Resource{Class, c})
+		pkgs[StripLast(c)] = true
+	}
+	var ps []string
+	for p := range pkgs {
+		ps = append(ps, p)
+	}
+	sort.Strings(ps)
+	for _, p := range ps {
+		rs = append(rs, Resource{Package, p})
+	}
+	for _, f := range files {
+		rs = append(rs, Resource{File, f})
+	}
+	return rs
+}
+
+// Named lists the names of all resources of a given type
+func (a Dependency) Named(t ResourceType) []string {
+	var ss []string
+	for _, r := range a.Resources {
+		if r.Type == t {
+			ss = append(ss, r.Name)
+		}
+	}
+	return ss
+}
+
+// Provides reports whether a dependency has a resource
+func (a Dependency) Provides(t ResourceType, name string) bool {
+	for _, r := range a.Resources {
+		if r.Type == t && r.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Evidence of a dependency providing a class: the jar entry or source file
+// of the class, or the package if only the package is known
+func (a Dependency) Evidence(j JavaClass) string {
+	path := strings.Replace(j.Name, ".", "/", -1)
+	if !a.Provides(Class, j.Name) {
+		return fmt.Sprintf("%s %s provides package %s", a.Kind, a.Name,
+			j.Package())
+	}
+	switch {
+	case strings.HasSuffix(a.ExternalReference, ".jar"):
+		return fmt.Sprintf("jar entry %s!/%s.class",
+			a.ExternalReference, path)
+	case a.Kind == Source:
+		return fmt.Sprintf("source file %s%s.java",
+			a.ExternalReference, path)
+	case a.ExternalReference != "":
+		// Kotlin and Scala classes may live in any file
+		return fmt.Sprintf("source directory %s declares %s",
+			a.ExternalReference, j.Name)
+	}
+	return fmt.Sprintf("%s %s provides class %s", a.Kind, a.Name, j.Name)
+}
@@ -0,0 +1,56 @@
+package index
+
+import (
+	"testing"
+)
+
+func TestResources(t *testing.T) {
+	d := Dependency{Resources: Resources(
+		[]string{"org.a.A", "org.a.B", "org.b.C"},
+		[]string{"META-INF/services/org.a.A"})}
+	for _, tt := range []struct {
+		t    ResourceType
+		name string
+		want bool
+	}{
+		{Class, "org.a.A", true},
+		{Class, "org.a", false},
+		{Package, "org.a", true},
+		{Package, "org.b", true},
+		{Package, "org", false},
+		{File, "META-INF/services/org.a.A", true},
+	} {
+		if got := d.Provides(tt.t, tt.name); tt.want != got {
+			t.Fatalf("%s %s: want %v but got %v\n", tt.t, tt.name,
+				tt.want, got)
+		}
+	}
+	want := 2
+	got := len(d.Named(Package))
+	if want != got {
+		t.Fatalf("want %d packages but got %d\n", want, got)
+	}
+}
+
+func TestEvidence(t *testing.T) {
+	j := JavaClass{Name: "org.a.A"}
+	for _, tt := range []struct {
+		d    Dependency
+		want string
+	}{
+		{Dependency{ExternalReference: "/ob/a-1.0.jar",
+			Resources: Resources([]string{"org.a.A"}, nil)},
+			"jar entry /ob/a-1.0.jar!/org/a/A.class"},
+		{Dependency{ExternalReference: "a/src/main/java/",
+			Resources: Resources([]string{"org.a.A"}, nil),
+			Kind:      Source},
+			"source file a/src/main/java/org/a/A.java"},
+		{Dependency{Name: "@maven//:a", Kind: RulesJvmExternal,
+			Resources: []Resource{{Type: Package, Name: "org.a"}}},
+			"rules_jvm_external @maven//:a provides package org.a"},
+	} {
+		if got := tt.d.Evidence(j); tt.want != got {
+			t.Fatalf("want %s but got %s\n", tt.want, got)
+		}
+	}
+}
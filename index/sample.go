@@ -0,0 +1,32 @@
+package index
+
+import (
+	"hash/fnv"
+)
+
+// Sampled reports whether a jar or module belongs to a sample of percent
+// percent. Membership depends on the name only, so repeated runs index the
+// same sample.
+func Sampled(name string, percent int) bool {
+	if percent >= 100 {
+		return true
+	}
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return int(h.Sum32()%100) < percent
+}
+
+// keep sampled source dependencies only
+func sampleSources(deps []Dependency, names map[string]string,
+	percent int) ([]Dependency, map[string]string) {
+	var ds []Dependency
+	ns := make(map[string]string)
+	for _, d := range deps {
+		dir := names[d.Name]
+		if Sampled(dir, percent) {
+			ds = append(ds, d)
+			ns[d.Name] = dir
+		}
+	}
+	return ds, ns
+}
@@ -0,0 +1,39 @@
+package index
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSampled(t *testing.T) {
+	n := 0
+	for i := 0; i < 1000; i++ {
+		name := fmt.Sprintf("//external:dep%d", i)
+		if Sampled(name, 10) {
+			n++
+		}
+		if Sampled(name, 10) != Sampled(name, 10) {
+			t.Fatalf("%s: want stable sample\n", name)
+		}
+		if !Sampled(name, 100) {
+			t.Fatalf("%s: want full sample at 100%%\n", name)
+		}
+	}
+	// roughly 10%
+	if n < 50 || n > 150 {
+		t.Fatalf("want about 100 of 1000 but got %d\n", n)
+	}
+}
+
+func TestSampleSources(t *testing.T) {
+	deps := []Dependency{{Name: "core"}, {Name: "web"}}
+	names := map[string]string{"core": "/ws/core", "web": "/ws/ui/web"}
+	ds, ns := sampleSources(deps, names, 0)
+	if len(ds) != 0 || len(ns) != 0 {
+		t.Fatalf("want empty sample but got %+v\n", ds)
+	}
+	ds, ns = sampleSources(deps, names, 100)
+	if len(ds) != len(deps) || len(ns) != len(names) {
+		t.Fatalf("want all %d modules but got %+v\n", len(deps), ds)
+	}
+}
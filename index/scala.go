@@ -0,0 +1,46 @@
+package index
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	// chained package clauses add up, package a; package b is a.b
+	REScalaPackage = regexp.MustCompile(`^package\s+([\w.]+)\s*$`)
+	// top level declarations start in the first column
+	REScalaClass = regexp.MustCompile(`^(?:(?:final|sealed|abstract|` +
+		`implicit|case|private\[\w+\])\s+)*` +
+		`(?:class|trait|object)\s+(\w+)`)
+	REScalaPackageObject = regexp.MustCompile(`^package\s+object\s+(\w+)`)
+)
+
+// ScalaClasses are the JVM classes of a Scala source file. Like Kotlin, the
+// package comes from the package clauses, not the directory. Objects also
+// compile into a Name$ class, but callers only ever see Name.
+func ScalaClasses(r io.Reader) []string {
+	var pkgs []string
+	var names []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := REScalaPackageObject.FindStringSubmatch(line); m != nil {
+			names = append(names, m[1]+".package")
+		} else if m := REScalaPackage.FindStringSubmatch(line); m != nil {
+			pkgs = append(pkgs, m[1])
+		} else if m := REScalaClass.FindStringSubmatch(line); m != nil {
+			names = append(names, m[1])
+		}
+	}
+	pkg := strings.Join(pkgs, ".")
+	var cs []string
+	for _, n := range names {
+		if pkg != "" {
+			n = pkg + "." + n
+		}
+		cs = append(cs, n)
+	}
+	return cs
+}
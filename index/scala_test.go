@@ -0,0 +1,26 @@
+package index
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScalaClasses(t *testing.T) {
+	src := `package ui
+package web
+
+import org.a.A
+
+case class Item(name: String)
+
+sealed trait State
+object State {
+  case object Idle extends State
+}
+`
+	cs := ScalaClasses(strings.NewReader(src))
+	want := []string{"ui.web.Item", "ui.web.State", "ui.web.State"}
+	if strings.Join(want, " ") != strings.Join(cs, " ") {
+		t.Fatalf("want %v but got %v\n", want, cs)
+	}
+}
@@ -0,0 +1,283 @@
+package index
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Scan recursively scans dir for files matching extension, skipping
+// ignored directories relative to dir
+func Scan(dir string, extension string, ignore ...string) []string {
+	log.Printf("recursively scanning %s for %s files\n", dir, extension)
+	var files []string
+	// filepath.Glob() is not recursive
+	f := func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.IsDir() && len(ignore) > 0 {
+			rel, _ := filepath.Rel(dir, path)
+			if ignored(ignore, filepath.ToSlash(rel)) {
+				return filepath.SkipDir
+			}
+		}
+		if strings.HasSuffix(path, extension) {
+			files = append(files, path)
+		}
+		return nil
+	}
+	filepath.Walk(dir, f)
+	log.Printf("found %d files\n", len(files))
+	return files
+}
+
+// RuleName converts a module directory into a Bazel-valid rule name.
+// Anything but ASCII letters, digits and '_' becomes '_'.
+func RuleName(dir string) string {
+	var sb strings.Builder
+	for _, r := range dir {
+		if r < utf8.RuneSelf && (unicode.IsLetter(r) ||
+			unicode.IsDigit(r) || r == '_') {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteByte('_')
+		}
+	}
+	return sb.String()
+}
+
+// RuleNames are collision-free rule names for module directories, named by
+// naming. If several directories map to the same name, such as a/b_c and
+// a_b/c, they are numbered in lexical order.
+func RuleNames(dirs []string,
+	naming func(dir string) string) map[string]string {
+	sorted := append([]string(nil), dirs...)
+	sort.Strings(sorted)
+	used := make(map[string]bool)
+	names := make(map[string]string)
+	for _, dir := range sorted {
+		base := RuleName(naming(dir))
+		n := base
+		for i := 2; used[n]; i++ {
+			n = fmt.Sprintf("%s_%d", base, i)
+		}
+		used[n] = true
+		names[dir] = n
+	}
+	return names
+}
+
+// Sources tells where modules keep their sources
+type Sources struct {
+	// Java source roots within modules, src/main/java if empty
+	Layouts []string
+	// workspace directories never scanned, such as third_party/legacy
+	Ignore []string
+}
+
+// test sources of a module, and the suffix of the testonly library
+// generated from them
+const (
+	testSep    = "/src/test/java/"
+	testSuffix = "_tests"
+)
+
+// FromSource converts source files from the same source folder
+// into single dependencies
+// Name is the derived/ suggested rule name
+// external reference is the source path into the module, such as
+// ui/web/src/main/java
+// The returned map resolves rule names back into module directories.
+// Test sources become test only dependencies of their own, named
+// <module>_tests.
+// Modules whose source files did not change since the previous cache are
+// not parsed again, the others are parsed by jobs goroutines.
+func FromSource(dir string, srcs Sources, naming func(dir string) string,
+	prev Previous, jobs int) ([]Dependency, map[string]string) {
+	layouts := srcs.Layouts
+	if len(layouts) == 0 {
+		layouts = []string{"src/main/java"}
+	}
+	var quoted []string
+	for _, l := range layouts {
+		quoted = append(quoted, regexp.QuoteMeta(strings.Trim(l, "/")))
+	}
+	files := Scan(dir, ".java", srcs.Ignore...)
+
+	// split workspace relative paths into module, layout, and class name.
+	// The first layout within the workspace wins, as java may also be a
+	// package. Sources of a single module repository have no module, their
+	// rules go into the root package.
+	var RESrcMainJava = regexp.MustCompile("^(?:(.+?)/)?(" +
+		strings.Join(quoted, "|") + ")/(.+)$")
+	var RESrcTestJava = regexp.MustCompile("^(?:(.+?)/)?" +
+		strings.TrimPrefix(testSep, "/") + "(.+)$")
+
+	// map of source directory and contained source files
+	modules := make(map[string][]string)
+	sources := make(map[string][]string)
+	// source root of each module, the first layout found
+	roots := make(map[string]string)
+	// test classes of modules, for test rules only
+	tests := make(map[string][]string)
+	for _, f := range files {
+		rel, _ := filepath.Rel(dir, f)
+		rel = filepath.ToSlash(rel)
+		if matches := RESrcTestJava.FindStringSubmatch(rel); len(matches) == 3 {
+			k := filepath.Join(dir, filepath.FromSlash(matches[1]))
+			tests[k] = append(tests[k],
+				strings.TrimSuffix(strings.Replace(matches[2], "/",
+					".", -1), ".java"))
+			continue
+		}
+		matches := RESrcMainJava.FindStringSubmatch(rel)
+		if len(matches) == 4 {
+			srcdir := filepath.Join(dir, filepath.FromSlash(matches[1]))
+			file := matches[3]
+			if _, ok := roots[srcdir]; !ok {
+				roots[srcdir] = "/" + matches[2] + "/"
+			}
+			clazz := strings.TrimSuffix(
+				strings.Replace(file, "/", ".", -1),
+				".java")
+			modules[srcdir] = append(modules[srcdir], clazz)
+			sources[srcdir] = append(sources[srcdir], f)
+		} else {
+			log.Printf("skip %s, missing %s?\n", f,
+				strings.Join(layouts, " or "))
+		}
+	}
+	// Kotlin modules, their sources may also live in src/main/java
+	kotlin := moduleFiles(dir, Scan(dir, ".kt", srcs.Ignore...),
+		regexp.MustCompile("^(?:(.+?)/)?src/main/(?:kotlin|java)/"),
+		"src/main/kotlin")
+	// Scala modules, scala_library compiles the Java sources as well
+	scala := moduleFiles(dir, Scan(dir, ".scala", srcs.Ignore...),
+		regexp.MustCompile("^(?:(.+?)/)?src/main/(?:scala|java)/"),
+		"src/main/scala")
+	for _, m := range []map[string][]string{kotlin, scala} {
+		for k, fs := range m {
+			sources[k] = append(sources[k], fs...)
+		}
+	}
+
+	var dirs []string
+	for k := range sources {
+		dirs = append(dirs, k)
+	}
+	all := append([]string{}, dirs...)
+	for k := range tests {
+		if _, ok := sources[k]; !ok {
+			all = append(all, k)
+		}
+	}
+	names := RuleNames(all, naming)
+
+	// Convert into dependencies
+	deps := make([]Dependency, len(dirs))
+	unchanged := make([]bool, len(dirs))
+	Parallel(len(dirs), jobs, func(i int) {
+		k := dirs[i]
+		d := Dependency{
+			Name:              names[k],
+			ExternalReference: k + roots[k],
+			Kind:              Source,
+			Stamp:             Stamp(sources[k]),
+			Module:            k,
+		}
+		if len(kotlin[k]) > 0 {
+			d.ExternalReference = k + "/src/main/"
+			d.Kind = KotlinSource
+		}
+		if len(scala[k]) > 0 {
+			d.ExternalReference = k + "/src/main/"
+			d.Kind = ScalaSource
+		}
+		if old, ok := prev.Unchanged(d); ok {
+			d.Resources = old.Resources
+			unchanged[i] = true
+		} else {
+			classes := modules[k]
+			for _, f := range kotlin[k] {
+				classes = append(classes, parseFile(f,
+					KotlinClasses)...)
+			}
+			for _, f := range scala[k] {
+				classes = append(classes, parseFile(f,
+					func(_ string, r io.Reader) []string {
+						return ScalaClasses(r)
+					})...)
+			}
+			d.Resources = Resources(classes, nil)
+		}
+		deps[i] = d
+	})
+	dirsByName := make(map[string]string)
+	reused := 0
+	for i, k := range dirs {
+		dirsByName[names[k]] = k
+		if unchanged[i] {
+			reused++
+		}
+	}
+	if prev != nil {
+		log.Printf("%d of %d modules unchanged\n", reused, len(deps))
+	}
+	for k, classes := range tests {
+		name := names[k] + testSuffix
+		deps = append(deps, Dependency{
+			Name:              name,
+			ExternalReference: k + testSep,
+			Resources:         Resources(classes, nil),
+			Kind:              Source,
+			TestOnly:          true,
+			Module:            k,
+		})
+		dirsByName[name] = k
+	}
+	return deps, dirsByName
+}
+
+// source files per module directory, the first submatch of re on the path
+// relative to dir. An empty submatch is the module of dir itself.
+func moduleFiles(dir string, files []string, re *regexp.Regexp,
+	layout string) map[string][]string {
+	m := make(map[string][]string)
+	for _, f := range files {
+		rel, _ := filepath.Rel(dir, f)
+		matches := re.FindStringSubmatch(filepath.ToSlash(rel))
+		if len(matches) != 2 {
+			log.Printf("skip %s, missing %s?\n", f, layout)
+			continue
+		}
+		k := filepath.Join(dir, filepath.FromSlash(matches[1]))
+		m[k] = append(m[k], f)
+	}
+	return m
+}
+
+// classes declared in a source file
+func parseFile(f string, classes func(string, io.Reader) []string) []string {
+	r, err := os.Open(f)
+	if err != nil {
+		log.Printf("skip %s: %v\n", f, err)
+		return nil
+	}
+	defer r.Close()
+	return classes(f, r)
+}
+
+// ignored reports whether a directory is one of the ignored ones
+func ignored(ignore []string, dir string) bool {
+	for _, s := range ignore {
+		if s == dir {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+package index
+
+import (
+	"testing"
+)
+
+func TestRuleName(t *testing.T) {
+	want := "_ui_web_v1_0_caf_"
+	got := RuleName("-ui/web.v1.0/café")
+	if want != got {
+		t.Fatalf("want %s but got %s\n", want, got)
+	}
+}
+
+func TestRuleNames(t *testing.T) {
+	names := RuleNames([]string{"a_b/c", "a/b_c", "a/b/c/2"},
+		func(dir string) string { return dir })
+	want := map[string]string{
+		"a/b/c/2": "a_b_c_2",
+		"a/b_c":   "a_b_c",
+		"a_b/c":   "a_b_c_3",
+	}
+	for dir, n := range want {
+		if names[dir] != n {
+			t.Fatalf("%s: want %s but got %s\n", dir, n, names[dir])
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package index
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Store is a class index kept in a directory, for workspaces too large to
+// load the whole cache. Each dependency lives in a file of its own, and a
+// sorted file maps class and package names to them. Lookups binary search
+// this file on disk, and updates rewrite changed dependencies only.
+type Store struct {
+	dir string
+}
+
+// store layout
+const (
+	storeMeta    = "meta.gob" // Cache without dependencies
+	storeDeps    = "deps"     // <key>.gob per dependency
+	storeClasses = "classes"  // sorted "c <class>\t<key>", "p <package>\t<key>"
+)
+
+// OpenStore opens the store in a directory, creating it if necessary
+func OpenStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Join(dir, storeDeps), 0755); err != nil {
+		return nil, err
+	}
+	return &Store{dir}, nil
+}
+
+// file name of a dependency
+func storeKey(name string) string {
+	sum := sha1.Sum([]byte(name))
+	return hex.EncodeToString(sum[:])
+}
+
+func (a *Store) depFile(key string) string {
+	return filepath.Join(a.dir, storeDeps, key+".gob")
+}
+
+// index lines of a dependency, sorted
+func storeLines(d Dependency, key string) []string {
+	var lines []string
+	for _, r := range d.Resources {
+		switch r.Type {
+		case Class:
+			lines = append(lines, "c "+r.Name+"\t"+key)
+		case Package:
+			lines = append(lines, "p "+r.Name+"\t"+key)
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// Update the store to hold the dependencies of a cache. Dependencies that
+// did not change are left alone, the number of changed ones is returned.
+func (a *Store) Update(c Cache) (int, error) {
+	var meta bytes.Buffer
+	c2 := c
+	c2.Dependencies = nil
+	if err := gob.NewEncoder(&meta).Encode(c2); err != nil {
+		return 0, err
+	}
+	err := ioutil.WriteFile(filepath.Join(a.dir, storeMeta), meta.Bytes(),
+		0644)
+	if err != nil {
+		return 0, err
+	}
+	// changed or removed dependencies
+	stale := make(map[string]bool)
+	var fresh []string
+	keep := make(map[string]bool)
+	for _, d := range c.Dependencies {
+		key := storeKey(d.Name)
+		keep[key] = true
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(d); err != nil {
+			return 0, err
+		}
+		old, err := ioutil.ReadFile(a.depFile(key))
+		if err == nil && bytes.Equal(old, buf.Bytes()) {
+			continue
+		}
+		err = ioutil.WriteFile(a.depFile(key), buf.Bytes(), 0644)
+		if err != nil {
+			return 0, err
+		}
+		stale[key] = true
+		fresh = append(fresh, storeLines(d, key)...)
+	}
+	files, _ := filepath.Glob(filepath.Join(a.dir, storeDeps, "*.gob"))
+	for _, f := range files {
+		key := strings.TrimSuffix(filepath.Base(f), ".gob")
+		if !keep[key] {
+			stale[key] = true
+			if err := os.Remove(f); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if len(stale) == 0 {
+		return 0, nil
+	}
+	sort.Strings(fresh)
+	return len(stale), a.merge(stale, fresh)
+}
+
+// rewrite the class file, dropping lines of stale dependencies and merging
+// in fresh lines
+func (a *Store) merge(stale map[string]bool, fresh []string) error {
+	name := filepath.Join(a.dir, storeClasses)
+	tmp, err := ioutil.TempFile(a.dir, storeClasses)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	w := bufio.NewWriter(tmp)
+	write := func(line string) {
+		w.WriteString(line)
+		w.WriteByte('\n')
+	}
+	if f, err := os.Open(name); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if stale[line[strings.LastIndex(line, "\t")+1:]] {
+				continue
+			}
+			for len(fresh) > 0 && fresh[0] < line {
+				write(fresh[0])
+				fresh = fresh[1:]
+			}
+			write(line)
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	for _, line := range fresh {
+		write(line)
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// first line starting at or after an offset, "" at the end
+func lineAt(r io.ReaderAt, size int64, off int64) string {
+	start := off
+	if off > 0 {
+		// the line containing off-1 ends before the next line starts
+		start = off - 1
+	}
+	br := bufio.NewReader(io.NewSectionReader(r, start, size-start))
+	if off > 0 {
+		if _, err := br.ReadString('\n'); err != nil {
+			return ""
+		}
+	}
+	line, _ := br.ReadString('\n')
+	return strings.TrimSuffix(line, "\n")
+}
+
+// keys of the index lines starting with prefix
+func (a *Store) keys(f *os.File, size int64, prefix string) []string {
+	lo, hi := int64(0), size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		if l := lineAt(f, size, mid); l == "" || l >= prefix {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	var keys []string
+	br := bufio.NewReader(io.NewSectionReader(f, lo, size-lo))
+	if lo > 0 {
+		br = bufio.NewReader(io.NewSectionReader(f, lo-1, size-lo+1))
+		br.ReadString('\n')
+	}
+	for {
+		line, err := br.ReadString('\n')
+		line = strings.TrimSuffix(line, "\n")
+		if !strings.HasPrefix(line, prefix) {
+			return keys
+		}
+		keys = append(keys, line[len(prefix):])
+		if err != nil {
+			return keys
+		}
+	}
+}
+
+func (a *Store) dependency(key string) (Dependency, error) {
+	var d Dependency
+	f, err := os.Open(a.depFile(key))
+	if err != nil {
+		return d, err
+	}
+	defer f.Close()
+	return d, gob.NewDecoder(f).Decode(&d)
+}
+
+// Lookup dependencies providing a class or its package, for FindClass
+func (a *Store) Lookup(j JavaClass) ([]Dependency, error) {
+	f, err := os.Open(filepath.Join(a.dir, storeClasses))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	keys := a.keys(f, fi.Size(), "c "+j.Name+"\t")
+	keys = append(keys, a.keys(f, fi.Size(), "p "+j.Package()+"\t")...)
+	seen := make(map[string]bool)
+	var deps []Dependency
+	for _, k := range keys {
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		d, err := a.dependency(k)
+		if err != nil {
+			return nil, fmt.Errorf("store %s: %v", a.dir, err)
+		}
+		deps = append(deps, d)
+	}
+	return deps, nil
+}
+
+// Classes of the store whose names start with prefix, such as org.a. for
+// the classes below package org.a
+func (a *Store) Classes(prefix string) ([]string, error) {
+	f, err := os.Open(filepath.Join(a.dir, storeClasses))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	var cs []string
+	seen := make(map[string]bool)
+	for _, k := range a.keys(f, fi.Size(), "c "+prefix) {
+		c := prefix + k[:strings.LastIndex(k, "\t")]
+		if !seen[c] {
+			seen[c] = true
+			cs = append(cs, c)
+		}
+	}
+	return cs, nil
+}
+
+// Cache loads the whole store, for commands working on all dependencies
+func (a *Store) Cache() (Cache, error) {
+	var c Cache
+	f, err := os.Open(filepath.Join(a.dir, storeMeta))
+	if err != nil {
+		return c, err
+	}
+	err = gob.NewDecoder(f).Decode(&c)
+	f.Close()
+	if err != nil {
+		return c, fmt.Errorf("cannot read store %s, rerun -update: %v",
+			a.dir, err)
+	}
+	files, _ := filepath.Glob(filepath.Join(a.dir, storeDeps, "*.gob"))
+	for _, file := range files {
+		d, err := a.dependency(strings.TrimSuffix(filepath.Base(file),
+			".gob"))
+		if err != nil {
+			return c, err
+		}
+		c.Dependencies = append(c.Dependencies, d)
+	}
+	sort.Slice(c.Dependencies, func(i, j int) bool {
+		return c.Dependencies[i].Name < c.Dependencies[j].Name
+	})
+	return c, nil
+}
@@ -0,0 +1,78 @@
+package index
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	st, err := OpenStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := Dependency{Name: "//a:a", Kind: Source,
+		Resources: Resources([]string{"org.a.A", "org.a.B"}, nil)}
+	b := Dependency{Name: "//b:b", Kind: Source,
+		Resources: Resources([]string{"org.b.C"}, nil)}
+	update := func(want int, deps ...Dependency) {
+		got, err := st.Update(Cache{Dependencies: deps})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want != got {
+			t.Fatalf("want %d changed dependencies but got %d\n",
+				want, got)
+		}
+	}
+	lookup := func(class string, want ...string) {
+		deps, err := st.Lookup(JavaClass{Name: class})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(want) != len(deps) {
+			t.Fatalf("%s: want %v but got %d dependencies\n", class,
+				want, len(deps))
+		}
+		for i := range want {
+			if want[i] != deps[i].Name {
+				t.Fatalf("%s: want %s but got %s\n", class,
+					want[i], deps[i].Name)
+			}
+		}
+	}
+	update(2, a, b)
+	update(0, a, b)
+	lookup("org.a.B", "//a:a")
+	lookup("org.b.C", "//b:b")
+	// package only
+	lookup("org.b.D", "//b:b")
+	lookup("org.x.X")
+	cs, err := st.Classes("org.a.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cs) != 2 || cs[0] != "org.a.A" || cs[1] != "org.a.B" {
+		t.Fatalf("want classes of org.a but got %q\n", cs)
+	}
+
+	b.Resources = Resources([]string{"org.b.C", "org.c.D"}, nil)
+	update(1, a, b)
+	lookup("org.c.D", "//b:b")
+	update(1, b)
+	lookup("org.a.A")
+
+	c, err := st.Cache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Dependencies) != 1 || c.Dependencies[0].Name != "//b:b" {
+		t.Fatalf("want //b:b only but got %d dependencies\n",
+			len(c.Dependencies))
+	}
+}
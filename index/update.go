@@ -0,0 +1,75 @@
+package index
+
+import (
+	"log"
+)
+
+// UpdateOptions tell Update how to index a workspace
+type UpdateOptions struct {
+	Sources Sources
+	// rule names of module directories, the full path if nil
+	Naming func(dir string) string
+	// previous cache of -incremental, nil re-indexes everything
+	Previous Previous
+	// goroutines parsing sources
+	Jobs int
+	// percent of modules indexed, all of them if 0
+	Sample int
+	// built-in providers, consulted before the registered ones. Their
+	// errors fail the update.
+	Providers []DependencyProvider
+}
+
+// Update indexes the source modules of a workspace, and the dependencies
+// of opts.Providers and of all registered providers. Source trees are
+// scanned while the providers run. A failing registered provider is logged,
+// and does not stop the update.
+func Update(workspace string, opts UpdateOptions) (Cache, error) {
+	naming := opts.Naming
+	if naming == nil {
+		naming = func(dir string) string {
+			return dir
+		}
+	}
+	sample := opts.Sample
+	if sample == 0 {
+		sample = 100
+	}
+	var deps []Dependency
+	var names map[string]string
+	scanned := make(chan bool)
+	go func() {
+		deps, names = FromSource(workspace, opts.Sources, naming,
+			opts.Previous, opts.Jobs)
+		close(scanned)
+	}()
+	var provided []Dependency
+	for _, p := range opts.Providers {
+		ds, err := p(workspace)
+		if err != nil {
+			<-scanned
+			return Cache{}, err
+		}
+		provided = append(provided, ds...)
+	}
+	ds, err := Provided(workspace)
+	if err != nil {
+		log.Printf("warning: registered provider failed: %v\n", err)
+	}
+	if len(ds) > 0 {
+		log.Printf("found %d dependencies of registered providers\n",
+			len(ds))
+	}
+	provided = append(provided, ds...)
+	<-scanned
+	if sample < 100 {
+		deps, names = sampleSources(deps, names, sample)
+		log.Printf("sampling %d%% of jars and modules\n", sample)
+	}
+	log.Printf("found %d source dependencies\n", len(deps))
+	return Cache{
+		Dependencies: append(deps, provided...),
+		Names:        names,
+		Sample:       sample,
+	}, nil
+}
@@ -0,0 +1,58 @@
+package index
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUpdate(t *testing.T) {
+	defer func(ps []DependencyProvider) { providers = ps }(providers)
+	providers = nil
+	ws := t.TempDir()
+	src := filepath.Join(ws, "core/src/main/java/core/A.java")
+	if err := os.MkdirAll(filepath.Dir(src), 0755); err != nil {
+		t.Fatal(err)
+	}
+	err := ioutil.WriteFile(src, []byte("package core;\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	builtin := func(workspace string) ([]Dependency, error) {
+		return []Dependency{{Name: "//external:junit", Kind: MavenJar}},
+			nil
+	}
+	RegisterProvider(func(workspace string) ([]Dependency, error) {
+		return []Dependency{{Name: "@nexus//:a", Kind: MavenJar}},
+			errors.New("partially offline")
+	})
+	c, err := Update(ws, UpdateOptions{Jobs: 1,
+		Providers: []DependencyProvider{builtin}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, d := range c.Dependencies {
+		got = append(got, d.Name)
+	}
+	want := RuleName(filepath.Join(ws, "core"))
+	if len(got) != 3 || got[0] != want || got[1] != "//external:junit" ||
+		got[2] != "@nexus//:a" {
+		t.Fatalf("want %s, junit and nexus but got %v\n", want, got)
+	}
+	if c.Names[want] != filepath.Join(ws, "core") || c.Sample != 100 {
+		t.Fatalf("want module names of a full sample but got %+v\n",
+			c)
+	}
+
+	failing := func(workspace string) ([]Dependency, error) {
+		return nil, errors.New("bazel failed")
+	}
+	_, err = Update(ws, UpdateOptions{Jobs: 1,
+		Providers: []DependencyProvider{failing}})
+	if err == nil {
+		t.Fatalf("want error of built-in provider but got nil\n")
+	}
+}
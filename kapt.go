@@ -0,0 +1,72 @@
+package main
+
+import (
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+)
+
+// simple names of classes generated by well known annotation processors
+var processors = map[string]*regexp.Regexp{
+	"autovalue": regexp.MustCompile(`^AutoValue_`),
+	"dagger": regexp.MustCompile(
+		`^Dagger[A-Z]|_Factory$|_MembersInjector$`),
+	"moshi": regexp.MustCompile(`JsonAdapter$`),
+	"room":  regexp.MustCompile(`_Impl$`),
+}
+
+// annotation processor generating a class, empty if unknown
+func generatedBy(class string) string {
+	simple := class[strings.LastIndex(class, ".")+1:]
+	var names []string
+	for n := range processors {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		if processors[n].MatchString(simple) {
+			return n
+		}
+	}
+	return ""
+}
+
+// parse processor=label pairs, such as dagger=//tools:dagger_kapt
+func parsePlugins(s string) map[string]string {
+	m := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(kv) != 2 {
+			if pair != "" {
+				log.Printf("ignoring plugin %q, want "+
+					"processor=label\n", pair)
+			}
+			continue
+		}
+		m[kv[0]] = kv[1]
+	}
+	return m
+}
+
+// add the kapt or ksp plugin of the processor generating a class to a Kotlin
+// rule, rules_kotlin runs both via the plugins attribute. plugins is nil for
+// rules other than Kotlin.
+func bdAddProcessor(rule string, class string,
+	plugins map[string]string) (edit.Edit, string, bool) {
+	p := generatedBy(class)
+	if p == "" {
+		return edit.Edit{}, "", false
+	}
+	label, ok := plugins[p]
+	if !ok && plugins != nil {
+		log.Printf("class %s is probably generated by %s, but there "+
+			"is no -kotlin-plugins entry for it\n", class, p)
+	}
+	if !ok {
+		return edit.Edit{}, p, false
+	}
+	return edit.Edit{Command: "add plugins " + label, Target: rule}, p, true
+}
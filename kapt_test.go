@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+)
+
+func TestGeneratedBy(t *testing.T) {
+	for class, want := range map[string]string{
+		"com.acme.DaggerAppComponent":     "dagger",
+		"com.acme.UserModule_Factory":     "dagger",
+		"com.acme.AutoValue_User":         "autovalue",
+		"com.acme.db.UserDao_Impl":        "room",
+		"com.acme.json.UserJsonAdapter":   "moshi",
+		"com.acme.User":                   "",
+		"com.acme.dagger.ComponentHolder": "",
+	} {
+		if got := generatedBy(class); want != got {
+			t.Fatalf("%s: want %q but got %q\n", class, want, got)
+		}
+	}
+}
+
+func TestAddProcessor(t *testing.T) {
+	plugins := parsePlugins("dagger=//tools:dagger_kapt, " +
+		"room=//tools:room_ksp")
+	e, p, ok := bdAddProcessor("//app:app", "com.acme.UserDao_Impl",
+		plugins)
+	want := edit.Edit{Command: "add plugins //tools:room_ksp", Target: "//app:app"}
+	if !ok || p != "room" || want != e {
+		t.Fatalf("want %s but got %s\n", want, e)
+	}
+	if _, _, ok := bdAddProcessor("//app:app", "com.acme.AutoValue_User",
+		plugins); ok {
+		t.Fatalf("want no plugin for unconfigured processor\n")
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+)
+
+// attribute rule kinds and macros take Java deps in, "" if they take none.
+// Kinds not listed take deps, as java_library, kt_jvm_library,
+// android_library, and scala_library do.
+var depsAttributes = map[string]string{
+	// deps of proto rules are proto_library rules
+	"java_proto_library":      "",
+	"java_lite_proto_library": "",
+	"java_grpc_library":       "",
+	"proto_library":           "",
+	"genrule":                 "",
+	"filegroup":               "",
+	"alias":                   "",
+	"test_suite":              "",
+	// instrumentation tests get their code from test_app
+	"android_instrumentation_test": "",
+}
+
+// parse comma separated kind=attribute pairs of -deps-attributes, such as
+// my_service_library=libs,generated_api=. An empty attribute marks kinds
+// taking no Java deps.
+func parseDepsAttributes(s string) (map[string]string, error) {
+	m := make(map[string]string)
+	for k, v := range depsAttributes {
+		m[k] = v
+	}
+	for _, pair := range strings.Split(s, ",") {
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || !edit.REIdentifier.MatchString(kv[0]) ||
+			(kv[1] != "" && !edit.REIdentifier.MatchString(kv[1])) {
+			return nil, fmt.Errorf("want kind=attribute but got %q",
+				pair)
+		}
+		m[kv[0]] = kv[1]
+	}
+	return m, nil
+}
+
+var (
+	REGeneratorName     = regexp.MustCompile(`generator_name = "([^"]+)"`)
+	REGeneratorFunction = regexp.MustCompile(
+		`generator_function = "([^"]+)"`)
+)
+
+// DepsTarget is where the deps of a rule are declared: the rule itself, or
+// the call of the macro generating it
+type DepsTarget struct {
+	Label     string
+	Attribute string
+}
+
+// deps target of a rule of a kind, according to its definition as printed
+// by bazel query --output=build. false if the rule takes no Java deps.
+func depsTarget(rule string, kind string, build string,
+	attributes map[string]string) (DepsTarget, bool) {
+	t := DepsTarget{rule, "deps"}
+	if a, ok := attributes[kind]; ok {
+		t.Attribute = a
+	}
+	// buildozer edits the macro call, not the rule it generates
+	if m := REGeneratorName.FindStringSubmatch(build); len(m) > 0 &&
+		!strings.HasSuffix(rule, ":"+m[1]) && rule != m[1] {
+		if i := strings.LastIndex(rule, ":"); i >= 0 {
+			t.Label = rule[:i+1] + m[1]
+		} else {
+			t.Label = m[1]
+		}
+	}
+	if f := REGeneratorFunction.FindStringSubmatch(build); len(f) > 0 {
+		if a, ok := attributes[f[1]]; ok {
+			t.Attribute = a
+		}
+	}
+	return t, t.Attribute != ""
+}
+
+// edits adding deps to rule, moved to its deps target. Without a target,
+// suggestions adding deps are of no use.
+func retarget(edits []edit.Edit, rule string, t DepsTarget) []edit.Edit {
+	if t.Label == rule && t.Attribute == "deps" {
+		return edits
+	}
+	var es []edit.Edit
+	for _, e := range edits {
+		if e.Target == rule && strings.HasPrefix(e.Command, "add deps ") {
+			e = edit.Edit{Command: "add " + t.Attribute + " " +
+				strings.TrimPrefix(e.Command, "add deps "),
+				Target: t.Label}
+		}
+		es = append(es, e)
+	}
+	return es
+}
+
+// addsDeps reports whether edits add deps to a rule
+func addsDeps(edits []edit.Edit, rule string) bool {
+	for _, e := range edits {
+		if e.Target == rule && strings.HasPrefix(e.Command, "add deps ") {
+			return true
+		}
+	}
+	return false
+}
+
+// log why deps cannot be added to a rule
+func logNoDeps(rule string, kind string) {
+	log.Printf("%s is a %s, which takes no Java deps: fix the rule it "+
+		"is generated from, or map its kind with -deps-attributes\n",
+		rule, kind)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+	"github.com/jhinrichsen/bazel-kaizen/parser"
+)
+
+func TestDepsTarget(t *testing.T) {
+	attributes, err := parseDepsAttributes("service_library=libs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	macro := `# /ws/ui/web/BUILD:3:16
+java_library(
+  name = "web_lib",
+  generator_name = "web",
+  generator_function = "service_library",
+  generator_location = "ui/web/BUILD:3:16",
+  srcs = ["//ui/web:Fx.java"],
+)
+`
+	for _, tt := range []struct {
+		rule, kind, build string
+		want              DepsTarget
+		ok                bool
+	}{
+		{"//ui/web:web", "kt_jvm_library", "",
+			DepsTarget{"//ui/web:web", "deps"}, true},
+		{"//ui/web:web", "android_library", "",
+			DepsTarget{"//ui/web:web", "deps"}, true},
+		{"//ui/web:web_java_proto", "java_proto_library", "",
+			DepsTarget{"//ui/web:web_java_proto", ""}, false},
+		{"//ui/web:web_lib", "java_library", macro,
+			DepsTarget{"//ui/web:web", "libs"}, true},
+	} {
+		got, ok := depsTarget(tt.rule, tt.kind, tt.build, attributes)
+		if tt.want != got || tt.ok != ok {
+			t.Fatalf("%s: want %+v but got %+v\n", tt.rule, tt.want,
+				got)
+		}
+	}
+	edits := retarget([]edit.Edit{edit.AddDeps("//ui/web:web_lib", "//a")},
+		"//ui/web:web_lib", DepsTarget{"//ui/web:web", "libs"})
+	want := edit.Edit{Command: "add libs //a", Target: "//ui/web:web"}
+	if len(edits) != 1 || want != edits[0] {
+		t.Fatalf("want %+v but got %+v\n", want, edits)
+	}
+	if _, err := parseDepsAttributes("service_library"); err == nil {
+		t.Fatalf("want error for missing attribute\n")
+	}
+}
+
+// bazel's strict deps commands add to the rule a macro generates
+func TestHealForwardsOwnDeps(t *testing.T) {
+	fakeTools(t, `case "$*" in
+*label_kind*) echo "java_library rule //ui/web:web_lib";;
+*//ui/web:web_lib*) cat <<EOF
+java_library(
+  name = "web_lib",
+  generator_name = "web",
+  generator_function = "service_library",
+)
+EOF
+;;
+*//api:api_lib*) cat <<EOF
+java_library(
+  name = "api_lib",
+  generator_name = "api",
+  generator_function = "generated_api",
+)
+EOF
+;;
+esac
+exit 0
+`, "exit 0\n")
+	attributes, err := parseDepsAttributes(
+		"service_library=extra_deps,generated_api=")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := Healer{Workspace: t.TempDir(), DepsAttributes: attributes}
+	ss, err := h.heal(parser.BuildProblems{Suggested: []edit.Edit{
+		edit.AddDeps("//ui/web:web_lib", "//a"),
+		edit.AddDeps("//app:app", "//b"),
+		// a macro taking no deps
+		edit.AddDeps("//api:api_lib", "//c"),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []edit.Edit
+	for _, s := range ss {
+		got = append(got, s.Edits...)
+	}
+	want := []edit.Edit{
+		{Command: "add extra_deps //a", Target: "//ui/web:web"},
+		edit.AddDeps("//app:app", "//b"),
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("want %+v but got %+v\n", want, got)
+	}
+}
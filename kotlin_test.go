@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+func TestFromSourceKotlin(t *testing.T) {
+	ws := t.TempDir()
+	fixtureFiles(t, ws, map[string]string{
+		"ui/web/src/main/kotlin/Fx.kt":            "package ui.web\n\nclass Fx\n",
+		"ui/web/src/main/java/ui/web/Legacy.java": "package ui.web;\n",
+		"core/src/main/java/core/A.java":          "package core;\n",
+	})
+	deps, _ := index.FromSource(ws, index.Sources{}, namings["segment"],
+		nil, 1)
+	kinds := make(map[string]index.Kind)
+	for _, d := range deps {
+		kinds[d.Name] = d.Kind
+		if d.Name == "web" && (!d.Provides(index.Class, "ui.web.Fx") ||
+			!d.Provides(index.Class, "ui.web.Legacy")) {
+			t.Fatalf("want Kotlin and Java classes but got %+v\n",
+				d.Resources)
+		}
+	}
+	if kinds["web"] != index.KotlinSource || kinds["core"] != index.Source {
+		t.Fatalf("want web Kotlin and core Java but got %v\n", kinds)
+	}
+	edits := edit.Valid(edit.NewKotlinLibrary(index.Dependency{Name: "web",
+		ExternalReference: "ui/web/src/main/"}))
+	if len(edits) != 3 {
+		t.Fatalf("want 3 valid commands but got %+v\n", edits)
+	}
+}
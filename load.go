@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+	"github.com/jhinrichsen/bazel-kaizen/index"
+	"github.com/jhinrichsen/bazel-kaizen/parser"
+)
+
+// labels of the .bzl files of a workspace, outside of bazel's output trees
+func bzlFiles(workspace string) []string {
+	var labels []string
+	for _, f := range index.Scan(workspace, ".bzl") {
+		rel, err := filepath.Rel(workspace, f)
+		if err != nil || strings.HasPrefix(rel, "bazel-") {
+			continue
+		}
+		pkg, file, ok := runfileOwner(workspace, filepath.ToSlash(rel))
+		if !ok {
+			log.Printf("%s belongs to no package\n", rel)
+			continue
+		}
+		labels = append(labels, "//"+pkg+":"+file)
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// .bzl files of the same basename as a label, other than the label itself
+func loadCandidates(label string, bzls []string) []string {
+	base := label[strings.LastIndexAny(label, ":/")+1:]
+	var cs []string
+	for _, b := range bzls {
+		if b != label && b[strings.LastIndexAny(b, ":/")+1:] == base {
+			cs = append(cs, b)
+		}
+	}
+	return cs
+}
+
+// suggestions correcting the label of a .bzl file a package cannot load,
+// if a single .bzl file of the same basename exists. buildozer edits BUILD
+// files only, loads of .bzl files are reported.
+func healLoad(l parser.Load, bzls []string) []Suggestion {
+	cs := loadCandidates(l.Label, bzls)
+	switch {
+	case len(cs) == 0:
+		log.Printf("package %s cannot load %s, and there is no .bzl "+
+			"file of that name\n", l.Package, l.Label)
+		return nil
+	case len(cs) > 1:
+		log.Printf("package %s cannot load %s, ambiguous: %s\n",
+			l.Package, l.Label, strings.Join(cs, ", "))
+		return nil
+	case l.File != "":
+		log.Printf("%s cannot load %s, use %s\n", l.File, l.Label,
+			cs[0])
+		return nil
+	}
+	target := "//" + l.Package + ":__pkg__"
+	return []Suggestion{{Rule: target, Action: FixLoad, Dep: cs[0],
+		Provider: "bzl",
+		Reason: fmt.Sprintf("package %s cannot load %s, %s exists",
+			l.Package, l.Label, cs[0]),
+		Confidence: index.Medium, Evidence: []string{l.Line},
+		Edits: []edit.Edit{{Command: "substitute_load ^" + l.Label +
+			"$ " + cs[0], Target: target}}}}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+	"github.com/jhinrichsen/bazel-kaizen/parser"
+)
+
+func TestProblemsLoad(t *testing.T) {
+	lines := `ERROR: error loading package 'app': cannot load '//tools:defs.bzl': no such file
+ERROR: error loading package 'ui/web': Extension file not found. Unable to load file '//tools:defs.bzl': file doesn't exist
+ERROR: error loading package 'lib': in /ws/lib/macros.bzl: cannot load '//tools:defs.bzl': no such file
+ERROR: Skipping '//app:app': error loading package 'app': cannot load '//tools:defs.bzl': no such file
+`
+	ps := parser.Problems(*bufio.NewScanner(strings.NewReader(lines)))
+	var got [][3]string
+	for _, l := range ps.Unloadable {
+		got = append(got, [3]string{l.Label, l.Package, l.File})
+	}
+	want := [][3]string{
+		{"//tools:defs.bzl", "app", ""},
+		{"//tools:defs.bzl", "ui/web", ""},
+		{"//tools:defs.bzl", "lib", "/ws/lib/macros.bzl"},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("want %v but got %v\n", want, got)
+	}
+}
+
+func TestHealLoad(t *testing.T) {
+	ws := t.TempDir()
+	fixtureFiles(t, ws, map[string]string{
+		"build/BUILD":                "",
+		"build/tools/defs.bzl":       "",
+		"build/tools/java.bzl":       "",
+		"other/BUILD":                "",
+		"other/java.bzl":             "",
+		"bazel-out/tools/defs.bzl":   "",
+		"bazel-out/tools/BUILD":      "",
+		"third_party/BUILD.bazel":    "",
+		"third_party/maven/deps.bzl": "",
+	})
+	bzls := bzlFiles(ws)
+	want := []string{"//build:tools/defs.bzl", "//build:tools/java.bzl",
+		"//other:java.bzl", "//third_party:maven/deps.bzl"}
+	if !reflect.DeepEqual(want, bzls) {
+		t.Fatalf("want %v but got %v\n", want, bzls)
+	}
+	for _, tt := range []struct {
+		load parser.Load
+		want []edit.Edit
+	}{
+		{parser.Load{Label: "//tools:defs.bzl", Package: "app"},
+			[]edit.Edit{{Command: "substitute_load ^//tools:defs.bzl$ " +
+				"//build:tools/defs.bzl", Target: "//app:__pkg__"}}},
+		// ambiguous
+		{parser.Load{Label: "//tools:java.bzl", Package: "app"}, nil},
+		// loaded by a .bzl file
+		{parser.Load{Label: "//tools:defs.bzl", Package: "app",
+			File: "/ws/lib/macros.bzl"}, nil},
+		{parser.Load{Label: "//tools:gone.bzl", Package: "app"}, nil},
+	} {
+		var got []edit.Edit
+		for _, s := range healLoad(tt.load, bzls) {
+			got = append(got, edit.Valid(s.Edits)...)
+		}
+		if !reflect.DeepEqual(tt.want, got) {
+			t.Fatalf("%+v: want %+v but got %+v\n", tt.load, tt.want,
+				got)
+		}
+	}
+}
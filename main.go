@@ -1,528 +1,732 @@
 package main
 
 import (
-	"archive/zip"
 	"bufio"
 	"bytes"
-	"encoding/gob"
 	"flag"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"log"
 	"os"
-	"os/exec"
-	"path/filepath"
-	"regexp"
+	"runtime"
 	"strings"
-)
-
-type BuildProblems struct {
-	BazelRule    string
-	MissingClass []JavaClass
-}
+	"text/template"
+	"time"
 
-type JavaClass struct {
-	Module string // Maven: relative module path
-	Layout string // Maven: src/main/java
-	Name   string
-}
-
-func (a JavaClass) Package() string {
-	return StripLast(a.Name)
-}
-
-type Dependency struct {
-	Name              string
-	ExternalReference string
-	Resources         []string
-}
+	"github.com/jhinrichsen/bazel-kaizen/bazel"
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+	"github.com/jhinrichsen/bazel-kaizen/index"
+	"github.com/jhinrichsen/bazel-kaizen/parser"
+)
 
-func die(err error) {
-	if err != nil {
-		log.Fatal(err)
-	}
+// piped reports whether stdin carries a build log, from a pipe or a file
+// rather than a terminal
+func piped() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && (info.Mode()&os.ModeNamedPipe != 0 ||
+		info.Mode().IsRegular())
 }
 
-// stripLast removes the last '.' and the following segment
-
-func StripLast(s string) string {
-	parts := strings.Split(s, ".")
-	// strip class name
-	parts = parts[0 : len(parts)-1]
-	return strings.Join(parts, ".")
+func main() {
+	os.Exit(run(os.Args[1:]))
 }
 
-func problems(scanner bufio.Scanner) BuildProblems {
-	const (
-		Building  = "Building"
-		Compiling = "Compiling Java headers"
-		NoPackage = "package (.*) does not exist"
-		NoSymbol  = "error: cannot find symbol"
-	)
+// run a command line, returning its exit status: 0 on success, 1 on
+// failure, and 2 for usage errors
+func run(args []string) int {
+	flags := flag.NewFlagSet("bazel-kaizen", flag.ContinueOnError)
 	var (
-		REBuilding  = regexp.MustCompile(Building + " lib(.*?)\\.jar ")
-		RECompiling = regexp.MustCompile(Compiling +
-			" lib(.*?)-hjar\\.jar ")
-		REImport       = regexp.MustCompile("import (.*);")
-		REImportStatic = regexp.MustCompile("import static (.*);")
+		update = flags.Bool("update", false,
+			"update internal class cache and exit")
+		incremental = flags.Bool("incremental", false,
+			"-update re-indexes only jars and source modules "+
+				"changed since the previous cache")
+		jobs = flags.Int("jobs", runtime.NumCPU(),
+			"-update reads jars and parses sources on this many "+
+				"goroutines")
+		sample = flags.Int("sample", 100,
+			"-update indexes only this percentage of jars and "+
+				"modules, for a quick check on large workspaces")
+		cachefile = flags.String("cachefile", ".healdb",
+			"name of cache file")
+		store = flags.Bool("store", false,
+			"keep the cache as a directory, looked up class by "+
+				"class and updated incrementally, for huge "+
+				"workspaces")
+		excludeClassifiers = flags.String("exclude-classifiers",
+			"sources,javadoc,tests",
+			"-update never indexes jars of these classifiers")
+		indexTests = flags.Bool("index-tests", false,
+			"-update also indexes tests classifier artifacts of "+
+				"rules_jvm_external, for test rules only")
+		preferClassifiers = flags.String("prefer-classifiers", "",
+			"-update indexes jars of these classifiers, in order, "+
+				"rather than the plain jar, such as shaded")
+		workspace  = flags.String("workspace", ".", "bazel workspace")
+		configfile = flags.String("config", "",
+			"settings by flag name, "+configFile+" of the "+
+				"workspace if empty")
+		sourceLayouts = flags.String("source-layouts", "src/main/java",
+			"-update indexes Java sources below these roots "+
+				"within modules")
+		ignorePackages = flags.String("ignore-packages", "",
+			"-update never scans these workspace directories "+
+				"for sources, such as third_party/legacy")
+		preferRepositories = flags.String("prefer-repos", "",
+			"external repositories preferred for classes "+
+				"several dependencies provide, in order")
+		defaultAttributes = flags.String("default-attributes", "",
+			"attributes of generated rules, such as "+
+				"visibility=//visibility:public")
+		conflicting = flags.Bool("conflicts", false,
+			"suggest exclusions for external dependencies "+
+				"carrying the same packages and exit")
+		apply = flags.Bool("apply", false,
+			"run buildozer commands instead of printing them")
+		format = flags.String("format", "buildozer",
+			"heal output: buildozer commands, or json documents "+
+				"per suggestion")
+		allImports = flags.Bool("all-imports", false,
+			"resolve all imports of failing source files at once, "+
+				"not just those javac reported")
+		removeSuperfluous = flags.Bool("remove-superfluous", false,
+			"analyze: also print commands removing deps nothing "+
+				"imports")
+		jdeps = flags.Bool("jdeps", false,
+			"prune: judge deps by the .jdeps files of the last "+
+				"build instead of imports")
+		strictJavacopt = flags.String("strict-javacopt",
+			"--strict_java_deps=ERROR",
+			"strict: javacopt marking a rule as migrated to strict deps")
+		strictPackages = flags.Int("strict-packages", 1,
+			"strict: packages to migrate per run, 0 for all")
+		providerNames = flags.String("providers",
+			strings.Join(defaultProviders, ","),
+			"resolution chain in order: srcs, genrule, codegen, "+
+				"index, and exec:<program> printing labels for "+
+				"a class name")
+		loop = flags.Bool("loop", false,
+			"heal: build, apply fixes, and repeat until the "+
+				"target builds or no progress is made")
+		visibility = flags.String("visibility", "package",
+			"heal visibility errors by making deps visible to "+
+				"the package, subpackages, or public, by adding "+
+				"to a package_group with group=//pkg:name, "+
+				"or none")
+		remember = flags.Bool("remember", true,
+			"remember fixes -apply applied in <cachefile>.fixes, "+
+				"and reuse them when a class is missing again")
+		stats = flags.Bool("stats", true,
+			"append anonymous usage counts of each run to "+
+				"<cachefile>.stats, which never leaves the machine")
+		journal = flags.String("journal", "",
+			"append diff of BUILD files changed by -apply to file")
+		strategy = flags.String("naming", "path",
+			"name generated rules by module path, segment, "+
+				"artifactId, or template")
+		namingTemplate = flags.String("naming-template", "{{.Segment}}",
+			"text/template for -naming template, fields are "+
+				"Dir, Segment, and ArtifactID")
+		learning = flags.Bool("learn", false,
+			"follow naming and label conventions of existing "+
+				"BUILD files")
+		minConfidence = flags.String("min-confidence", "low",
+			"suggest only fixes of at least low, medium, or high "+
+				"confidence")
+		reportFile = flags.String("report-file", "",
+			"write report to file instead of stderr")
+		logfile = flags.String("log", "",
+			"read the build log from file instead of stdin")
+		buildTarget = flags.String("target", "",
+			"heal this target, building it, if no log is given")
+		interactive = flags.Bool("interactive", false,
+			"review suggestions on the terminal before applying or "+
+				"printing them")
+		queryOutputBase = flags.String("query-output-base", "",
+			"run queries on a bazel server of their own in this "+
+				"output_base, kept warm while building")
+		interval = flags.Duration("interval", 2*time.Second,
+			"watch: how often to look for changed files")
+		stream = flags.Bool("stream", false,
+			"pass the build log through to stderr while reading it")
+		bep = flags.String("bep", "",
+			"read build problems from a --build_event_json_file "+
+				"instead of a build log")
+		generated = flags.Bool("bazel-bin", false,
+			"also resolve against jars of the current build")
+		kotlinPlugins = flags.String("kotlin-plugins", "",
+			"kapt/ksp plugins of Kotlin rules by processor, such "+
+				"as dagger=//tools:dagger_kapt,room=//tools:room_ksp")
+		codegens = flags.String("codegen", "",
+			"rules generating Java packages by package prefix, "+
+				"such as com.company.rest=//rest:"+
+				"{{.Segment}}_client, fields are Package, Name, "+
+				"and Segment")
+		bazelrc = flags.String("bazelrc", "",
+			"bazelrc file passed to every bazel invocation, in "+
+				"addition to the workspace's .bazelrc")
+		configs = flags.String("bazel-config", "",
+			"comma separated --config names for every bazel "+
+				"invocation, such as ci,remote")
+		noNetwork = flags.Bool("no-network", false,
+			"never let bazel fetch external repositories, use "+
+				"local data only")
+		online = flags.Bool("online", false,
+			"search Maven Central for classes no provider knows")
+		depsAttributeNames = flags.String("deps-attributes", "",
+			"attribute rule kinds or macros take Java deps in, "+
+				"such as my_service=libs, empty for none")
+		packageFilters = flags.String("package-filter", "",
+			"packages dependencies are suggested for, such as "+
+				"@maven//:fat=com.fat,@maven//:fat=!com.fat."+
+				"shaded, ! excludes")
+		top = flags.Int("top", 10,
+			"imports: most imported external packages to report "+
+				"per module, 0 for all")
+		discouragedDeps = flags.String("discouraged", "",
+			"imports: dependencies modules should not import "+
+				"from, such as @maven//:commons_lang,@legacy//*")
+		wrappers = flags.String("third-party", "",
+			"consume external artifacts via wrapper libraries in "+
+				"this package template, such as "+
+				"third_party/java/{{.Name}}")
 	)
-	var problems BuildProblems
-	// build scanner only knows about missing class names, no module etc.
-	add := func(classname string) {
-		problems.MissingClass = append(problems.MissingClass,
-			JavaClass{Name: classname})
-	}
-	for scanner.Scan() {
-		var line = scanner.Text()
-		// Easiest: bazels own suggestions
-		if strings.HasPrefix(line, "buildozer ") {
-			fmt.Println(line)
-			// bazel log will not contain anything else
-			os.Exit(0)
-		} else if strings.Contains(line, Building) {
-			matches := REBuilding.FindStringSubmatch(line)
-			if len(matches) == 0 {
-				log.Fatalf("expected rule but got %s\n",
-					line)
-			}
-			pkg := matches[1]
-			log.Printf("using package name %s\n", pkg)
-			problems.BazelRule = pkg
-		} else if strings.Contains(line, Compiling) {
-			matches := RECompiling.FindStringSubmatch(line)
-			if len(matches) == 0 {
-				log.Fatalf("expected rule but got %s\n",
-					line)
-			}
-			pkg := matches[1]
-			log.Printf("using package name %s\n", pkg)
-			problems.BazelRule = pkg
-		} else if b, _ := regexp.MatchString(NoPackage, line); b {
-			// Parse next line for class in package
-			scanner.Scan()
-			line = scanner.Text()
-			matches := REImportStatic.FindStringSubmatch(line)
-			if len(matches) > 0 {
-				// Convert Java member to class
-				add(StripLast(matches[1]))
-			}
-			matches = REImport.FindStringSubmatch(line)
-			if len(matches) > 0 {
-				add(matches[1])
-			}
-		} else if strings.Contains(line, NoSymbol) {
-			scanner.Scan()
-			line = scanner.Text()
-			matches := REImport.FindStringSubmatch(line)
-			if len(matches) > 0 {
-				add(matches[1])
-			}
-		}
+	if err := flags.Parse(args); err == flag.ErrHelp {
+		return 0
+	} else if err != nil {
+		return 2
 	}
-	return problems
-}
-
-// expect and return exactly one *.jar file
-func oneJarFrom(dir string) string {
-	fis, err := ioutil.ReadDir(dir)
-	die(err)
-	var jars []string
-	for _, fi := range fis {
-		// filter 'sources' classifier
-		if strings.HasSuffix(fi.Name(), "-sources.jar") {
-			continue
+	if err := loadConfig(flags, *configfile, *workspace); err != nil {
+		log.Println(err)
+		return 2
+	}
+	if *format != "buildozer" && *format != "json" {
+		log.Printf("unknown -format %q, want buildozer or json\n",
+			*format)
+		return 2
+	}
+	if *online && *noNetwork {
+		log.Printf("-online and -no-network exclude each other\n")
+		return 2
+	}
+	defer log.SetOutput(log.Writer())
+	if *reportFile != "" {
+		f, err := os.Create(*reportFile)
+		if err != nil {
+			log.Println(err)
+			return 1
 		}
-		if strings.HasSuffix(fi.Name(), ".jar") {
-			jars = append(jars, fi.Name())
+		if err := own(*reportFile); err != nil {
+			log.Println(err)
+			return 1
 		}
+		defer f.Close()
+		log.SetOutput(f)
 	}
-	if len(jars) != 1 {
-		log.Fatalf("want exactly one jar file in %s but got %+v\n",
-			dir, jars)
+	bazel.Offline = *noNetwork
+	generators, err := parseCodegens(*codegens)
+	if err != nil {
+		log.Println(err)
+		return 2
 	}
-	return filepath.Join(dir, jars[0])
-}
-
-func canRead(dir string) bool {
-	_, err := os.Stat(dir)
-	// no need for os.IsNotExist() dance as all we care is if it's there
+	filters, err := parsePackageFilters(*packageFilters)
 	if err != nil {
-		return false
+		log.Println(err)
+		return 2
 	}
-	return true
-}
-
-func content(jar string) []string {
-	r, err := zip.OpenReader(jar)
-	die(err)
-	defer r.Close()
-	var files []string
-	for _, f := range r.File {
-		// Files in jars are / separated, and end in .class
-		if strings.HasSuffix(f.Name, ".class") {
-			clazz := strings.TrimSuffix(
-				strings.Replace(f.Name, "/", ".", -1),
-				".class")
-			files = append(files, clazz)
-		}
-	}
-	return files
-}
-
-// list all classes in external dependencies
-func externalDependencyProvider(workspace string) []Dependency {
-	var deps []Dependency
-	base := bzOutputBase(workspace)
-	for _, dep := range bzQueryExternalDependencies(workspace) {
-		log.Printf("processing dependency %s\n", dep)
-		dir := filepath.Join(
-			base,
-			"external",
-			strings.TrimPrefix(dep, "//external:"),
-			"jar")
-		// Some external dependencies may be declared, but not
-		// used
-		if canRead(dir) {
-			jar := oneJarFrom(dir)
-			fs := content(jar)
-			deps = append(deps, Dependency{dep, jar, fs})
-		} else {
-			log.Printf("skip non-existent dependency %v\n", dep)
-		}
+	attributes, err := parseDepsAttributes(*depsAttributeNames)
+	if err != nil {
+		log.Println(err)
+		return 2
 	}
-	return deps
-}
-
-func bzOutputBase(workdir string) string {
-	prms := []string{"bazel", "info", "output_base"}
-	cmd := exec.Command(prms[0], prms[1:]...)
-	cmd.Dir = workdir
-	log.Printf("executing %v in %s\n", prms, cmd.Dir)
-	buf, err := cmd.CombinedOutput()
+	defaults, err := parseAttributes(*defaultAttributes)
 	if err != nil {
-		log.Printf("error: %v\n", err)
-		log.Printf("combined output: %s\n", string(buf))
-		log.Fatal(err)
+		log.Println(err)
+		return 2
 	}
-	// expect exactly one line, but just to be on the safe side
-	var lines []string
-	scanner := bufio.NewScanner(bytes.NewReader(buf))
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+	if err := parseVisibility(*visibility); err != nil {
+		log.Println(err)
+		return 2
 	}
-	if len(lines) != 1 {
-		log.Fatalf("expected exactly one line but got %+v\n", lines)
+	providers, err := parseProviders(*providerNames)
+	if err != nil {
+		log.Println(err)
+		return 2
 	}
-	return lines[0]
-}
-
-// list of all external dependencies
-func bzQueryExternalDependencies(workdir string) (deps []string) {
-	// might trigger dependency resolution
-	prms := []string{
-		"bazel",
-		"query",
-		"kind(maven_jar, //external:all)"}
-	cmd := exec.Command(prms[0], prms[1:]...)
-	cmd.Dir = workdir
-	log.Printf("executing %v in %s\n", prms, cmd.Dir)
-	buf, err := cmd.CombinedOutput()
+	if *online && !contains(providers, "central") {
+		providers = append(providers, "central")
+	}
+	if *bazelrc != "" {
+		bazel.Startup = []string{"--bazelrc=" + *bazelrc}
+	}
+	bazel.QueryOutputBase = *queryOutputBase
+	if *configs != "" {
+		bazel.Configs = strings.Split(*configs, ",")
+	}
+	threshold, err := index.ParseConfidence(*minConfidence)
 	if err != nil {
-		log.Printf("error: %v\n", err)
-		log.Printf("combined output: %s\n", string(buf))
-		log.Fatal(err)
+		log.Println(err)
+		return 2
 	}
-	scanner := bufio.NewScanner(bytes.NewReader(buf))
-	for scanner.Scan() {
-		line := scanner.Text()
-		deps = append(deps, line)
+	var wrapper *template.Template
+	if *wrappers != "" {
+		wrapper, err = parseWrapper(*wrappers)
+		if err != nil {
+			log.Printf("bad -third-party template: %v\n", err)
+			return 2
+		}
 	}
-	return
-}
-
-func bzRuleExists(rule string, workdir string) bool {
-	prms := []string{
-		"bazel",
-		"query",
-		rule,
-	}
-	cmd := exec.Command(prms[0], prms[1:]...)
-	cmd.Dir = workdir
-	log.Printf("executing %v in %s\n", prms, cmd.Dir)
-	err := cmd.Run()
+	var conventions Conventions
+	if *learning {
+		conventions = learn(*workspace)
+		log.Printf("using conventions %+v\n", conventions)
+		explicit := false
+		flags.Visit(func(f *flag.Flag) {
+			explicit = explicit || f.Name == "naming"
+		})
+		if !explicit && conventions.Naming != "" {
+			*strategy = conventions.Naming
+		}
+	}
+	ruleNaming, err := naming(*strategy, *namingTemplate)
 	if err != nil {
-		if ee, ok := err.(*exec.ExitError); ok {
-			rc := ee.Sys().(interface {
-				ExitStatus() int
-			}).ExitStatus()
-			if rc == 7 {
-				// Not found
-				return false
-			} else {
-				log.Fatal(err)
+		log.Printf("bad -naming: %v\n", err)
+		return 2
+	}
+	if *update {
+		if *sample < 1 || *sample > 100 {
+			log.Printf("-sample %d out of range 1..100\n", *sample)
+			return 2
+		}
+		if *jobs < 1 {
+			log.Printf("-jobs %d, want at least 1\n", *jobs)
+			return 2
+		}
+		var prev index.Previous
+		if *incremental {
+			var err error
+			prev, err = index.ReadPrevious(*cachefile, *store)
+			if err != nil {
+				log.Println(err)
+				return 1
+			}
+		}
+		cs := parseClassifiers(*excludeClassifiers, *preferClassifiers)
+		external := func(ws string) ([]index.Dependency, error) {
+			ds, unreadable, err := externalDependencyProvider(ws,
+				*sample, cs, prev, *jobs)
+			log.Printf("found %d external dependencies\n", len(ds))
+			if len(unreadable) > 0 {
+				log.Printf("skipped %d unreadable jars, their "+
+					"classes will not resolve:\n",
+					len(unreadable))
+				for _, jar := range unreadable {
+					log.Printf("\t%s\n", jar)
+				}
 			}
+			return ds, err
+		}
+		rje := func(ws string) ([]index.Dependency, error) {
+			ds, err := mavenInstallDependencies(ws, *sample,
+				*indexTests, prev, *jobs)
+			log.Printf("found %d rules_jvm_external artifacts\n",
+				len(ds))
+			return ds, err
 		}
+		c, err := index.Update(*workspace, index.UpdateOptions{
+			Sources: index.Sources{
+				Layouts: split(*sourceLayouts),
+				Ignore:  split(*ignorePackages),
+			},
+			Naming:    ruleNaming,
+			Previous:  prev,
+			Jobs:      *jobs,
+			Sample:    *sample,
+			Providers: []index.DependencyProvider{external, rje},
+		})
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		if *store {
+			err = updateStore(*cachefile, c)
+		} else {
+			err = index.UpdateCache(*cachefile, c)
+			if err == nil {
+				err = own(*cachefile)
+			}
+		}
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		// we cannot run bazel build and these internal bazel commands
+		// in parallel, so we're done here
+		return 0
 	}
-	return true
-}
-
-// recursively scan dir for files matching extension
-func scan(dir string, extension string) []string {
-	log.Printf("recursively scanning %s for %s files\n", dir, extension)
-	var files []string
-	// filepath.Glob() is not recursive
-	f := func(path string, info os.FileInfo, err error) error {
-		if strings.HasSuffix(path, extension) {
-			files = append(files, path)
-		}
-		return nil
-	}
-	filepath.Walk(dir, f)
-	log.Printf("found %d files\n", len(files))
-	return files
-}
-
-// convert a module directory into a rule name
-func name(dir string) string {
-	// keep a 1:1 relationship between module locations and names
-	return strings.Replace(dir, "/", "_", -1)
-}
-
-// convert source files from the same source folder
-// into single dependencies
-// Name is the derived/ suggested rule name
-// external reference is the source path into the module, such as
-// ui/web/src/main/java
-func fromSource(dir string) []Dependency {
-	const sep = "/src/main/java/"
-	files := scan(dir, ".java")
-
-	// split into module and class name
-	var RESrcMainJava = regexp.MustCompile("(.*)" + sep + "(.*)")
-
-	// map of source directory and contained source files
-	modules := make(map[string][]string)
-	for _, f := range files {
-		matches := RESrcMainJava.FindStringSubmatch(f)
-		if len(matches) == 3 {
-			srcdir := matches[1]
-			file := matches[2]
-			clazz := strings.TrimSuffix(
-				strings.Replace(file, "/", ".", -1),
-				".java")
-			modules[srcdir] = append(modules[srcdir], clazz)
+	var deps []index.Dependency
+	var st *index.Store
+	if *store {
+		st, err = index.OpenStore(*cachefile)
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+	}
+	// healing looks up the store class by class, anything else needs
+	// all dependencies
+	if st == nil || *conflicting ||
+		(flags.Arg(0) != "" && flags.Arg(0) != "heal" &&
+			flags.Arg(0) != "watch") {
+		var cache index.Cache
+		if st != nil {
+			cache, err = st.Cache()
 		} else {
-			log.Printf("skip %s, missing %s?\n", f, sep)
+			cache, err = index.ReadCache(*cachefile)
 		}
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		deps = cache.Dependencies
+		log.Printf("cache contains %d dependencies (%s)\n", len(deps),
+			index.CountKinds(deps))
+		st = nil
 	}
-
-	// Convert into dependencies
-	var deps []Dependency
-	for k, v := range modules {
-		deps = append(deps, Dependency{
-			Name:              name(k),
-			ExternalReference: k + sep,
-			Resources:         v,
-		})
+	if *generated {
+		deps = append(deps, generatedDependencies(*workspace)...)
+	}
+	if *conflicting {
+		cs := conflicts(deps)
+		log.Printf("found %d conflicting dependencies\n", len(cs))
+		for _, c := range cs {
+			for _, line := range exclusion(c) {
+				edit.Emit(line)
+			}
+		}
+		return 0
 	}
-	return deps
-}
-
-func readCache(filename string) []Dependency {
-	f, err := os.Open(filename)
-	die(err)
-	defer f.Close()
-	dec := gob.NewDecoder(f)
-	var deps []Dependency
-	err = dec.Decode(&deps)
-	die(err)
-	return deps
-}
-
-func updateCache(filename string, deps []Dependency) {
-	// Gobify
-	var buf bytes.Buffer
-	enc := gob.NewEncoder(&buf)
-	err := enc.Encode(deps)
-	die(err)
-	ioutil.WriteFile(filename, buf.Bytes(), 0644)
-	log.Printf("updated cache %s\n", filename)
-}
 
-// there's a 1:1 mapping of genrule name to java package name
-func findGenrule(javaPackage string, workspace string) *string {
-	rule := strings.Replace(javaPackage, ".", "_", -1)
-	prms := []string{
-		"bazel",
-		"query",
-		rule,
-		"--output=label_kind",
-	}
-	cmd := exec.Command(prms[0], prms[1:]...)
-	cmd.Dir = workspace
-	log.Printf("executing %v in %s\n", prms, cmd.Dir)
-	buf, err := cmd.CombinedOutput()
+	h := Healer{
+		Workspace:         *workspace,
+		Deps:              deps,
+		Store:             st,
+		Threshold:         threshold,
+		Wrapper:           wrapper,
+		Generators:        generators,
+		PackageFilters:    filters,
+		DepsAttributes:    attributes,
+		PreferRepos:       split(*preferRepositories),
+		DefaultAttributes: defaults,
+		Visibility:        *visibility,
+		KotlinPlugins:     *kotlinPlugins,
+		AllImports:        *allImports,
+		Providers:         providers,
+	}
+	if *remember {
+		h.Fixes = readFixes(*cachefile + ".fixes")
+	}
+	h.Overrides, err = readOverrides(*workspace)
 	if err != nil {
-		if ee, ok := err.(*exec.ExitError); ok {
-			rc := ee.Sys().(interface {
-				ExitStatus() int
-			}).ExitStatus()
-			if rc == 7 {
-				return nil
-			} else {
-				log.Fatal(err)
-			}
+		log.Println(err)
+		return 1
+	}
+	if *learning {
+		h.Conventions = &conventions
+	}
+	// build log from stdin or -log, or from building a target ourselves
+	var input io.Reader = os.Stdin
+	if *logfile != "" {
+		f, err := os.Open(*logfile)
+		if err != nil {
+			log.Println(err)
+			return 1
 		}
+		defer f.Close()
+		input = f
 	}
-	scanner := bufio.NewScanner(bytes.NewReader(buf))
-	var lines []string
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+	// -target heals without a command unless a log is given, and is the
+	// default of heal
+	command, target := flags.Arg(0), flags.Arg(1)
+	if command == "" && *buildTarget != "" && *logfile == "" &&
+		*bep == "" && !piped() {
+		command = "heal"
 	}
-	want := fmt.Sprintf("genrule rule //:%s", rule)
-	if len(lines) == 1 && lines[0] == want {
-		return &rule
+	if (command == "heal" || command == "watch") && target == "" {
+		target = *buildTarget
 	}
-	return nil
-}
-
-func findClass(j JavaClass, deps []Dependency) *Dependency {
-	log.Printf("looking for dependency providing class %s\n", j.Name)
-	for _, d := range deps {
-		for _, r := range d.Resources {
-			if j.Name == r {
-				return &d
+	switch command {
+	case "":
+	case "heal":
+		if target == "" || flags.NArg() > 2 {
+			log.Printf("usage: bazel-kaizen [flags] heal " +
+				"//pkg:target\n")
+			return 2
+		}
+		if *loop {
+			if err := h.loop(target, *journal); err != nil {
+				log.Println(err)
+				return 1
 			}
+			return 0
+		}
+		buf, ok, err := bazel.Build(target, *workspace)
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		if ok {
+			log.Printf("%s builds fine, nothing to heal\n", target)
+			return 0
+		}
+		input = bytes.NewReader(buf)
+	case "watch":
+		if target == "" || flags.NArg() > 2 {
+			log.Printf("usage: bazel-kaizen [flags] watch " +
+				"//pkg:target\n")
+			return 2
+		}
+		err := h.watch(target, *interval, 0, *apply, *journal)
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		return 0
+	case "analyze":
+		if flags.NArg() != 2 {
+			log.Printf("usage: bazel-kaizen [flags] analyze " +
+				"//pkg:target|//...\n")
+			return 2
+		}
+		as, err := auditCached(bazel.JavaRules(flags.Arg(1), *workspace),
+			*workspace, *cachefile, deps)
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		missing, superfluous := 0, 0
+		for _, a := range as {
+			a.report()
+			missing += len(a.Missing)
+			superfluous += len(a.Superfluous)
+			if len(a.Missing) > 0 {
+				edit.Emit(edit.AddDeps(a.Target, a.Missing...).String())
+			}
+			if *removeSuperfluous && len(a.Superfluous) > 0 {
+				edit.Emit(edit.Edit{Command: "remove deps " +
+					strings.Join(a.Superfluous, " "),
+					Target: a.Target}.String())
+			}
+		}
+		log.Printf("summary: %d rules, %d missing, %d superfluous "+
+			"deps\n", len(as), missing, superfluous)
+		return 0
+	case "imports":
+		ms := importStats(deps, flags.Args()[1:],
+			split(*discouragedDeps), *jobs)
+		offending := 0
+		for _, m := range ms {
+			m.report(*top)
+			if len(m.Discouraged) > 0 {
+				offending++
+			}
+		}
+		log.Printf("summary: %d modules, %d importing discouraged "+
+			"dependencies\n", len(ms), offending)
+		return 0
+	case "strict":
+		if flags.NArg() != 2 {
+			log.Printf("usage: bazel-kaizen [flags] strict //pkg/...\n")
+			return 2
+		}
+		rules := bazel.JavaRules(flags.Arg(1), *workspace)
+		as, err := auditCached(rules, *workspace, *cachefile, deps)
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		for _, e := range strictStage(rules, as, *strictJavacopt,
+			*strictPackages) {
+			edit.Emit(e.String())
+		}
+		return 0
+	case "prune":
+		if flags.NArg() != 2 {
+			log.Printf("usage: bazel-kaizen [flags] prune " +
+				"//pkg:target|//...\n")
+			return 2
+		}
+		rules := bazel.JavaRules(flags.Arg(1), *workspace)
+		var as []Analysis
+		if *jdeps {
+			as, err = jdepsUnused(rules, *workspace)
+		} else {
+			as, err = auditCached(rules, *workspace, *cachefile, deps)
+		}
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		edits := pruneEdits(as)
+		log.Printf("summary: %d rules, %d to prune\n", len(as),
+			len(edits))
+		for _, e := range edits {
+			edit.Emit(e.String())
+		}
+		return 0
+	case "index":
+		if flags.NArg() < 3 || flags.Arg(1) != "query" {
+			log.Printf("usage: bazel-kaizen [flags] index query " +
+				"class:|package:|provider:<value>...\n")
+			return 2
+		}
+		terms, err := parseTerms(flags.Args()[2:])
+		if err != nil {
+			log.Println(err)
+			return 2
+		}
+		ms := query(deps, terms)
+		for _, m := range ms {
+			edit.Emit(m.Class + "\t" + m.Provider)
+		}
+		log.Printf("%d matches\n", len(ms))
+		return 0
+	case "export":
+		if flags.NArg() < 2 {
+			log.Printf("usage: bazel-kaizen [flags] export " +
+				"csv|tsv|jsonl [class:|package:|provider:<value>...]\n")
+			return 2
+		}
+		terms, err := parseTerms(flags.Args()[2:])
+		if err != nil {
+			log.Println(err)
+			return 2
+		}
+		ms := query(deps, terms)
+		if err := export(edit.Stdout, ms, flags.Arg(1)); err != nil {
+			log.Println(err)
+			return 2
+		}
+		log.Printf("exported %d classes\n", len(ms))
+		return 0
+	case "adopt":
+		if flags.NArg() != 2 {
+			log.Printf("usage: bazel-kaizen [flags] adopt <dir>\n")
+			return 2
+		}
+		if err := adoptDir(flags.Arg(1), deps); err != nil {
+			log.Println(err)
+			return 1
 		}
+		return 0
+	default:
+		log.Printf("unknown command %q\n", flags.Arg(0))
+		return 2
+	}
+	var ps parser.BuildProblems
+	if *bep != "" {
+		f, err := os.Open(*bep)
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		ps, err = parser.BepProblems(f)
+		f.Close()
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+	} else if *stream {
+		ps = streamProblems(input, os.Stderr)
+	} else {
+		var scanner = bufio.NewScanner(input)
+		ps = parser.Problems(*scanner)
+	}
+	log.Printf("build problems: %+v\n", ps)
+	if ps.Localized {
+		log.Printf("warning: javac reports in a language kaizen does " +
+			"not understand, problems are missing. Rebuild with " +
+			"English diagnostics by running javac with " +
+			"-J-Duser.language=en.\n")
+	}
+	if ps.Truncated {
+		log.Printf("warning: javac stopped reporting errors, the log " +
+			"is truncated. Rebuild with --javacopt=-Xmaxerrs=10000 " +
+			"to see all of them.\n")
 	}
-	return nil
-}
 
-func findSrcs(j JavaClass, workspace string) *string {
-	// making use of java package '.' as regexp to find /
-	q := fmt.Sprintf("attr('srcs', %s, :all)", j.Name)
-	prms := []string{
-		"bazel",
-		"query",
-		q,
-	}
-	cmd := exec.Command(prms[0], prms[1:]...)
-	cmd.Dir = workspace
-	log.Printf("executing %v in %s\n", prms, cmd.Dir)
-	buf, err := cmd.CombinedOutput()
+	var unresolved []Suggestion
+	if *format == "json" {
+		h.Unresolved = &unresolved
+	}
+	ss, err := h.healAll(ps)
 	if err != nil {
-		return nil
+		log.Println(err)
+		return 1
 	}
-	scanner := bufio.NewScanner(bytes.NewReader(buf))
-	var lines []string
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+	if *interactive {
+		// stdin may carry the build log
+		tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+		if err != nil {
+			log.Printf("cannot review suggestions: %v\n", err)
+			return 1
+		}
+		rules := queryRules(*workspace, nil, nil)
+		rules.attributes = h.DepsAttributes
+		ss, err = review(ss, tty, tty, h.alternatives(rules),
+			rules.Exists)
+		tty.Close()
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
 	}
-	if len(lines) == 1 && strings.HasPrefix(lines[0], "//:") {
-		return &lines[0]
+	edits := commands(ss)
+	summary := fmt.Sprintf("summary: %d missing classes, %d missing "+
+		"runfiles, %d missing main classes, %d visibility errors, "+
+		"%d missing targets, %d load errors, %d commands",
+		len(ps.MissingClass), len(ps.MissingRunfile), len(ps.MissingMain),
+		len(ps.Invisible), len(ps.MissingTarget), len(ps.Unloadable),
+		len(edits))
+	if ps.Truncated {
+		summary += ", incomplete because javac output was truncated"
 	}
-	return nil
-
-}
-
-// return buildozer representation
-func bdAddDeps(rule string, deps ...string) string {
-	return fmt.Sprintf("buildozer 'add deps %s' %s",
-		strings.Join(deps, " "), rule)
-}
-
-func emit(s string) {
-	fmt.Println(s)
-}
-
-func bdNewJavaLibrary(d Dependency) []string {
-	return []string{
-		fmt.Sprintf("buildozer 'new java_library %s' __pkg__",
-			d.Name),
-		fmt.Sprintf(`buildozer 'set srcs glob(["%s**/*.java"])' %s`,
-			d.ExternalReference, d.Name),
+	log.Println(summary)
+	if *stats {
+		used := command
+		if used == "" {
+			used = "log"
+		}
+		u := usage(used, ps, ss, len(edits), *apply, time.Now())
+		if err := appendUsage(*cachefile+".stats", u); err != nil {
+			log.Printf("cannot record usage: %v\n", err)
+		}
 	}
-}
-
-func main() {
-	var (
-		update = flag.Bool("update", false,
-			"update internal class cache and exit")
-		cachefile = flag.String("cachefile", ".healdb",
-			"name of cache file")
-		workspace = flag.String("workspace", ".", "bazel workspace")
-	)
-	flag.Parse()
-	if *update {
-		deps := fromSource(*workspace)
-		log.Printf("found %d source dependencies\n", len(deps))
-		d2 := externalDependencyProvider(*workspace)
-		log.Printf("found %d external dependencies\n", len(d2))
-		for _, d := range d2 {
-			deps = append(deps, d)
-		}
-		updateCache(*cachefile, deps)
-		// we cannot run bazel build and these internal bazel commands
-		// in parallel, so we're done here
-		os.Exit(0)
+	// rules must exist before anything depends on them
+	gen, rest := edit.Phases(edits)
+	if *apply {
+		if err := applyAll(edits, *workspace, *journal); err != nil {
+			log.Println(err)
+			return 1
+		}
+		h.Fixes.record(ss)
+		if err := h.Fixes.write(); err != nil {
+			log.Println(err)
+			return 1
+		}
 	}
-	deps := readCache(*cachefile)
-	log.Printf("cache contains %d dependencies\n", len(deps))
-
-	var scanner = bufio.NewScanner(os.Stdin)
-	ps := problems(*scanner)
-	log.Printf("build problems: %+v\n", ps)
-
-	// Match missing dependencies against providers
-	// Performance: process one missing class per Java package only
-	packagesResolved := make(map[string]bool)
-	done := func(pkg string) {
-		packagesResolved[pkg] = true
-	}
-	for _, p := range ps.MissingClass {
-		if packagesResolved[p.Package()] {
-			log.Printf("skipping resolution of class %s as "+
-				"package %s has already been resolved\n",
-				p.Name, p.Package())
-			continue
-		}
-		log.Printf("resolving missing dependency %v\n", p.Name)
-		// sources from internal packages/ rules?
-		r := findSrcs(p, *workspace)
-		if r == nil {
-			log.Printf("not provided by an existing rule\n")
-		} else {
-			emit(bdAddDeps(ps.BazelRule, *r))
-			done(p.Package())
-			continue
-		}
-		// dynamically generated via wsimport?
-		f := findGenrule(p.Package(), *workspace)
-		if f == nil {
-			log.Printf("not provided by wsimport genrule\n")
-		} else {
-			emit(bdAddDeps(ps.BazelRule, *f))
-			done(p.Package())
-			continue
-		}
-		e := findClass(p, deps)
-		if e == nil {
-			log.Printf("not provided by internal (source) or "+
-				"external (maven_jar) dependency %s\n", p)
-		} else {
-			log.Printf("missing class %v provided by %+v\n",
-				p.Name, e.Name)
-			// Treat external dependencies same as internal
-			name := strings.TrimPrefix(e.Name, "//external:")
-			if bzRuleExists(name, *workspace) {
-				emit(bdAddDeps(ps.BazelRule, name))
-			} else {
-				for _, cmd := range bdNewJavaLibrary(*e) {
-					emit(cmd)
-				}
-			}
-			done(p.Package())
+	if *format == "json" {
+		if err := emitJSON(append(ss, unresolved...)); err != nil {
+			log.Println(err)
+			return 1
 		}
-		log.Printf("*sniff* cannot resolve %s\n", p.Name)
+		return 0
+	}
+	if *apply {
+		return 0
+	}
+	for _, e := range append(gen, rest...) {
+		edit.Emit(e.String())
 	}
+	return 0
 }
@@ -3,73 +3,293 @@ package main
 import (
 	"bufio"
 	"bytes"
-	"fmt"
+	"encoding/json"
 	"io/ioutil"
 	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
 	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/bazel"
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+	"github.com/jhinrichsen/bazel-kaizen/index"
+	"github.com/jhinrichsen/bazel-kaizen/parser"
 )
 
-func ExampleStripLast() {
-	fmt.Println(StripLast("a.b.c.d"))
-	// Output: a.b.c
+func TestBzOutputBase(t *testing.T) {
+	needBazel(t)
+	s, err := bazel.OutputBase(fixtureWorkspace(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Printf("output base: %s\n", s)
+}
+
+func TestFromSource(t *testing.T) {
+	deps, names := index.FromSource(fixtureWorkspace(t), index.Sources{},
+		namings["segment"], nil, 1)
+	log.Printf("deps: %+v\n", deps)
+	want := 2
+	if len(deps) != want || len(names) != want {
+		t.Fatalf("want %d modules but got %+v\n", want, deps)
+	}
+	if _, ok := names["framework"]; !ok {
+		t.Fatalf("want module framework but got %+v\n", names)
+	}
 }
 
-func TestProblems(t *testing.T) {
-	buf, err := ioutil.ReadFile("testdata/bazel-1.log")
-	die(err)
-	probs := problems(*bufio.NewScanner(bytes.NewReader(buf)))
-	if len(probs.BazelRule) == 0 {
-		log.Fatalf("expected bazel rule but found nothing")
+func TestFromSourceTests(t *testing.T) {
+	ws := t.TempDir()
+	fixtureFiles(t, ws, map[string]string{
+		"framework/src/main/java/org/company/framework/A.java":       "",
+		"framework/src/test/java/org/company/framework/Fixture.java": "",
+		"it/src/test/java/it/SmokeTest.java":                         "",
+	})
+	deps, names := index.FromSource(ws, index.Sources{},
+		namings["segment"], nil, 1)
+	byName := make(map[string]index.Dependency)
+	for _, d := range deps {
+		byName[d.Name] = d
+	}
+	d, ok := byName["framework_tests"]
+	if !ok || !d.TestOnly ||
+		!d.Provides(index.Class, "org.company.framework.Fixture") ||
+		byName["framework"].Provides(index.Class,
+			"org.company.framework.Fixture") {
+		t.Fatalf("want test only framework_tests but got %+v\n", deps)
 	}
-	want := 7
-	got := len(probs.MissingClass)
-	if want != got {
-		log.Fatalf("want %d but got %d\n", want, got)
+	if _, ok := byName["it_tests"]; !ok || len(deps) != 3 ||
+		names["it_tests"] != filepath.Join(ws, "it") {
+		t.Fatalf("want it_tests but got %+v\n", names)
 	}
-	for _, m := range probs.MissingClass {
-		log.Printf("%+v\n", m)
+	edits := edit.NewJavaLibrary(d)
+	want := edit.Edit{Command: "set testonly 1", Target: "framework_tests"}
+	if edits[len(edits)-1] != want {
+		t.Fatalf("want %+v but got %+v\n", want, edits)
 	}
 }
 
-func TestOneJarFrom(t *testing.T) {
-	want := "testdata/junit-4.10.jar"
-	got := oneJarFrom("testdata")
-	if want != got {
-		t.Fatalf("want %s but got %s\n", want, got)
+func TestFromSourceLayouts(t *testing.T) {
+	ws := t.TempDir()
+	fixtureFiles(t, ws, map[string]string{
+		"framework/java/org/company/framework/A.java":       "",
+		"web/src/main/java/org/company/web/B.java":          "",
+		"third_party/legacy/java/org/company/legacy/C.java": "",
+	})
+	deps, _ := index.FromSource(ws, index.Sources{
+		Layouts: []string{"src/main/java", "java"},
+		Ignore:  []string{"third_party/legacy"},
+	}, namings["segment"], nil, 1)
+	refs := make(map[string]string)
+	for _, d := range deps {
+		refs[d.Name] = d.ExternalReference
+	}
+	want := map[string]string{
+		"framework": filepath.Join(ws, "framework") + "/java/",
+		"web":       filepath.Join(ws, "web") + "/src/main/java/",
+	}
+	if !reflect.DeepEqual(want, refs) {
+		t.Fatalf("want %v but got %v\n", want, refs)
 	}
 }
 
-func TestJarContent(t *testing.T) {
-	want := 252
-	got := len(content("testdata/junit-4.10.jar"))
-	if want != got {
-		t.Fatalf("want %v but got %v\n", want, got)
+func TestFromSourceSingleModule(t *testing.T) {
+	for _, tt := range []struct {
+		file, content string
+		kind          index.Kind
+		ref           string
+	}{
+		{"src/main/java/org/company/A.java", "package org.company;\n",
+			index.Source, "/src/main/java/"},
+		{"src/main/kotlin/A.kt", "package org.company\n\nclass A\n",
+			index.KotlinSource, "/src/main/"},
+		{"src/main/scala/A.scala", "package org.company\n\nclass A\n",
+			index.ScalaSource, "/src/main/"},
+	} {
+		ws := t.TempDir()
+		fixtureFiles(t, ws, map[string]string{tt.file: tt.content})
+		deps, _ := index.FromSource(ws, index.Sources{},
+			namings["segment"], nil, 1)
+		if len(deps) != 1 || deps[0].Kind != tt.kind ||
+			deps[0].ExternalReference != ws+tt.ref ||
+			!deps[0].Provides(index.Class, "org.company.A") {
+			t.Fatalf("%s: want one %s module but got %+v\n", tt.file,
+				tt.kind, deps)
+		}
+		// rules of the single module go into the root package
+		if pkg, ok := modulePackage(ws, deps[0]); ok {
+			t.Fatalf("%s: want root package but got %s\n", tt.file,
+				pkg)
+		}
 	}
 }
 
-func TestBzOutputBase(t *testing.T) {
-	s := bzOutputBase("testdata/workspace")
-	log.Printf("output base: %s\n", s)
+// stdout is piped into sh, so it must never carry anything but commands
+func TestStdoutContract(t *testing.T) {
+	var out, report bytes.Buffer
+	edit.Stdout = &out
+	log.SetOutput(&report)
+	defer func() {
+		edit.Stdout = os.Stdout
+		log.SetOutput(os.Stderr)
+	}()
+
+	lines := `INFO: Analysed target //:ui_web.
+ERROR: /ws/BUILD:1:1: Building libui_web.jar (1 source file) failed
+ui/web/src/main/java/ui/Fx.java:3: error: package org.a does not exist
+import org.a.A;
+`
+	ps := parser.Problems(*bufio.NewScanner(strings.NewReader(lines)))
+	deps := []index.Dependency{{Name: "a", Resources: classes("org.a.A")}}
+	d, _ := index.FindClass(ps.MissingClass[0], deps)
+	edits := edit.Valid(append(edit.NewJavaLibrary(*d),
+		edit.AddDeps(ps.BazelRule, "//:"+d.Name),
+		edit.Edit{Command: "add deps //:it's", Target: ps.BazelRule}))
+	for _, e := range edits {
+		edit.Emit(e.String())
+	}
+
+	if report.Len() == 0 {
+		t.Fatalf("want report on log but got nothing\n")
+	}
+	REBuildozer := regexp.MustCompile(`^buildozer '[^']+' \S+$`)
+	for _, line := range strings.Split(strings.TrimSpace(out.String()),
+		"\n") {
+		if !REBuildozer.MatchString(line) {
+			t.Fatalf("want buildozer command on stdout but got %q\n",
+				line)
+		}
+	}
 }
 
-func TestExternalDependencies(t *testing.T) {
-	want := 2
-	got := len(externalDependencyProvider("testdata/workspace"))
-	if want != got {
-		t.Fatalf("expected %v but got %v\n", want, got)
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	fakeTools(t, `test "$1" = info && echo `+dir+"\nexit 0\n", "exit 0\n")
+	var out bytes.Buffer
+	edit.Stdout = &out
+	defer func() { edit.Stdout = os.Stdout }()
+	cachefile := filepath.Join(dir, ".healdb")
+	buildLog := filepath.Join(dir, "build.log")
+	// strict deps repeat commands for each indirect use
+	err := ioutil.WriteFile(buildLog, []byte(
+		"buildozer 'add deps //:a' //ui/web:web\n"+
+			"buildozer 'add deps //:a' //ui/web:web\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		args []string
+		want int
+	}{
+		{[]string{"-cachefile", cachefile, "-workspace", dir, "-update"}, 0},
+		{[]string{"-cachefile", cachefile, "-log", buildLog}, 0},
+		{[]string{"-cachefile", cachefile, "heal"}, 2},
+		{[]string{"-cachefile", cachefile, "frobnicate"}, 2},
+		{[]string{"-min-confidence", "certain"}, 2},
+		{[]string{"-codegen", "nonsense"}, 2},
+		{[]string{"-package-filter", "nonsense"}, 2},
+		{[]string{"-deps-attributes", "service_library"}, 2},
+		{[]string{"-default-attributes", "nonsense"}, 2},
+		{[]string{"-format", "yaml"}, 2},
+		{[]string{"-online", "-no-network"}, 2},
+		{[]string{"-third-party", "third_party/{{.Name"}, 2},
+		{[]string{"-third-party", "third_party/{{.Nme}}"}, 2},
+		{[]string{"-naming", "camel"}, 2},
+		{[]string{"-providers", "srcs,oracle"}, 2},
+		{[]string{"-providers", "exec:"}, 2},
+		{[]string{"-naming", "template", "-naming-template",
+			"{{.Dri}}"}, 2},
+	} {
+		if got := run(tt.args); tt.want != got {
+			t.Fatalf("%q: want status %d but got %d\n", tt.args,
+				tt.want, got)
+		}
+	}
+	want := "buildozer 'add deps //:a' '//ui/web:web'\n"
+	if want != out.String() {
+		t.Fatalf("want %s but got %s\n", want, out.String())
 	}
 }
 
-func TestFromSource(t *testing.T) {
-	deps := fromSource("testdata/modules")
-	log.Printf("deps: %+v\n", deps)
+func TestRunJSON(t *testing.T) {
+	dir := t.TempDir()
+	fakeTools(t, `test "$1" = info && echo `+dir+"\nexit 0\n", "exit 0\n")
+	var out bytes.Buffer
+	edit.Stdout = &out
+	defer func() { edit.Stdout = os.Stdout }()
+	fixtureFiles(t, dir, map[string]string{
+		"ui/web/BUILD": "java_library(name = \"web\")\n",
+		"build.log": "ERROR: /ws/ui/web/BUILD:1:13: Building " +
+			"ui/web/libweb.jar (1 source file) failed: (Exit 1)\n" +
+			"ui/web/src/main/java/ui/Fx.java:3: error: package " +
+			"org.a does not exist\nimport org.a.A;\n" +
+			"buildozer 'add deps //:a' //ui/web:web\n",
+	})
+	cachefile := filepath.Join(dir, ".healdb")
+	for _, args := range [][]string{
+		{"-cachefile", cachefile, "-workspace", dir, "-update"},
+		{"-cachefile", cachefile, "-workspace", dir, "-format", "json",
+			"-apply", "-log", filepath.Join(dir, "build.log")},
+	} {
+		if got := run(args); got != 0 {
+			t.Fatalf("%q: want status 0 but got %d\n", args, got)
+		}
+	}
+	var actions []Action
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var s Suggestion
+		if err := dec.Decode(&s); err != nil {
+			t.Fatal(err)
+		}
+		actions = append(actions, s.Action)
+	}
+	want := []Action{Bazel, Unresolved}
+	if !reflect.DeepEqual(want, actions) {
+		t.Fatalf("want %v but got %v\n", want, actions)
+	}
 }
 
-func TestPackage(t *testing.T) {
-	j := JavaClass{Name: "org.company.framework.A"}
-	want := "org.company.framework"
-	got := j.Package()
-	if want != got {
-		t.Fatalf("want %s but got %s\n", want, got)
+func TestRunTarget(t *testing.T) {
+	dir := t.TempDir()
+	fakeTools(t, `case "$1" in
+info) echo `+dir+`;;
+build) for last; do :; done
+	echo "buildozer 'add deps //:a' $last"; exit 1;;
+esac
+exit 0
+`, "exit 0\n")
+	var out bytes.Buffer
+	edit.Stdout = &out
+	defer func() { edit.Stdout = os.Stdout }()
+	// a piped stdin would be the log
+	null, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer null.Close()
+	stdin := os.Stdin
+	os.Stdin = null
+	defer func() { os.Stdin = stdin }()
+	cachefile := filepath.Join(dir, ".healdb")
+	for _, args := range [][]string{
+		{"-cachefile", cachefile, "-workspace", dir, "-update"},
+		{"-cachefile", cachefile, "-target", "//ui/web:web"},
+		{"-cachefile", cachefile, "-target", "//ui/web:web", "heal"},
+		{"-cachefile", cachefile, "-target", "//ui/web:web", "heal",
+			"//app:app"},
+	} {
+		if got := run(args); got != 0 {
+			t.Fatalf("%q: want status 0 but got %d\n", args, got)
+		}
+	}
+	want := "buildozer 'add deps //:a' '//ui/web:web'\n" +
+		"buildozer 'add deps //:a' '//ui/web:web'\n" +
+		"buildozer 'add deps //:a' '//app:app'\n"
+	if want != out.String() {
+		t.Fatalf("want %s but got %s\n", want, out.String())
 	}
 }
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+	"github.com/jhinrichsen/bazel-kaizen/index"
+	"github.com/jhinrichsen/bazel-kaizen/parser"
+)
+
+// heal a binary whose run script cannot load its main class. An indexed
+// class is missing at runtime, so its provider becomes a runtime dep.
+// Otherwise main_class is likely wrong, and is set to the single indexed
+// class of the same simple name or of a similar name, if any.
+func (a *indexProvider) mainClass(m parser.MainClass) []Suggestion {
+	if m.Binary == "" {
+		return nil
+	}
+	b := *a
+	b.rule, b.classpath, b.testOnly = m.Binary, nil, nil
+	if ss := b.runtimeDep(m, m.Class); len(ss) > 0 {
+		return ss
+	}
+	c, ok := b.mainClassCandidate(m.Class)
+	if !ok {
+		return nil
+	}
+	ss := []Suggestion{{Rule: m.Binary, Action: SetMainClass,
+		Class: c, Provider: "index", Confidence: index.Medium,
+		Reason: fmt.Sprintf("main class %s is unknown, %s is indexed",
+			m.Class, c),
+		Evidence: []string{m.Line},
+		Edits: []edit.Edit{{Command: "set main_class " + c,
+			Target: m.Binary}}}}
+	return append(ss, b.runtimeDep(m, c)...)
+}
+
+// suggestions adding the dependency providing a main class to the runtime
+// deps of a binary. Unlike imports, a main class must be indexed itself, a
+// dependency providing its package is not good enough.
+func (a *indexProvider) runtimeDep(m parser.MainClass,
+	class string) []Suggestion {
+	j := index.JavaClass{Name: class, Log: []string{m.Line}}
+	indexed := false
+	for _, d := range a.candidates(j) {
+		indexed = indexed || d.Provides(index.Class, class)
+	}
+	if !indexed {
+		return nil
+	}
+	var ss []Suggestion
+	for _, s := range a.Lookup(j) {
+		s.Rule, s.Class = m.Binary, class
+		s.Action = AddDep
+		if len(s.Edits) > 0 {
+			s.Action = CreateRule
+		}
+		s.Reason = fmt.Sprintf("main class %s is missing at runtime, "+
+			"%s", class, s.Reason)
+		s.Evidence = append([]string{m.Line}, s.Evidence...)
+		s.Edits = append(append([]edit.Edit{}, s.Edits...), edit.Edit{
+			Command: "add runtime_deps " + s.Dep, Target: m.Binary})
+		ss = append(ss, s)
+	}
+	return ss
+}
+
+// the single indexed class of the same simple name as a main class, or of
+// a similar name
+func (a *indexProvider) mainClassCandidate(class string) (string, bool) {
+	simple := "." + class[strings.LastIndex(class, ".")+1:]
+	found := make(map[string]bool)
+	for _, d := range index.Production(a.h.Deps) {
+		for _, c := range d.Named(index.Class) {
+			if strings.HasSuffix(c, simple) {
+				found[c] = true
+			}
+		}
+	}
+	if len(found) == 0 {
+		for _, d := range index.Production(a.h.Deps) {
+			for _, c := range d.Named(index.Class) {
+				if distance(c, class) <= 2 {
+					found[c] = true
+				}
+			}
+		}
+	}
+	var cs []string
+	for c := range found {
+		cs = append(cs, c)
+	}
+	sort.Strings(cs)
+	if len(cs) > 1 {
+		log.Printf("main class %s is ambiguous: %s\n", class,
+			strings.Join(cs, ", "))
+	}
+	if len(cs) != 1 {
+		return "", false
+	}
+	return cs[0], true
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+	"github.com/jhinrichsen/bazel-kaizen/index"
+	"github.com/jhinrichsen/bazel-kaizen/parser"
+)
+
+func TestProblemsMainClass(t *testing.T) {
+	lines := `INFO: Build completed successfully, 3 total actions
+INFO: Running command line: bazel-bin/app/server
+Error: Could not find or load main class com.company.app.Server
+Caused by: java.lang.ClassNotFoundException: com.company.app.Server
+`
+	ps := parser.Problems(*bufio.NewScanner(strings.NewReader(lines)))
+	want := []parser.MainClass{{Binary: "//app:server",
+		Class: "com.company.app.Server",
+		Line: "Error: Could not find or load main class " +
+			"com.company.app.Server"}}
+	if !reflect.DeepEqual(want, ps.MissingMain) {
+		t.Fatalf("want %+v but got %+v\n", want, ps.MissingMain)
+	}
+}
+
+func TestHealMainClass(t *testing.T) {
+	fakeTools(t, "exit 0\n", "exit 0\n")
+	h := Healer{
+		Workspace: t.TempDir(),
+		Deps: []index.Dependency{{Name: "@maven//:server",
+			Kind:      index.RulesJvmExternal,
+			Resources: classes("com.company.app.Server")}},
+		Providers: []string{"index"},
+	}
+	for _, tt := range []struct {
+		class string
+		want  []edit.Edit
+	}{
+		// missing at runtime
+		{"com.company.app.Server", []edit.Edit{{
+			Command: "add runtime_deps @maven//:server",
+			Target:  "//app:server"}}},
+		// wrong package
+		{"com.company.Server", []edit.Edit{
+			{Command: "set main_class com.company.app.Server",
+				Target: "//app:server"},
+			{Command: "add runtime_deps @maven//:server",
+				Target: "//app:server"}}},
+		// typo
+		{"com.company.app.Sever", []edit.Edit{
+			{Command: "set main_class com.company.app.Server",
+				Target: "//app:server"},
+			{Command: "add runtime_deps @maven//:server",
+				Target: "//app:server"}}},
+		{"org.other.Main", nil},
+	} {
+		ss, err := h.heal(parser.BuildProblems{
+			MissingMain: []parser.MainClass{
+				{Binary: "//app:server", Class: tt.class}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := commands(ss); !reflect.DeepEqual(tt.want, got) {
+			t.Fatalf("%s: want %+v but got %+v\n", tt.class, tt.want,
+				got)
+		}
+	}
+}
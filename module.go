@@ -0,0 +1,52 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+// bazel package of the module of a source dependency, such as ui/web. ok is
+// false for modules at the workspace root, dependencies without sources, and
+// caches predating modules, whose rules go into the root package.
+func modulePackage(workspace string, d index.Dependency) (string, bool) {
+	if d.Module == "" || !strings.HasPrefix(d.ExternalReference,
+		d.Module+"/") {
+		return "", false
+	}
+	ws, err := filepath.Abs(workspace)
+	if err != nil {
+		return "", false
+	}
+	dir, err := filepath.Abs(d.Module)
+	if err != nil {
+		return "", false
+	}
+	pkg, err := filepath.Rel(ws, dir)
+	if err != nil || pkg == "." || strings.HasPrefix(pkg, "..") {
+		return "", false
+	}
+	return filepath.ToSlash(pkg), true
+}
+
+// edits generating the rule of a source dependency in the package of its
+// module, globbing sources relative to it. bazel globs stop at package
+// boundaries, and buildozer creates the BUILD file of the package if there
+// is none. Rules of other packages need a visibility, public unless
+// attributes set one.
+func inModule(rule func(index.Dependency) []edit.Edit, d index.Dependency,
+	pkg string, attributes []Attribute) []edit.Edit {
+	local := d
+	local.ExternalReference = strings.TrimPrefix(d.ExternalReference,
+		d.Module+"/")
+	es := edit.InPackage(rule(local), d.Name, pkg)
+	for _, a := range attributes {
+		if a.Name == "visibility" {
+			return es
+		}
+	}
+	return append(es, edit.Edit{Command: "set visibility " +
+		"//visibility:public", Target: "//" + pkg + ":" + d.Name})
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+	"github.com/jhinrichsen/bazel-kaizen/index"
+	"github.com/jhinrichsen/bazel-kaizen/parser"
+)
+
+func TestHealModulePackage(t *testing.T) {
+	fakeTools(t, "exit 0\n", "exit 0\n")
+	ws := t.TempDir()
+	module := filepath.Join(ws, "ui/web")
+	h := Healer{
+		Workspace: ws,
+		Deps: []index.Dependency{{Name: "ui_web", Kind: index.Source,
+			ExternalReference: module + "/src/main/java/",
+			Module:            module,
+			Resources:         classes("org.a.A")}},
+		Providers: []string{"index"},
+	}
+	ss, err := h.heal(parser.BuildProblems{BazelRule: "//app:app",
+		MissingClass: []index.JavaClass{{Name: "org.a.A"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []edit.Edit{
+		{Command: "new java_library ui_web", Target: "//ui/web:__pkg__"},
+		{Command: `set srcs glob(["src/main/java/**/*.java"])`,
+			Target: "//ui/web:ui_web"},
+		{Command: "set visibility //visibility:public",
+			Target: "//ui/web:ui_web"},
+		{Command: "add deps //ui/web:ui_web", Target: "//app:app"},
+	}
+	if len(ss) != 1 || !reflect.DeepEqual(want, ss[0].Edits) {
+		t.Fatalf("want %+v but got %+v\n", want, ss)
+	}
+
+	// caches without modules keep generating into the root package
+	h.Deps[0].Module = ""
+	ss, err = h.heal(parser.BuildProblems{BazelRule: "//app:app",
+		MissingClass: []index.JavaClass{{Name: "org.a.A"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ss) != 1 || ss[0].Dep != "//:ui_web" {
+		t.Fatalf("want //:ui_web but got %+v\n", ss)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"text/template"
+)
+
+// Naming derives the name of a generated rule from a module directory,
+// before it is sanitized and made unique
+type Naming func(dir string) string
+
+// Module describes a module directory for naming templates
+type Module struct {
+	Dir        string // ui/web
+	Segment    string // web
+	ArtifactID string // Maven artifactId from pom.xml, Segment if none
+}
+
+// artifactId of a Maven module, empty if there is no pom.xml
+func artifactID(dir string) string {
+	buf, err := ioutil.ReadFile(filepath.Join(dir, "pom.xml"))
+	if err != nil {
+		return ""
+	}
+	// only the project's own artifactId, not the parent's
+	var pom struct {
+		ArtifactID string `xml:"artifactId"`
+	}
+	if err := xml.Unmarshal(buf, &pom); err != nil {
+		log.Printf("cannot parse %s/pom.xml: %v\n", dir, err)
+		return ""
+	}
+	return pom.ArtifactID
+}
+
+func module(dir string) Module {
+	m := Module{Dir: dir, Segment: filepath.Base(dir)}
+	m.ArtifactID = artifactID(dir)
+	if m.ArtifactID == "" {
+		m.ArtifactID = m.Segment
+	}
+	return m
+}
+
+// naming strategies taking no template: path (full path, default), segment
+// (last path segment), and artifactId (from pom.xml)
+var namings = map[string]Naming{
+	"path": func(dir string) string {
+		return dir
+	},
+	"segment": func(dir string) string {
+		return filepath.Base(dir)
+	},
+	"artifactId": func(dir string) string {
+		return module(dir).ArtifactID
+	},
+}
+
+// naming strategy by name, one of namings, or template (text/template on
+// Module). Templates are tried on a module up front, modules they still
+// fail on are named by path.
+func naming(strategy string, tmpl string) (Naming, error) {
+	if n, ok := namings[strategy]; ok {
+		return n, nil
+	}
+	if strategy != "template" {
+		return nil, fmt.Errorf("unknown naming strategy %q, want path, "+
+			"segment, artifactId, or template", strategy)
+	}
+	t, err := template.New("naming").Parse(tmpl)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	sample := Module{Dir: "ui/web", Segment: "web", ArtifactID: "web"}
+	if err := t.Execute(&buf, sample); err != nil {
+		return nil, err
+	}
+	return func(dir string) string {
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, module(dir)); err != nil {
+			log.Printf("naming %s by path: %v\n", dir, err)
+			return dir
+		}
+		return buf.String()
+	}, nil
+}
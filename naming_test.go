@@ -0,0 +1,50 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+func TestNaming(t *testing.T) {
+	module := filepath.Join(t.TempDir(), "services", "billing-core")
+	pom := `<project>
+  <parent><artifactId>services</artifactId></parent>
+  <artifactId>billing</artifactId>
+</project>`
+	fixtureFiles(t, module, map[string]string{"pom.xml": pom})
+	for _, tt := range []struct {
+		strategy, tmpl, want string
+	}{
+		{"path", "", index.RuleName(module)},
+		{"segment", "", "billing_core"},
+		{"artifactId", "", "billing"},
+		{"template", "lib_{{.ArtifactID}}", "lib_billing"},
+	} {
+		n, err := naming(tt.strategy, tt.tmpl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := index.RuleNames([]string{module}, n)
+		got := names[module]
+		if tt.want != got {
+			t.Fatalf("%s: want %s but got %s\n",
+				tt.strategy, tt.want, got)
+		}
+	}
+}
+
+func TestNamingInvalid(t *testing.T) {
+	for _, tt := range []struct {
+		strategy, tmpl string
+	}{
+		{"camel", ""},
+		{"template", "lib_{{.ArtifactID"},
+		{"template", "lib_{{.Artifact}}"},
+	} {
+		if _, err := naming(tt.strategy, tt.tmpl); err == nil {
+			t.Fatalf("%s %q: want error\n", tt.strategy, tt.tmpl)
+		}
+	}
+}
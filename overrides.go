@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+// overridesFile pins classes to deps, in the user's home, the workspace, or
+// any directory of the workspace for the rules below it. Keys are class
+// prefixes, values the deps to use:
+//
+//	com.company.billing: //services/billing:api
+//	com.google.common: "@maven//:com_google_guava_guava"
+const overridesFile = ".kaizen-overrides.yaml"
+
+// precedence of override files, directories rank above the workspace by
+// depth, so that the nearest one wins
+const (
+	homeLevel = iota
+	workspaceLevel
+)
+
+// Override pins the classes of a prefix to a dep for the rules of a package
+// subtree
+type Override struct {
+	Dir    string // package subtree, "" for the whole workspace
+	Level  int    // precedence, higher wins
+	Prefix string // class or package, such as com.company.billing
+	Dep    string
+	File   string // override file declaring it
+}
+
+// Overrides of the override files read so far, merged. The files of the
+// user's home and the workspace root are read up front, those of packages on
+// the first lookup for a rule below them, never the whole workspace.
+type Overrides struct {
+	workspace string
+	read      map[string]bool // packages looked for an override file
+	all       []Override
+}
+
+// parse an override file of a level, for the rules of dir
+func parseOverrides(r io.Reader, dir string, level int,
+	file string) ([]Override, error) {
+	m, err := parseConfig(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	var overrides []Override
+	for prefix, dep := range m {
+		if !edit.ValidLabel(dep) || strings.Contains(dep, ",") {
+			return nil, fmt.Errorf("%s: %s: invalid dep %q", file,
+				prefix, dep)
+		}
+		overrides = append(overrides,
+			Override{dir, level, prefix, dep, file})
+	}
+	sort.Slice(overrides, func(i, j int) bool {
+		return overrides[i].Prefix < overrides[j].Prefix
+	})
+	return overrides, nil
+}
+
+// read an override file, if there is one
+func readOverrideFile(filename string, dir string,
+	level int) ([]Override, error) {
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	log.Printf("reading overrides %s\n", filename)
+	return parseOverrides(f, dir, level, filename)
+}
+
+// overrides of the user's home and of the workspace root
+func readOverrides(workspace string) (*Overrides, error) {
+	a := &Overrides{workspace: workspace,
+		read: map[string]bool{"": true}}
+	if home, err := os.UserHomeDir(); err == nil {
+		overrides, err := readOverrideFile(filepath.Join(home,
+			overridesFile), "", homeLevel)
+		if err != nil {
+			return nil, err
+		}
+		a.all = append(a.all, overrides...)
+	}
+	overrides, err := readOverrideFile(filepath.Join(workspace,
+		overridesFile), "", workspaceLevel)
+	if err != nil {
+		return nil, err
+	}
+	a.all = append(a.all, overrides...)
+	return a, nil
+}
+
+// read the override files of a package and its ancestors not read yet
+func (a *Overrides) readPackage(pkg string) error {
+	if a.read == nil {
+		a.read = map[string]bool{"": true}
+	}
+	for dir := pkg; !a.read[dir]; {
+		a.read[dir] = true
+		level := workspaceLevel + 1 + strings.Count(dir, "/")
+		overrides, err := readOverrideFile(filepath.Join(a.workspace,
+			filepath.FromSlash(dir), overridesFile), dir, level)
+		if err != nil {
+			return err
+		}
+		a.all = append(a.all, overrides...)
+		parent := ""
+		if i := strings.LastIndex(dir, "/"); i >= 0 {
+			parent = dir[:i]
+		}
+		dir = parent
+	}
+	return nil
+}
+
+// override of a class for a rule: of the nearest override file, and of the
+// longest prefix within it. Prefixes match whole classes or packages.
+func (a *Overrides) lookup(rule string, class string) (Override, bool,
+	error) {
+	if a == nil {
+		return Override{}, false, nil
+	}
+	pkg := edit.LabelPackage(rule)
+	if err := a.readPackage(pkg); err != nil {
+		return Override{}, false, err
+	}
+	var best Override
+	found := false
+	for _, o := range a.all {
+		if o.Dir != "" && pkg != o.Dir &&
+			!strings.HasPrefix(pkg, o.Dir+"/") {
+			continue
+		}
+		if class != o.Prefix && !strings.HasPrefix(class, o.Prefix+".") {
+			continue
+		}
+		if !found || o.Level > best.Level || o.Level == best.Level &&
+			len(o.Prefix) > len(best.Prefix) {
+			best, found = o, true
+		}
+	}
+	return best, found, nil
+}
+
+// suggestion applying an override
+func (a Override) suggestion(rule string, j index.JavaClass) Suggestion {
+	return Suggestion{Rule: rule, Action: AddDep, Dep: a.Dep,
+		Class: j.Name, Location: j.Location, Provider: "overrides",
+		Confidence: index.High,
+		Reason:     fmt.Sprintf("%s is pinned to %s", a.Prefix, a.Dep),
+		Evidence: append(append([]string{}, j.Log...),
+			"overrides: "+a.File),
+		Edits: []edit.Edit{edit.AddDeps(rule, a.Dep)}}
+}
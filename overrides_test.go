@@ -0,0 +1,88 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+	"github.com/jhinrichsen/bazel-kaizen/parser"
+)
+
+func TestOverrides(t *testing.T) {
+	home, ws := t.TempDir(), t.TempDir()
+	t.Setenv("HOME", home)
+	fixtureFiles(t, home, map[string]string{
+		overridesFile: "com.company: //home:company\n" +
+			"org.only: //home:only\n",
+	})
+	fixtureFiles(t, ws, map[string]string{
+		overridesFile: "com.company: //ws:company\n" +
+			"com.company.billing: //ws:billing\n",
+		filepath.Join("services", overridesFile): "com.company: " +
+			"//services:company\n",
+		filepath.Join("services/billing", overridesFile): "" +
+			"com.company.billing.Invoice: '@maven//:invoice'\n",
+		filepath.Join("bazel-out", overridesFile): "com: //out:out\n",
+		// never read, no failing rule below it
+		filepath.Join("other", overridesFile): "com.bad: it's\n",
+	})
+	overrides, err := readOverrides(ws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		rule, class, want string
+	}{
+		{"//app:app", "com.company.billing.Invoice", "//ws:billing"},
+		{"//app:app", "com.company.A", "//ws:company"},
+		{"//app:app", "org.only.A", "//home:only"},
+		// nearest directory wins over a longer prefix
+		{"//services/web:web", "com.company.billing.A",
+			"//services:company"},
+		{"//services/billing/core:core", "com.company.billing.Invoice",
+			"@maven//:invoice"},
+		{"//services/billing/core:core", "com.company.billing.A",
+			"//services:company"},
+		// prefixes match whole segments
+		{"//app:app", "com.companyx.A", ""},
+	} {
+		o, _, err := overrides.lookup(tt.rule, tt.class)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.want != o.Dep {
+			t.Fatalf("%s %s: want %q but got %+v\n", tt.rule, tt.class,
+				tt.want, o)
+		}
+	}
+
+	if _, _, err := overrides.lookup("//other/a:a", "com.A"); err == nil {
+		t.Fatalf("want error for invalid dep\n")
+	}
+	fixtureFiles(t, ws, map[string]string{
+		overridesFile: "com.bad: it's\n",
+	})
+	if _, err := readOverrides(ws); err == nil {
+		t.Fatalf("want error for invalid dep\n")
+	}
+}
+
+func TestHealOverride(t *testing.T) {
+	fakeTools(t, "exit 0\n", "exit 0\n")
+	h := Healer{
+		Workspace: t.TempDir(),
+		Deps:      []index.Dependency{{Name: "a", Resources: classes("org.a.A")}},
+		Providers: []string{"index"},
+		Overrides: &Overrides{all: []Override{{Prefix: "org.a",
+			Dep: "//pinned:a"}}},
+	}
+	ss, err := h.heal(parser.BuildProblems{BazelRule: "//app:app",
+		MissingClass: []index.JavaClass{{Name: "org.a.A"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ss) != 1 || ss[0].Dep != "//pinned:a" ||
+		ss[0].Provider != "overrides" {
+		t.Fatalf("want //pinned:a but got %+v\n", ss)
+	}
+}
@@ -0,0 +1,31 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// own hands a file written by root over to the owner of its directory. In a
+// container, kaizen runs as root on a mounted workspace, and files it creates
+// must stay editable by the user outside.
+func own(filename string) error {
+	if os.Getuid() != 0 {
+		return nil
+	}
+	fi, err := os.Stat(filepath.Dir(filename))
+	if err != nil {
+		return err
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok || st.Uid == 0 {
+		return nil
+	}
+	err = os.Lchown(filename, int(st.Uid), int(st.Gid))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,35 @@
+//go:build unix
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestOwn(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("need root to change owners")
+	}
+	dir := t.TempDir()
+	if err := os.Chown(dir, 1000, 1000); err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "BUILD.bazel")
+	if err := ioutil.WriteFile(filename, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := own(filename); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uid := fi.Sys().(*syscall.Stat_t).Uid; uid != 1000 {
+		t.Fatalf("want owner 1000 but got %d\n", uid)
+	}
+}
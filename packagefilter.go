@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+// PackageFilter restricts the Java packages a dependency is suggested for,
+// such as the repackaged third party code of a fat jar
+type PackageFilter struct {
+	Include []string // package prefixes, all packages if empty
+	Exclude []string // package prefixes never suggested
+}
+
+// parse comma separated dependency=prefix pairs, such as
+// @maven//:fat=com.fat,@maven//:fat=!com.fat.shaded.*. A prefix covers its
+// subpackages, and excludes when starting with !. Several pairs of a
+// dependency add up.
+func parsePackageFilters(s string) (map[string]PackageFilter, error) {
+	fs := make(map[string]PackageFilter)
+	for _, pair := range strings.Split(s, ",") {
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] == "" || strings.Trim(kv[1], "!") == "" {
+			return nil, fmt.Errorf("want dependency=package but got %q",
+				pair)
+		}
+		f := fs[kv[0]]
+		prefix := strings.TrimSuffix(kv[1], ".*")
+		if strings.HasPrefix(prefix, "!") {
+			f.Exclude = append(f.Exclude, prefix[1:])
+		} else {
+			f.Include = append(f.Include, prefix)
+		}
+		fs[kv[0]] = f
+	}
+	return fs, nil
+}
+
+// below reports whether a package is one of prefixes or a subpackage
+func below(javaPackage string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if javaPackage == p || strings.HasPrefix(javaPackage, p+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// allows reports whether a package may be suggested
+func (a PackageFilter) allows(javaPackage string) bool {
+	if below(javaPackage, a.Exclude) {
+		return false
+	}
+	return len(a.Include) == 0 || below(javaPackage, a.Include)
+}
+
+// dependencies their filters allow to provide a class
+func filterPackages(fs map[string]PackageFilter, j index.JavaClass,
+	deps []index.Dependency) []index.Dependency {
+	if len(fs) == 0 {
+		return deps
+	}
+	var ds []index.Dependency
+	for _, d := range deps {
+		if f, ok := fs[d.Name]; ok && !f.allows(j.Package()) {
+			log.Printf("%s: package %s filtered out\n", d.Name,
+				j.Package())
+			continue
+		}
+		ds = append(ds, d)
+	}
+	return ds
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+func TestPackageFilters(t *testing.T) {
+	fs, err := parsePackageFilters("@maven//:fat=com.fat," +
+		"@maven//:fat=!com.fat.shaded.*,@maven//:guava=!com.google.thirdparty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fat := index.Dependency{Name: "@maven//:fat",
+		Resources: index.Resources([]string{"com.fat.A",
+			"com.fat.shaded.B", "org.apache.C"}, nil)}
+	other := index.Dependency{Name: "@maven//:other",
+		Resources: index.Resources([]string{"org.apache.C"}, nil)}
+	for _, tt := range []struct {
+		class string
+		want  bool // fat may provide
+	}{
+		{"com.fat.A", true},
+		{"com.fat.shaded.B", false},
+		{"org.apache.C", false},
+		{"com.fatter.D", false},
+	} {
+		ds := filterPackages(fs, index.JavaClass{Name: tt.class},
+			[]index.Dependency{fat, other})
+		got := ds[0].Name == fat.Name
+		if tt.want != got || ds[len(ds)-1].Name != other.Name {
+			t.Fatalf("%s: want fat %v but got %+v\n", tt.class,
+				tt.want, ds)
+		}
+	}
+	if _, err := parsePackageFilters("@maven//:fat=!"); err == nil {
+		t.Fatalf("want error for empty package\n")
+	}
+}
@@ -0,0 +1,94 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/url"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+// BuildEvent holds the parts of a Build Event Protocol event, as written by
+// --build_event_json_file, that carry compiler output
+type BuildEvent struct {
+	Action *struct {
+		Success bool     `json:"success"`
+		Label   string   `json:"label"`
+		Type    string   `json:"type"`
+		Stderr  *BepFile `json:"stderr"`
+	} `json:"action"`
+}
+
+// BepFile of a build event, either inlined or referenced by URI
+type BepFile struct {
+	Name     string `json:"name"`
+	URI      string `json:"uri"`
+	Contents []byte `json:"contents"`
+}
+
+// Read reads a build event file, only local files can be read
+func (a BepFile) Read() ([]byte, error) {
+	if len(a.Contents) > 0 {
+		return a.Contents, nil
+	}
+	u, err := url.Parse(a.URI)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "file" {
+		return nil, fmt.Errorf("cannot read %s, not a local file", a.URI)
+	}
+	return ioutil.ReadFile(u.Path)
+}
+
+// BepProblems are the build problems from the Build Event Protocol. The
+// stderr of each failed action is scanned for missing classes just like a
+// build log, the first failed action determines the rule.
+func BepProblems(r io.Reader) (BuildProblems, error) {
+	dec := json.NewDecoder(r)
+	var ps BuildProblems
+	for {
+		var e BuildEvent
+		err := dec.Decode(&e)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return ps, err
+		}
+		a := e.Action
+		if a == nil || a.Success || a.Stderr == nil {
+			continue
+		}
+		buf, err := a.Stderr.Read()
+		if err != nil {
+			log.Printf("skip %s action of %s: %v\n", a.Type, a.Label,
+				err)
+			continue
+		}
+		p := Problems(*bufio.NewScanner(bytes.NewReader(buf)))
+		if ps.BazelRule == "" {
+			ps.BazelRule = a.Label
+			ps.Classpath = p.Classpath
+			ps.Suggested = p.Suggested
+		}
+		if ps.ByRule == nil {
+			ps.ByRule = make(map[string][]index.JavaClass)
+		}
+		if len(p.MissingClass) > 0 {
+			ps.ByRule[a.Label] = append(ps.ByRule[a.Label],
+				p.MissingClass...)
+		}
+		ps.MissingClass = append(ps.MissingClass, p.MissingClass...)
+		ps.MissingRunfile = append(ps.MissingRunfile,
+			p.MissingRunfile...)
+		ps.Sources = append(ps.Sources, p.Sources...)
+		ps.Truncated = ps.Truncated || p.Truncated
+	}
+	return ps, nil
+}
@@ -0,0 +1,47 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBepProblems(t *testing.T) {
+	stderr := filepath.Join(t.TempDir(), "stderr")
+	err := ioutil.WriteFile(stderr, []byte(fixtureLog), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inline, _ := json.Marshal([]byte("b/B.java:1: error: package org.b " +
+		"does not exist\nimport org.b.B;\n"))
+	events := strings.Join([]string{
+		`{"id":{"progress":{}},"progress":{"stderr":"Loading"}}`,
+		`{"id":{},"action":{"success":true,"label":"//a:a"}}`,
+		fmt.Sprintf(`{"id":{},"action":{"success":false,`+
+			`"label":"//ui/web:web","type":"Javac",`+
+			`"stderr":{"uri":"file://%s"}}}`, stderr),
+		fmt.Sprintf(`{"id":{},"action":{"success":false,`+
+			`"label":"//b:b","type":"Javac",`+
+			`"stderr":{"contents":%s}}}`, inline),
+	}, "\n")
+	ps, err := BepProblems(strings.NewReader(events))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "//ui/web:web"
+	if want != ps.BazelRule {
+		t.Fatalf("want %s but got %s\n", want, ps.BazelRule)
+	}
+	if len(ps.MissingClass) != 8 {
+		t.Fatalf("want 8 missing classes but got %+v\n",
+			ps.MissingClass)
+	}
+	rs := ps.Rules()
+	if len(rs) != 2 || rs[0].BazelRule != "//b:b" ||
+		len(rs[0].MissingClass) != 1 {
+		t.Fatalf("want //b:b missing org.b.B but got %+v\n", rs)
+	}
+}
@@ -0,0 +1,46 @@
+package parser
+
+import (
+	"bufio"
+	"log"
+	"os"
+	"strings"
+)
+
+// ReadParams reads the arguments of a Bazel params file, one per line
+func ReadParams(filename string) []string {
+	f, err := os.Open(filename)
+	if err != nil {
+		log.Printf("cannot read params file: %v\n", err)
+		return nil
+	}
+	defer f.Close()
+	var args []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		args = append(args, scanner.Text())
+	}
+	return args
+}
+
+// ParseClasspath returns the classpath of a JavaBuilder (--classpath a.jar
+// b.jar --next_option) or javac (-cp a.jar:b.jar) command line
+func ParseClasspath(args []string) []string {
+	var cp []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--classpath":
+			for i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				i++
+				cp = append(cp, args[i])
+			}
+		case "-cp", "-classpath":
+			if i+1 < len(args) {
+				i++
+				cp = append(cp, strings.Split(args[i], ":")...)
+			}
+		}
+	}
+	return cp
+}
@@ -0,0 +1,46 @@
+package parser
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestParseClasspath(t *testing.T) {
+	args := []string{
+		"--output", "bazel-out/k8-fastbuild/bin/ui/web/libweb.jar",
+		"--classpath",
+		"bazel-out/k8-fastbuild/bin/api/libapi-hjar.jar",
+		"bazel-out/k8-fastbuild/bin/external/guava/jar/_ijar/jar/" +
+			"external/guava/jar/guava-20.0-ijar.jar",
+		"--sourcepath",
+		"-cp", "a.jar:b.jar",
+	}
+	cp := ParseClasspath(args)
+	if len(cp) != 4 {
+		t.Fatalf("want 4 entries but got %+v\n", cp)
+	}
+}
+
+func TestProblemsClasspath(t *testing.T) {
+	execroot := t.TempDir()
+	fixtureFiles(t, execroot, map[string]string{
+		"bazel-out/k8-fastbuild/bin/ui/web/libweb.jar-0.params": "" +
+			"--classpath\n" +
+			"bazel-out/k8-fastbuild/bin/api/libapi-hjar.jar\n" +
+			"--sourcepath\n",
+	})
+	lines := "ERROR: /ws/ui/web/BUILD:1:13: Building ui/web/libweb.jar " +
+		"(1 source file) failed: (Exit 1): java failed: error " +
+		"executing command (from target //ui/web:web)\n" +
+		"  (cd " + execroot + " && \\\n" +
+		"  exec env - \\\n" +
+		"  external/remotejdk11_linux/bin/java -jar " +
+		"JavaBuilder_deploy.jar " +
+		"@bazel-out/k8-fastbuild/bin/ui/web/libweb.jar-0.params)\n"
+	probs := Problems(*bufio.NewScanner(strings.NewReader(lines)))
+	want := "bazel-out/k8-fastbuild/bin/api/libapi-hjar.jar"
+	if len(probs.Classpath) != 1 || probs.Classpath[0] != want {
+		t.Fatalf("want %s but got %+v\n", want, probs.Classpath)
+	}
+}
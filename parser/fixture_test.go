@@ -0,0 +1,49 @@
+package parser
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// build log of a failing java_library, missing 7 classes
+const fixtureLog = `INFO: Analysed target //:ui_web (0 packages loaded).
+INFO: Found 1 target...
+ERROR: /ws/BUILD:1:1: Building libui_web.jar (3 source files) failed (Exit 1)
+ui/web/src/main/java/ui/Fx.java:3: error: package org.company.framework does not exist
+import org.company.framework.A;
+                            ^
+ui/web/src/main/java/ui/Fx.java:4: error: package org.company.framework does not exist
+import org.company.framework.B;
+                            ^
+ui/web/src/main/java/ui/Fx.java:5: error: package org.company.util does not exist
+import org.company.util.Strings;
+                              ^
+ui/web/src/main/java/ui/Fx.java:6: error: package org.junit does not exist
+import org.junit.Test;
+                ^
+ui/web/src/main/java/ui/Fx.java:7: error: package org.junit does not exist
+import org.junit.Assert;
+                ^
+ui/web/src/main/java/ui/Fx.java:8: error: cannot find symbol
+import com.google.common.base.Optional;
+                             ^
+ui/web/src/main/java/ui/Fx.java:9: error: cannot find symbol
+import com.google.common.base.Strings;
+                             ^
+Target //:ui_web failed to build
+`
+
+// write files below dir, creating directories as needed
+func fixtureFiles(t *testing.T, dir string, files map[string]string) {
+	for f, content := range files {
+		p := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
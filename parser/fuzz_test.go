@@ -0,0 +1,25 @@
+package parser
+
+import (
+	"bufio"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func FuzzProblems(f *testing.F) {
+	f.Add(fixtureLog)
+	f.Add("==================== Test output for //a:b:\n" +
+		"Cannot find runfile: __main__/a.txt\n")
+	f.Add("error: cannot find symbol\n")
+	f.Add("package a does not exist\n")
+	f.Add("Building external/maven/v1/https/repo/guava.jar\n")
+	f.Add("Compiling Java headers external/x.jar\n")
+	f.Fuzz(func(t *testing.T, s string) {
+		log.SetOutput(ioutil.Discard)
+		defer log.SetOutput(os.Stderr)
+		Problems(*bufio.NewScanner(strings.NewReader(s)))
+	})
+}
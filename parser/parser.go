@@ -0,0 +1,453 @@
+// Package parser extracts build problems from Bazel build logs, such as
+// missing classes of javac, kotlinc, and scalac, or missing runfiles.
+package parser
+
+import (
+	"bufio"
+	"log"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+// BuildProblems found in a build log
+type BuildProblems struct {
+	BazelRule    string
+	MissingClass []index.JavaClass
+	// missing classes per failing rule, --keep_going builds fail many
+	ByRule         map[string][]index.JavaClass
+	MissingRunfile []Runfile
+	MissingMain    []MainClass
+	Invisible      []Visibility
+	MissingTarget  []MissingTarget
+	Unloadable     []Load
+	Truncated      bool        // javac stopped reporting errors
+	Localized      bool        // diagnostics in an unsupported language
+	Classpath      []string    // of the failing action, --verbose_failures
+	Sources        []string    // failing source files, relative to execroot
+	Suggested      []edit.Edit // bazel's own buildozer commands
+	// failing rules read from jar names, and the rules building a jar of
+	// the same name otherwise, see ProgressRule
+	Alternatives map[string]string
+}
+
+// Runfile is a data file a test could not find at runtime
+type Runfile struct {
+	Test string // test target, such as //ui/web:web_test
+	Path string // runfiles path, such as __main__/ui/web/testdata/a.txt
+	Line string // log line reporting the runfile
+}
+
+// MainClass is the main class a java_binary's run script could not load
+type MainClass struct {
+	Binary string // such as //app:server
+	Class  string // such as com.company.app.Server
+	Line   string // log line reporting the class
+}
+
+// Visibility is a dep a rule may not see
+type Visibility struct {
+	Target string // dep, such as //a:b
+	From   string // rule depending on it, such as //c:d
+	Line   string // log line reporting the error
+}
+
+// MissingTarget is a dep on a package or target that does not exist
+type MissingTarget struct {
+	Label   string // such as //a:b, or //a/b for a package
+	Package bool   // the whole package is missing
+	From    string // rule depending on it, if known
+	Line    string // log line reporting the error
+}
+
+// Load is a .bzl file a BUILD file cannot load
+type Load struct {
+	Label   string // such as //tools:defs.bzl
+	Package string // loading package, such as app
+	File    string // loading .bzl file, if not the BUILD file
+	Line    string // log line reporting the error
+}
+
+// labels of javac diagnostics in supported languages
+var knownLabels = map[string]bool{
+	"error": true, "warning": true,
+	"Fehler": true, "Warnung": true,
+	"エラー": true, "警告": true,
+	"错误": true,
+}
+
+// Problems of a build log
+func Problems(scanner bufio.Scanner) BuildProblems {
+	const (
+		Building  = "Building"
+		Compiling = "Compiling Java headers"
+		// kotlinc
+		Unresolved = "nresolved reference"
+		// scalac
+		NotFound = "error: not found: "
+		TestFor  = "Test output for "
+		// test output streamed, or of passing tests
+		FromTesting = "INFO: From Testing "
+		// javac default for -Xmaxerrs
+		MaxErrs = 100
+	)
+	var (
+		REImport       = regexp.MustCompile("import (.*);")
+		REImportStatic = regexp.MustCompile("import static (.*);")
+		RETestOutput   = regexp.MustCompile(
+			"(?:" + TestFor + "|" + FromTesting + ")(//[^ ]*):")
+		RENoRunfile = regexp.MustCompile(
+			"[Cc]annot find runfile:? *([^ ]+)")
+		// run scripts of java_binary, bazel run and tests
+		RENoMain = regexp.MustCompile(
+			`Could not find or load main class ([\w.$/]+)`)
+		RERunning = regexp.MustCompile(
+			`Running command line: (?:\S*/)?bazel-bin/(\S+)`)
+		// on one line, or, since bazel 6, target by target on lines of
+		// their own
+		RENotVisible = regexp.MustCompile(`target '([^']+)' is not ` +
+			`visible from(?: target '([^']+)')?`)
+		RETarget = regexp.MustCompile(`^\s*target '([^']+)'`)
+		// loading phase errors of bazel 5 and later, and before
+		RELoad = regexp.MustCompile(
+			`(?:cannot load|Unable to load file) '([^']+)'`)
+		RELoading = regexp.MustCompile(`error loading package '([^']*)'`)
+		REInBzl   = regexp.MustCompile(`in (\S+\.bzl): `)
+		// the depending rule follows on the same or the next line
+		RENoSuch = regexp.MustCompile(
+			`no such (package|target) '([^']+)'`)
+		REReferenced  = regexp.MustCompile(`referenced by '([^']+)'`)
+		REErrorCount  = regexp.MustCompile(`^(\d+) errors?$`)
+		REOnlyShowing = regexp.MustCompile(
+			"only showing the first \\d+ errors")
+		// javac diagnostics in English, German, Japanese, and Chinese
+		RESource = regexp.MustCompile(`^(\S+\.(?:java|kt|scala)):(\d+)` +
+			`(?::\d+)?: (?:error|Fehler|エラー|错误):`)
+		RENoPackage = regexp.MustCompile(`package (.*) does not exist|` +
+			`Package (\S+) ist nicht vorhanden|パッケージ(\S+)は存在しません|` +
+			`程序包(\S+)不存在`)
+		RENoSymbol = regexp.MustCompile(`(?:error|Fehler|エラー|错误): ` +
+			`(?:cannot find symbol|Symbol nicht gefunden|` +
+			`シンボルを見つけられません|找不到符号)`)
+		// diagnostics of any other language
+		REDiagnostic = regexp.MustCompile(
+			`^\S+\.java:\d+: ([^:]+?) ?: `)
+		// cannot find symbol, on separate lines or the error line
+		RESymbol       = regexp.MustCompile(`symbol:\s+class (\w+)`)
+		RELocation     = regexp.MustCompile(`location:\s+package ([\w.]+)`)
+		REKotlinImport = regexp.MustCompile(`^\s*import\s+([\w.]+)`)
+		RECannotAccess = regexp.MustCompile(
+			`[Cc]annot access class '([\w.$]+)'`)
+		// --verbose_failures
+		REExecroot  = regexp.MustCompile(`^\s*\(cd (\S+) &&`)
+		REParams    = regexp.MustCompile(`@(\S+\.params)`)
+		REBuildozer = regexp.MustCompile(
+			`^buildozer '([^']+)' '?([^'\s]+)'?$`)
+	)
+	var execroot string
+	var test string
+	// binary of bazel run
+	var binary string
+	var problems BuildProblems
+	// build scanner only knows about missing class names, no module etc.
+	location := ""
+	// log lines of the current error
+	var logged []string
+	add := func(classname string) {
+		j := index.JavaClass{Name: classname, Location: location,
+			Log: append([]string{}, logged...)}
+		problems.MissingClass = append(problems.MissingClass, j)
+		if problems.ByRule == nil {
+			problems.ByRule = make(map[string][]index.JavaClass)
+		}
+		// the most recent rule failed
+		problems.ByRule[problems.BazelRule] = append(
+			problems.ByRule[problems.BazelRule], j)
+	}
+	sources := make(map[string]bool)
+	source := func(line string) {
+		logged = []string{line}
+		location = ""
+		matches := RESource.FindStringSubmatch(line)
+		if len(matches) > 0 {
+			location = matches[1] + ":" + matches[2]
+		}
+		if len(matches) > 0 && !sources[matches[1]] {
+			sources[matches[1]] = true
+			problems.Sources = append(problems.Sources, matches[1])
+		}
+	}
+	// line read ahead by follow
+	var pending []string
+	next := func() (string, bool) {
+		if len(pending) > 0 {
+			line := pending[0]
+			pending = pending[1:]
+			return line, true
+		}
+		if !scanner.Scan() {
+			return "", false
+		}
+		return scanner.Text(), true
+	}
+	// next line of the current diagnostic, "" if the next one starts.
+	// Custom javac formatters put a diagnostic on a single line, so the
+	// following line may already be the next error.
+	follow := func() string {
+		line, ok := next()
+		if !ok {
+			return ""
+		}
+		if RESource.MatchString(line) || REErrorCount.MatchString(line) ||
+			strings.HasPrefix(line, "ERROR: ") ||
+			strings.HasPrefix(line, "INFO: ") ||
+			strings.HasPrefix(line, "Target ") {
+			pending = append(pending, line)
+			return ""
+		}
+		logged = append(logged, line)
+		return line
+	}
+	// classes of an import statement
+	imported := func(line string) bool {
+		if matches := REImportStatic.FindStringSubmatch(line); len(matches) > 0 {
+			// Convert Java member to class
+			add(index.StripLast(matches[1]))
+			return true
+		}
+		if matches := REImport.FindStringSubmatch(line); len(matches) > 0 {
+			add(matches[1])
+			return true
+		}
+		return false
+	}
+	for {
+		line, ok := next()
+		if !ok {
+			break
+		}
+		// Easiest: bazels own suggestions
+		if strings.HasPrefix(line, "buildozer ") {
+			matches := REBuildozer.FindStringSubmatch(line)
+			if len(matches) == 0 {
+				log.Printf("ignoring malformed %s\n", line)
+				continue
+			}
+			// strict deps errors suggest one command each
+			problems.Suggested = append(problems.Suggested,
+				edit.Edit{Command: matches[1], Target: matches[2]})
+		} else if strings.Contains(line, TestFor) ||
+			strings.HasPrefix(line, FromTesting) {
+			matches := RETestOutput.FindStringSubmatch(line)
+			if len(matches) > 0 {
+				test = matches[1]
+			}
+		} else if matches := RENoRunfile.FindStringSubmatch(line); len(matches) > 0 {
+			problems.MissingRunfile = append(
+				problems.MissingRunfile,
+				Runfile{test, matches[1], line})
+		} else if matches := RERunning.FindStringSubmatch(line); len(matches) > 0 {
+			dir, name := filepath.Split(matches[1])
+			binary = "//" + strings.TrimSuffix(dir, "/") + ":" + name
+		} else if matches := RENoMain.FindStringSubmatch(line); len(matches) > 0 {
+			m := MainClass{Binary: binary, Line: line,
+				Class: strings.Replace(matches[1], "/", ".", -1)}
+			if test != "" {
+				m.Binary = test
+			}
+			if m.Binary == "" {
+				m.Binary = problems.BazelRule
+			}
+			problems.MissingMain = append(problems.MissingMain, m)
+		} else if matches := RENotVisible.FindStringSubmatch(line); len(matches) > 0 {
+			v := Visibility{Target: matches[1], From: matches[2],
+				Line: line}
+			if v.From == "" {
+				m := RETarget.FindStringSubmatch(follow())
+				if len(m) == 0 {
+					continue
+				}
+				v.From = m[1]
+			}
+			problems.Invisible = append(problems.Invisible, v)
+		} else if matches := RELoad.FindStringSubmatch(line); len(matches) > 0 {
+			l := Load{Label: matches[1], Line: line}
+			pkg := RELoading.FindStringSubmatch(line)
+			if len(pkg) == 0 {
+				log.Printf("cannot attribute load of %s to a "+
+					"package\n", l.Label)
+				continue
+			}
+			l.Package = pkg[1]
+			if in := REInBzl.FindStringSubmatch(line); len(in) > 0 {
+				l.File = in[1]
+			}
+			known := false
+			for _, k := range problems.Unloadable {
+				known = known || k.Label == l.Label &&
+					k.Package == l.Package
+			}
+			if !known {
+				problems.Unloadable = append(problems.Unloadable, l)
+			}
+		} else if matches := RENoSuch.FindStringSubmatch(line); len(matches) > 0 {
+			m := MissingTarget{Label: matches[2],
+				Package: matches[1] == "package", Line: line}
+			if m.Package && !strings.Contains(m.Label, "//") {
+				m.Label = "//" + m.Label
+			}
+			ref := REReferenced.FindStringSubmatch(line)
+			if len(ref) == 0 {
+				l := follow()
+				ref = REReferenced.FindStringSubmatch(l)
+				if len(ref) == 0 && l != "" {
+					pending = append(pending, l)
+				}
+			}
+			if len(ref) > 0 {
+				m.From = ref[1]
+			}
+			// aborted analysis repeats the error
+			known := false
+			for _, k := range problems.MissingTarget {
+				known = known || k.Label == m.Label &&
+					(k.From == m.From || m.From == "")
+			}
+			if !known {
+				problems.MissingTarget = append(
+					problems.MissingTarget, m)
+			}
+		} else if matches := REErrorCount.FindStringSubmatch(line); len(matches) > 0 {
+			if n, _ := strconv.Atoi(matches[1]); n >= MaxErrs {
+				problems.Truncated = true
+			}
+		} else if REOnlyShowing.MatchString(line) {
+			problems.Truncated = true
+		} else if matches := REExecroot.FindStringSubmatch(line); len(matches) > 0 {
+			execroot = matches[1]
+		} else if matches := REParams.FindStringSubmatch(line); len(matches) > 0 && execroot != "" {
+			args := ReadParams(filepath.Join(execroot, matches[1]))
+			problems.Classpath = ParseClasspath(args)
+			log.Printf("failing action has %d classpath entries\n",
+				len(problems.Classpath))
+		} else if rule, alt, version, ok := ProgressRule(line); ok {
+			log.Printf("using rule %s (bazel %s format)\n", rule,
+				version)
+			problems.BazelRule = rule
+			if alt != "" {
+				log.Printf("or %s, if %s does not exist\n", alt,
+					rule)
+				if problems.Alternatives == nil {
+					problems.Alternatives =
+						make(map[string]string)
+				}
+				problems.Alternatives[rule] = alt
+			}
+		} else if strings.Contains(line, Building) ||
+			strings.Contains(line, Compiling) {
+			// such as Building external/...
+			log.Printf("warning: expected rule but got %s\n", line)
+		} else if RENoPackage.MatchString(line) {
+			source(line)
+			// import on the error line, or the next one
+			if !imported(line) {
+				imported(follow())
+			}
+		} else if RENoSymbol.MatchString(line) {
+			source(line)
+			// import, or symbol and location of a qualified usage:
+			// error, source, caret, symbol, and location lines
+			var class, pkg string
+			for i := 0; i < 5 && line != ""; i++ {
+				if i > 0 {
+					line = follow()
+				}
+				if imported(line) {
+					break
+				}
+				if matches := RESymbol.FindStringSubmatch(line); len(matches) > 0 {
+					class = matches[1]
+				}
+				if matches := RELocation.FindStringSubmatch(line); len(matches) > 0 {
+					pkg = matches[1]
+				}
+				if class != "" && pkg != "" {
+					add(pkg + "." + class)
+					break
+				}
+			}
+		} else if strings.Contains(line, Unresolved) {
+			source(line)
+			// only imports name the class, not usages
+			matches := REKotlinImport.FindStringSubmatch(follow())
+			if len(matches) > 0 {
+				add(matches[1])
+			}
+		} else if strings.Contains(line, NotFound) {
+			source(line)
+			// object, value, or type only name the first segment
+			for _, c := range ScalaImports(follow()) {
+				add(c)
+			}
+		} else if matches := RECannotAccess.FindStringSubmatch(line); len(matches) > 0 {
+			source(line)
+			add(matches[1])
+		} else if matches := REDiagnostic.FindStringSubmatch(line); len(matches) > 0 && !knownLabels[matches[1]] {
+			problems.Localized = true
+		}
+	}
+	return problems
+}
+
+// Rules splits problems of many failing rules into problems per rule, in
+// order of their labels. The classpath belongs to a single action and is
+// dropped, runfiles, main classes, visibility errors, missing targets, load
+// errors, and bazel's own fixes stay with the first rule.
+func (a BuildProblems) Rules() []BuildProblems {
+	if len(a.ByRule) < 2 {
+		return []BuildProblems{a}
+	}
+	var rules []string
+	for r := range a.ByRule {
+		rules = append(rules, r)
+	}
+	sort.Strings(rules)
+	var ps []BuildProblems
+	for i, r := range rules {
+		p := BuildProblems{
+			BazelRule:    r,
+			MissingClass: a.ByRule[r],
+			ByRule:       map[string][]index.JavaClass{r: a.ByRule[r]},
+			Truncated:    a.Truncated,
+		}
+		if alt, ok := a.Alternatives[r]; ok {
+			p.Alternatives = map[string]string{r: alt}
+		}
+		files := make(map[string]bool)
+		for _, j := range p.MissingClass {
+			if i := strings.LastIndex(j.Location, ":"); i > 0 {
+				files[j.Location[:i]] = true
+			}
+		}
+		for _, s := range a.Sources {
+			if files[s] {
+				p.Sources = append(p.Sources, s)
+			}
+		}
+		if i == 0 {
+			p.MissingRunfile = a.MissingRunfile
+			p.MissingMain = a.MissingMain
+			p.Invisible = a.Invisible
+			p.MissingTarget = a.MissingTarget
+			p.Unloadable = a.Unloadable
+			p.Suggested = a.Suggested
+		}
+		ps = append(ps, p)
+	}
+	return ps
+}
@@ -0,0 +1,182 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestProblems(t *testing.T) {
+	probs := Problems(*bufio.NewScanner(strings.NewReader(fixtureLog)))
+	if len(probs.BazelRule) == 0 {
+		log.Fatalf("expected bazel rule but found nothing")
+	}
+	want := 7
+	got := len(probs.MissingClass)
+	if want != got {
+		log.Fatalf("want %d but got %d\n", want, got)
+	}
+	for _, m := range probs.MissingClass {
+		log.Printf("%+v\n", m)
+	}
+}
+
+func TestProblemsUnexpectedRule(t *testing.T) {
+	lines := "Building external/maven/guava.jar (1 file)\n" +
+		fixtureLog +
+		"Building ui/web/web_deploy.jar (1 file)\n" +
+		"error: cannot find symbol\n" +
+		"import org.a.A;\n"
+	probs := Problems(*bufio.NewScanner(strings.NewReader(lines)))
+	want := "ui_web"
+	if want != probs.BazelRule {
+		t.Fatalf("want %s but got %s\n", want, probs.BazelRule)
+	}
+	if len(probs.MissingClass) != 8 {
+		t.Fatalf("want 8 missing classes but got %d\n",
+			len(probs.MissingClass))
+	}
+}
+
+func TestProblemsTruncated(t *testing.T) {
+	for _, tt := range []struct {
+		lines string
+		want  bool
+	}{
+		{fixtureLog + "7 errors\n", false},
+		{fixtureLog + "100 errors\n", true},
+		{fixtureLog + "only showing the first 100 errors, of 212 " +
+			"total; use -Xmaxerrs if you would like to see more\n" +
+			"100 errors\n", true},
+	} {
+		probs := Problems(*bufio.NewScanner(strings.NewReader(tt.lines)))
+		if tt.want != probs.Truncated {
+			t.Fatalf("want truncated %v but got %v\n", tt.want,
+				probs.Truncated)
+		}
+	}
+}
+
+func TestProblemsSources(t *testing.T) {
+	ps := Problems(*bufio.NewScanner(strings.NewReader(fixtureLog)))
+	want := "ui/web/src/main/java/ui/Fx.java"
+	if len(ps.Sources) != 1 || ps.Sources[0] != want {
+		t.Fatalf("want %s but got %q\n", want, ps.Sources)
+	}
+}
+
+func TestProblemsLocation(t *testing.T) {
+	ps := Problems(*bufio.NewScanner(strings.NewReader(fixtureLog)))
+	want := "ui/web/src/main/java/ui/Fx.java:3"
+	if len(ps.MissingClass) == 0 || ps.MissingClass[0].Location != want {
+		t.Fatalf("want %s but got %+v\n", want, ps.MissingClass)
+	}
+	// error and offending line
+	want = "import org.company.framework.A;"
+	log := ps.MissingClass[0].Log
+	if len(log) != 2 || !strings.Contains(log[1], want) {
+		t.Fatalf("want error and %s but got %q\n", want, log)
+	}
+}
+
+// custom javac formatters report a diagnostic on a single line
+func TestProblemsLayouts(t *testing.T) {
+	want := "[org.company.framework.A org.junit.Assert " +
+		"org.company.util.Strings com.google.common.collect.Lists]"
+	for _, filename := range []string{
+		"testdata/javac-multi-line.log",
+		"testdata/javac-single-line.log",
+	} {
+		f, err := os.Open(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ps := Problems(*bufio.NewScanner(f))
+		f.Close()
+		var names []string
+		for _, j := range ps.MissingClass {
+			names = append(names, j.Name)
+		}
+		if got := fmt.Sprint(names); want != got {
+			t.Fatalf("%s: want %s but got %s\n", filename, want, got)
+		}
+		if ps.BazelRule != "//ui/web:web" {
+			t.Fatalf("%s: want rule //ui/web:web but got %s\n",
+				filename, ps.BazelRule)
+		}
+	}
+}
+
+// --keep_going interleaves errors of many rules
+func TestProblemsKeepGoing(t *testing.T) {
+	lines := fixtureLog +
+		"ERROR: /ws/b/BUILD:1:1: Building b/libb.jar (1 source file) " +
+		"failed\n" +
+		"b/src/main/java/b/B.java:2: error: package org.b does not exist\n" +
+		"import org.b.B;\n"
+	ps := Problems(*bufio.NewScanner(strings.NewReader(lines)))
+	if len(ps.ByRule) != 2 || len(ps.ByRule["//b:b"]) != 1 {
+		t.Fatalf("want 1 missing class of //b:b but got %+v\n",
+			ps.ByRule)
+	}
+	rs := ps.Rules()
+	if len(rs) != 2 {
+		t.Fatalf("want problems of 2 rules but got %+v\n", rs)
+	}
+	want := "b/src/main/java/b/B.java"
+	if rs[0].BazelRule != "//b:b" || len(rs[0].Sources) != 1 ||
+		rs[0].Sources[0] != want {
+		t.Fatalf("want //b:b failing in %s but got %+v\n", want, rs[0])
+	}
+	if len(rs[1].MissingClass) != 7 {
+		t.Fatalf("want 7 missing classes of %s but got %+v\n",
+			rs[1].BazelRule, rs[1].MissingClass)
+	}
+}
+
+func TestProblemsLocales(t *testing.T) {
+	for _, tt := range []struct {
+		lines     string
+		want      string
+		localized bool
+	}{
+		{"Fx.java:3: Fehler: Package org.a ist nicht vorhanden\n" +
+			"import org.a.A;\n", "org.a.A", false},
+		{"Fx.java:3: エラー: パッケージorg.aは存在しません\n" +
+			"import org.a.A;\n", "org.a.A", false},
+		{"Fx.java:3: 错误: 程序包org.a不存在\n" +
+			"import org.a.A;\n", "org.a.A", false},
+		{"Fx.java:3: Fehler: Symbol nicht gefunden\n" +
+			"import org.a.A;\n", "org.a.A", false},
+		{"Fx.java:3: erreur : le package org.a n'existe pas\n" +
+			"import org.a.A;\n", "", true},
+	} {
+		ps := Problems(*bufio.NewScanner(strings.NewReader(tt.lines)))
+		var got string
+		if len(ps.MissingClass) > 0 {
+			got = ps.MissingClass[0].Name
+		}
+		if tt.want != got || tt.localized != ps.Localized {
+			t.Fatalf("%q: want %q (localized %v) but got %+v\n",
+				tt.lines, tt.want, tt.localized, ps)
+		}
+	}
+}
+
+// bazel suggests a command per strict deps error, javac errors may follow
+func TestProblemsSuggested(t *testing.T) {
+	lines := "buildozer 'add deps //:a' //ui/web:web\n" +
+		"buildozer 'add deps //:b' //ui/web:web\n" + fixtureLog
+	ps := Problems(*bufio.NewScanner(strings.NewReader(lines)))
+	if len(ps.Suggested) != 2 {
+		t.Fatalf("want 2 suggested commands but got %+v\n",
+			ps.Suggested)
+	}
+	if len(ps.MissingClass) != 7 {
+		t.Fatalf("want 7 missing classes but got %d\n",
+			len(ps.MissingClass))
+	}
+}
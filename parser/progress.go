@@ -0,0 +1,81 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ProgressPattern recognizes the rule of a Java compile action in progress
+// and error lines of a Bazel version. Submatches are package and rule name,
+// or package and jar name if Jar is set.
+type ProgressPattern struct {
+	Version string
+	RE      *regexp.Regexp
+	Jar     bool
+}
+
+var progressPatterns = []ProgressPattern{
+	// ERROR: /ws/ui/web/BUILD:9:10: Building ui/web/web_test.jar (1
+	// source file) failed: (Exit 1): java failed: error executing Javac
+	// command (from target //ui/web:web_test) ...
+	{"7", regexp.MustCompile(`\(from target //(\S*):([^\s)]+)\)`), false},
+	// [1,234 / 5,678] Javac ui/web/libweb.jar; 3s worker
+	// Running tests or coverage, actions run next to each other:
+	// [1,234 / 5,678] 3 actions running
+	//     Javac ui/web/web_test.jar; 3s worker
+	//     Testing //ui/web:web_test; 2s linux-sandbox
+	{"7", regexp.MustCompile(`^(?:\[[\d,]+ / [\d,]+\] |\s+)` +
+		`(?:Building|Javac|JavaCompile) ` +
+		`(?:(\S*)/)?(\S+?)\.jar[ ;]`), true},
+	// [1,234 / 5,678] Turbine ui/web/libweb-hjar.jar; 1s linux-sandbox
+	{"7", regexp.MustCompile(`^\[[\d,]+ / [\d,]+\] ` +
+		`(?:Compiling Java headers|Turbine) ` +
+		`(?:(\S*)/)?lib(\S*?)-hjar\.jar[ ;]`), false},
+	// ERROR: /ws/ui/web/BUILD:3:13: Building ui/web/libweb.jar (1 source
+	// file) failed: (Exit 1). Binaries and tests build name.jar.
+	{"6", regexp.MustCompile(`Building ` +
+		`(?:(\S*)/)?(\S+?)\.jar[ ;]`), true},
+	{"6", regexp.MustCompile(`Compiling Java headers ` +
+		`(?:(\S*)/)?lib(\S*?)-hjar\.jar[ ;]`), false},
+	// ERROR: /ws/ui/web/BUILD:3:14: Compiling Kotlin to JVM //ui/web:web
+	// { kt: 1, java: 0, srcjars: 0 } for k8-fastbuild failed: (Exit 1)
+	{"rules_kotlin", regexp.MustCompile(`Compiling Kotlin to JVM ` +
+		`//(\S*):(\S+) `), false},
+	// ERROR: /ws/ui/web/BUILD:3:14: scala //ui/web:web failed: (Exit 1)
+	{"rules_scala", regexp.MustCompile(`: scala //(\S*):(\S+) `),
+		false},
+}
+
+// ProgressRule is the rule compiled according to a progress or error line.
+// Rules of the root package are returned by name, all others by label. Jars
+// of external repositories belong to no rule of the workspace, deploy jars
+// compile nothing. Libraries build lib<name>.jar, binaries and tests
+// <name>.jar, so a jar named lib<name>.jar is read as library, and alt is the
+// test or binary of the full name building the same jar. Only bazel knows
+// which of the two exists.
+func ProgressRule(line string) (rule string, alt string, version string,
+	ok bool) {
+	for _, p := range progressPatterns {
+		matches := p.RE.FindStringSubmatch(line)
+		if len(matches) == 0 || matches[1] == "external" ||
+			strings.HasPrefix(matches[1], "external/") ||
+			strings.HasSuffix(matches[2], "_deploy") {
+			continue
+		}
+		name, other := matches[2], ""
+		if p.Jar {
+			if n := strings.TrimPrefix(name, "lib"); n != name &&
+				n != "" {
+				name, other = n, name
+			}
+		}
+		label := func(name string) string {
+			if name == "" || matches[1] == "" {
+				return name
+			}
+			return "//" + matches[1] + ":" + name
+		}
+		return label(name), label(other), p.Version, true
+	}
+	return "", "", "", false
+}
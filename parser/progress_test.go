@@ -0,0 +1,144 @@
+package parser
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestProgressRule(t *testing.T) {
+	for _, tt := range []struct {
+		line, rule, alt, version string
+	}{
+		{"ERROR: /ws/BUILD:1:1: Building libui_web.jar (3 source files)",
+			"ui_web", "libui_web", "6"},
+		{"ERROR: /ws/a/BUILD:1:1: Compiling Java headers " +
+			"a/liba-hjar.jar (1 source file) failed", "//a:a", "",
+			"6"},
+		{"[3 / 4] Javac ui/web/libweb.jar; 1s worker", "//ui/web:web",
+			"//ui/web:libweb", "7"},
+		{"[1,234 / 5,678] Turbine a/b/libb-hjar.jar; 0s linux-sandbox",
+			"//a/b:b", "", "7"},
+		{"    Javac ui/web/web_test.jar; 3s worker",
+			"//ui/web:web_test", "", "7"},
+		{"ERROR: /ws/a/BUILD:1:1: Building a/a_test.jar (1 source " +
+			"file) failed: (Exit 1): java failed: error executing " +
+			"Javac command (from target //a:a_test) java", "//a:a_test",
+			"", "7"},
+		{"ERROR: /ws/a/BUILD:1:1: Building a/liba_test.jar (1 source " +
+			"file) failed: (Exit 1): java failed: error executing " +
+			"Javac command (from target //a:liba_test) java",
+			"//a:liba_test", "", "7"},
+		{"ERROR: /ws/a/BUILD:1:1: Building a/a_test.jar (1 source " +
+			"file) failed: (Exit 1)", "//a:a_test", "", "6"},
+		{"    Javac a/library_test.jar; 3s worker", "//a:rary_test",
+			"//a:library_test", "7"},
+		{"    Javac a/lib.jar; 3s worker", "//a:lib", "", "7"},
+		{"ERROR: /ws/a/BUILD:1:1: Building a/liblibrary.jar (1 " +
+			"source file) failed: (Exit 1)", "//a:library",
+			"//a:liblibrary", "6"},
+		// testonly library fixtures_tests, or test libfixtures_tests
+		{"[3 / 4] Javac ui/web/libfixtures_tests.jar; 1s worker",
+			"//ui/web:fixtures_tests", "//ui/web:libfixtures_tests",
+			"7"},
+	} {
+		rule, alt, version, ok := ProgressRule(tt.line)
+		if !ok || rule != tt.rule || alt != tt.alt ||
+			version != tt.version {
+			t.Fatalf("want %s or %s (bazel %s) but got %s or %s "+
+				"(bazel %s)\n", tt.rule, tt.alt, tt.version, rule,
+				alt, version)
+		}
+	}
+	if _, _, _, ok := ProgressRule("Building external/x/guava.jar"); ok {
+		t.Fatalf("want no rule for external jar\n")
+	}
+}
+
+func TestProblemsAlternatives(t *testing.T) {
+	log := "[3 / 4] Javac ui/web/libfixtures_tests.jar; 1s worker\n" +
+		"ui/web/F.java:1: error: package org.a does not exist\n" +
+		"import org.a.A;\n"
+	probs := Problems(*bufio.NewScanner(strings.NewReader(log)))
+	want := "//ui/web:fixtures_tests"
+	if want != probs.BazelRule {
+		t.Fatalf("want %s but got %s\n", want, probs.BazelRule)
+	}
+	alt := "//ui/web:libfixtures_tests"
+	if probs.Alternatives[want] != alt {
+		t.Fatalf("want %s but got %+v\n", alt, probs.Alternatives)
+	}
+}
+
+func TestProblemsBazelVersions(t *testing.T) {
+	for _, filename := range []string{
+		"testdata/bazel-6.log",
+		"testdata/bazel-7.log",
+	} {
+		f, err := os.Open(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		probs := Problems(*bufio.NewScanner(f))
+		f.Close()
+		want := "//ui/web:web"
+		if want != probs.BazelRule {
+			t.Fatalf("%s: want %s but got %s\n", filename, want,
+				probs.BazelRule)
+		}
+		if len(probs.MissingClass) != 2 {
+			t.Fatalf("%s: want 2 missing classes but got %+v\n",
+				filename, probs.MissingClass)
+		}
+	}
+}
+
+func TestProblemsTestCommands(t *testing.T) {
+	for _, filename := range []string{
+		"testdata/bazel-test.log",
+		"testdata/bazel-6-coverage.log",
+	} {
+		f, err := os.Open(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		probs := Problems(*bufio.NewScanner(f))
+		f.Close()
+		want := "//ui/web:web_test"
+		if want != probs.BazelRule {
+			t.Fatalf("%s: want %s but got %s\n", filename, want,
+				probs.BazelRule)
+		}
+		if len(probs.ByRule[want]) != 2 || len(probs.ByRule) != 1 {
+			t.Fatalf("%s: want 2 missing classes of %s but got %+v\n",
+				filename, want, probs.ByRule)
+		}
+	}
+}
+
+func TestProblemsKotlin(t *testing.T) {
+	lines := `ERROR: /ws/ui/web/BUILD:3:14: Compiling Kotlin to JVM //ui/web:web { kt: 1, java: 0, srcjars: 0 } for k8-fastbuild failed: (Exit 1)
+ui/web/src/main/kotlin/ui/Fx.kt:3:30: error: unresolved reference: framework
+import org.company.framework.A
+                             ^
+ui/web/src/main/kotlin/ui/Fx.kt:9:5: error: unresolved reference: render
+    render(a)
+    ^
+ui/web/src/main/kotlin/ui/Fx.kt:12:5: error: cannot access class 'org.company.util.Strings'. Check your module classpath for missing or conflicting dependencies
+`
+	probs := Problems(*bufio.NewScanner(strings.NewReader(lines)))
+	want := "//ui/web:web"
+	if want != probs.BazelRule {
+		t.Fatalf("want %s but got %s\n", want, probs.BazelRule)
+	}
+	if len(probs.MissingClass) != 2 ||
+		probs.MissingClass[0].Name != "org.company.framework.A" ||
+		probs.MissingClass[1].Name != "org.company.util.Strings" {
+		t.Fatalf("want 2 missing classes but got %+v\n",
+			probs.MissingClass)
+	}
+	if len(probs.Sources) != 1 {
+		t.Fatalf("want 1 failing source but got %q\n", probs.Sources)
+	}
+}
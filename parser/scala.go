@@ -0,0 +1,37 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// REScalaImport matches import a.b.C, import a.b.{C, D => E}
+var REScalaImport = regexp.MustCompile(
+	`^\s*import\s+([\w.]+?)(?:\.\{([^}]*)\})?\s*$`)
+
+// ScalaImports are the classes of a Scala import, wildcards and renames
+// resolve to their package and original name
+func ScalaImports(line string) []string {
+	m := REScalaImport.FindStringSubmatch(line)
+	if m == nil {
+		return nil
+	}
+	if m[2] == "" {
+		if strings.HasSuffix(m[1], "._") {
+			return nil
+		}
+		return []string{m[1]}
+	}
+	var cs []string
+	for _, s := range strings.Split(m[2], ",") {
+		s = strings.TrimSpace(s)
+		if i := strings.Index(s, "=>"); i >= 0 {
+			s = strings.TrimSpace(s[:i])
+		}
+		if s == "" || s == "_" {
+			continue
+		}
+		cs = append(cs, m[1]+"."+s)
+	}
+	return cs
+}
@@ -0,0 +1,56 @@
+package parser
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestScalaImports(t *testing.T) {
+	for _, tt := range []struct {
+		line string
+		want string
+	}{
+		{"import org.a.A", "org.a.A"},
+		{"import org.a.{A, B => C, _}", "org.a.A org.a.B"},
+		{"import org.a._", ""},
+		{"val a = new A", ""},
+	} {
+		got := strings.Join(ScalaImports(tt.line), " ")
+		if tt.want != got {
+			t.Fatalf("%s: want %s but got %s\n", tt.line, tt.want,
+				got)
+		}
+	}
+}
+
+func TestProblemsScala(t *testing.T) {
+	lines := `ERROR: /ws/ui/web/BUILD:3:14: scala //ui/web:web failed: (Exit 1)
+ui/web/src/main/scala/ui/Fx.scala:3: error: not found: object framework
+import org.company.framework.{A, B}
+       ^
+ui/web/src/main/scala/ui/Fx.scala:9: error: not found: value render
+    render(a)
+    ^
+ui/web/src/main/scala/ui/Fx.scala:4: error: not found: type Strings
+import org.company.util.Strings
+                        ^
+`
+	probs := Problems(*bufio.NewScanner(strings.NewReader(lines)))
+	want := "//ui/web:web"
+	if want != probs.BazelRule {
+		t.Fatalf("want %s but got %s\n", want, probs.BazelRule)
+	}
+	var got []string
+	for _, c := range probs.MissingClass {
+		got = append(got, c.Name)
+	}
+	want = "org.company.framework.A org.company.framework.B " +
+		"org.company.util.Strings"
+	if want != strings.Join(got, " ") {
+		t.Fatalf("want %s but got %s\n", want, got)
+	}
+	if len(probs.Sources) != 1 {
+		t.Fatalf("want 1 failing source but got %q\n", probs.Sources)
+	}
+}
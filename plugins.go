@@ -0,0 +1,46 @@
+package main
+
+import (
+	"log"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+)
+
+func addsPlugins(edits []edit.Edit, rule string) bool {
+	for _, e := range edits {
+		if e.Target == rule && strings.HasPrefix(e.Command, "add plugins ") {
+			return true
+		}
+	}
+	return false
+}
+
+// drop plugins a rule runs already, registering a processor twice fails the
+// build
+func dedupePlugins(edits []edit.Edit, rule string,
+	running map[string]bool) []edit.Edit {
+	var es []edit.Edit
+	for _, e := range edits {
+		fs := strings.Fields(e.Command)
+		if e.Target != rule || len(fs) < 3 || fs[0] != "add" ||
+			fs[1] != "plugins" {
+			es = append(es, e)
+			continue
+		}
+		var plugins []string
+		for _, p := range fs[2:] {
+			if running[p] {
+				log.Printf("plugin %s is already a plugin of %s, "+
+					"or exported by a dep\n", p, rule)
+				continue
+			}
+			plugins = append(plugins, p)
+		}
+		if len(plugins) > 0 {
+			e.Command = "add plugins " + strings.Join(plugins, " ")
+			es = append(es, e)
+		}
+	}
+	return es
+}
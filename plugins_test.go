@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/bazel"
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+)
+
+func TestDedupePlugins(t *testing.T) {
+	edits := []edit.Edit{
+		{Command: "add deps //:autovalue", Target: "//a:a"},
+		{Command: "add plugins //:autovalue_plugin //:lombok_plugin",
+			Target: "//a:a"},
+		{Command: "add plugins //:autovalue_plugin", Target: "//b:b"},
+	}
+	if !addsPlugins(edits, "//a:a") || addsPlugins(edits, "//c:c") {
+		t.Fatalf("want plugins added to //a:a only\n")
+	}
+	exported := map[string]bool{"//:autovalue_plugin": true}
+	got := dedupePlugins(edits, "//a:a", exported)
+	want := []edit.Edit{
+		{Command: "add deps //:autovalue", Target: "//a:a"},
+		{Command: "add plugins //:lombok_plugin", Target: "//a:a"},
+		{Command: "add plugins //:autovalue_plugin", Target: "//b:b"},
+	}
+	if len(want) != len(got) {
+		t.Fatalf("want %+v but got %+v\n", want, got)
+	}
+	for i := range want {
+		if want[i] != got[i] {
+			t.Fatalf("want %s but got %s\n", want[i], got[i])
+		}
+	}
+}
+
+func TestRulePlugins(t *testing.T) {
+	fakeTools(t, `case "$*" in
+*"labels(plugins, //a:a) + labels(exported_plugins, deps(//a:a, 1))"*)
+	echo //:lombok_plugin; echo //:autovalue_plugin;;
+esac
+exit 0
+`, "exit 0\n")
+	running := bazel.RulePlugins("//a:a", t.TempDir())
+	got := dedupePlugins([]edit.Edit{{
+		Command: "add plugins //:autovalue_plugin //:lombok_plugin",
+		Target:  "//a:a"}}, "//a:a", running)
+	if len(got) != 0 {
+		t.Fatalf("want no plugins added but got %+v\n", got)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/bazel"
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+// ProtoFile is the part of a .proto file that decides which Java classes it
+// generates, and which other .proto files it needs
+type ProtoFile struct {
+	Path        string // relative to the workspace
+	Package     string
+	JavaPackage string
+	Imports     []string
+}
+
+// Java package of the generated classes, java_package if set, else the proto
+// package
+func (a ProtoFile) Java() string {
+	if a.JavaPackage != "" {
+		return a.JavaPackage
+	}
+	return a.Package
+}
+
+func parseProto(path string, r io.Reader) ProtoFile {
+	var (
+		REPackage     = regexp.MustCompile(`^\s*package\s+([\w.]+)\s*;`)
+		REJavaPackage = regexp.MustCompile(
+			`^\s*option\s+java_package\s*=\s*"([\w.]+)"\s*;`)
+		REImport = regexp.MustCompile(
+			`^\s*import\s+(?:public\s+|weak\s+)?"([^"]+)"\s*;`)
+	)
+	p := ProtoFile{Path: filepath.ToSlash(path)}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := REPackage.FindStringSubmatch(line); m != nil {
+			p.Package = m[1]
+		} else if m := REJavaPackage.FindStringSubmatch(line); m != nil {
+			p.JavaPackage = m[1]
+		} else if m := REImport.FindStringSubmatch(line); m != nil {
+			p.Imports = append(p.Imports, m[1])
+		}
+	}
+	return p
+}
+
+// all .proto files of a workspace
+func protoFiles(workspace string) []ProtoFile {
+	var protos []ProtoFile
+	for _, f := range index.Scan(workspace, ".proto") {
+		rel, err := filepath.Rel(workspace, f)
+		if err != nil || strings.HasPrefix(rel, "bazel-") {
+			continue
+		}
+		r, err := os.Open(f)
+		if err != nil {
+			log.Printf("skip %s: %v\n", f, err)
+			continue
+		}
+		protos = append(protos, parseProto(rel, r))
+		r.Close()
+	}
+	return protos
+}
+
+// .proto files generating classes of a Java package
+func protoProviders(protos []ProtoFile, javaPackage string) []ProtoFile {
+	var ps []ProtoFile
+	for _, p := range protos {
+		if p.Java() == javaPackage {
+			ps = append(ps, p)
+		}
+	}
+	return ps
+}
+
+// .proto files importing another one
+func protoImporters(protos []ProtoFile, path string) []ProtoFile {
+	var ps []ProtoFile
+	for _, p := range protos {
+		for _, i := range p.Imports {
+			if i == path {
+				ps = append(ps, p)
+				break
+			}
+		}
+	}
+	return ps
+}
+
+// edits healing a class generated from a .proto file: the Java rule needs
+// the java_proto_library, and proto_library rules of the rule's own .proto
+// files importing the provider need its proto_library.
+func healProto(rule string, javaPackage string, protos []ProtoFile,
+	workdir string) []edit.Edit {
+	var edits []edit.Edit
+	for _, p := range protoProviders(protos, javaPackage) {
+		lib := bazel.ProtoLibrary(p.Path, workdir)
+		if lib == "" {
+			log.Printf("no proto_library for %s\n", p.Path)
+			continue
+		}
+		if jpl := bazel.JavaProtoLibrary(lib, workdir); jpl != "" {
+			edits = append(edits, edit.AddDeps(rule, jpl))
+		}
+		for _, i := range protoImporters(protos, p.Path) {
+			importer := bazel.ProtoLibrary(i.Path, workdir)
+			if importer == "" || importer == lib {
+				continue
+			}
+			if !bazel.DependsOn(importer, lib, workdir) {
+				edits = append(edits, edit.AddDeps(importer, lib))
+			}
+		}
+	}
+	return edits
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseProto(t *testing.T) {
+	src := `syntax = "proto3";
+
+package billing.v1;
+
+import "google/protobuf/timestamp.proto";
+import public "common/money.proto";
+
+option java_package = "com.company.billing.v1";
+option java_multiple_files = true;
+
+message Invoice {}
+`
+	p := parseProto("billing/invoice.proto", strings.NewReader(src))
+	if p.Package != "billing.v1" {
+		t.Fatalf("want package billing.v1 but got %s\n", p.Package)
+	}
+	want := "com.company.billing.v1"
+	if want != p.Java() {
+		t.Fatalf("want %s but got %s\n", want, p.Java())
+	}
+	if len(p.Imports) != 2 || p.Imports[1] != "common/money.proto" {
+		t.Fatalf("want 2 imports but got %+v\n", p.Imports)
+	}
+}
+
+func TestProtoProviders(t *testing.T) {
+	protos := []ProtoFile{
+		{Path: "common/money.proto", Package: "common",
+			JavaPackage: "com.company.common"},
+		{Path: "billing/invoice.proto", Package: "billing",
+			Imports: []string{"common/money.proto"}},
+	}
+	ps := protoProviders(protos, "com.company.common")
+	if len(ps) != 1 || ps[0].Path != "common/money.proto" {
+		t.Fatalf("want common/money.proto but got %+v\n", ps)
+	}
+	if ps := protoProviders(protos, "billing"); len(ps) != 1 {
+		t.Fatalf("want proto package as Java package but got %+v\n",
+			ps)
+	}
+	is := protoImporters(protos, "common/money.proto")
+	if len(is) != 1 || is[0].Path != "billing/invoice.proto" {
+		t.Fatalf("want billing/invoice.proto but got %+v\n", is)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/bazel"
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+// Provider knows where some classes come from
+type Provider interface {
+	// suggestions for a missing class, none if the class is unknown. The
+	// Dep of a suggestion is the label to add, its Edits create the rule
+	// behind the label if it does not exist yet.
+	Lookup(j index.JavaClass) []Suggestion
+}
+
+// resolution chain used if -providers is not set
+var defaultProviders = []string{"srcs", "genrule", "codegen", "index",
+	"wellknown"}
+
+// providers -providers may name, besides exec:path
+var knownProviders = []string{"srcs", "genrule", "codegen", "index",
+	"wellknown", "central"}
+
+// check a -providers list
+func parseProviders(names string) ([]string, error) {
+	ns := strings.Split(names, ",")
+	for _, n := range ns {
+		if contains(knownProviders, n) ||
+			strings.HasPrefix(n, "exec:") && n != "exec:" {
+			continue
+		}
+		return nil, fmt.Errorf("unknown provider %q, want %s, or "+
+			"exec:path", n, strings.Join(knownProviders, ", "))
+	}
+	return ns, nil
+}
+
+// srcsProvider finds classes in the srcs of existing rules
+type srcsProvider struct {
+	rules *Rules
+}
+
+func (a srcsProvider) Lookup(j index.JavaClass) []Suggestion {
+	r := a.rules.withSrcs(j)
+	if r == nil {
+		log.Printf("not provided by an existing rule\n")
+		return nil
+	}
+	return []Suggestion{{Dep: *r, Provider: "srcs", Confidence: index.High,
+		Reason: fmt.Sprintf("class %s is in the srcs of %s", j.Name, *r),
+		Evidence: []string{fmt.Sprintf("query: bazel query %q",
+			bazel.SrcsQuery(j.Name))}}}
+}
+
+// genruleProvider finds packages generated via wsimport
+type genruleProvider struct {
+	rules *Rules
+}
+
+func (a genruleProvider) Lookup(j index.JavaClass) []Suggestion {
+	f := a.rules.genrule(j.Package())
+	if f == nil {
+		log.Printf("not provided by wsimport genrule\n")
+		return nil
+	}
+	// genrules map packages, not classes
+	return []Suggestion{{Dep: *f, Provider: "genrule",
+		Confidence: index.Medium,
+		Reason: fmt.Sprintf("package %s is generated by %s",
+			j.Package(), *f),
+		Evidence: []string{fmt.Sprintf("query: bazel query %s "+
+			"--output=label_kind", *f)}}}
+}
+
+// codegenProvider finds packages of configured code generators, such as
+// openapi
+type codegenProvider struct {
+	rules      *Rules
+	generators []Codegen
+}
+
+func (a codegenProvider) Lookup(j index.JavaClass) []Suggestion {
+	l, ok := codegenLabel(a.generators, j.Package())
+	if !ok || !a.rules.Exists(l) {
+		return nil
+	}
+	return []Suggestion{{Dep: l, Provider: "codegen",
+		Confidence: index.Medium,
+		Reason: fmt.Sprintf("package %s is generated by %s",
+			j.Package(), l),
+		Evidence: []string{
+			fmt.Sprintf("-codegen maps package %s to %s",
+				j.Package(), l),
+			fmt.Sprintf("query: bazel query %s", l),
+		}}}
+}
+
+// commandProvider asks an external program, such as a resolver backed by an
+// internal artifact server. The program gets the class name as its only
+// argument, and prints one label per line.
+type commandProvider struct {
+	command string
+}
+
+func (a commandProvider) Lookup(j index.JavaClass) []Suggestion {
+	buf, err := exec.Command(a.command, j.Name).Output()
+	if err != nil {
+		log.Printf("provider %s failed for %s: %v\n", a.command, j.Name,
+			err)
+		return nil
+	}
+	var ss []Suggestion
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for scanner.Scan() {
+		if l := strings.TrimSpace(scanner.Text()); l != "" {
+			ss = append(ss, Suggestion{Dep: l,
+				Provider:   "exec:" + a.command,
+				Confidence: index.Medium,
+				Reason: fmt.Sprintf("class %s is provided by %s "+
+					"according to %s", j.Name, l, a.command),
+				Evidence: []string{fmt.Sprintf("command: %s %s",
+					a.command, j.Name)}})
+		}
+	}
+	return ss
+}
+
+// providers in the order given by names. index resolves against the class
+// index, and creates rules for it; exec:path runs a commandProvider;
+// wellknown and central suggest artifacts to declare. The others look up
+// rules.
+func (h Healer) providers(idx Provider, rules *Rules) ([]Provider, error) {
+	names := h.Providers
+	if len(names) == 0 {
+		names = defaultProviders
+	}
+	var ps []Provider
+	for _, n := range names {
+		switch {
+		case n == "srcs":
+			ps = append(ps, srcsProvider{rules})
+		case n == "genrule":
+			ps = append(ps, genruleProvider{rules})
+		case n == "codegen":
+			ps = append(ps, codegenProvider{rules, h.Generators})
+		case n == "index":
+			ps = append(ps, idx)
+		case n == "wellknown":
+			ps = append(ps, wellKnownProvider{h.Workspace})
+		case n == "central":
+			ps = append(ps, centralProvider{h.Workspace})
+		case strings.HasPrefix(n, "exec:"):
+			ps = append(ps, commandProvider{strings.TrimPrefix(n,
+				"exec:")})
+		default:
+			return nil, fmt.Errorf("unknown provider %q", n)
+		}
+	}
+	return ps, nil
+}
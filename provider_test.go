@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+	"github.com/jhinrichsen/bazel-kaizen/index"
+	"github.com/jhinrichsen/bazel-kaizen/parser"
+)
+
+func TestCommandProvider(t *testing.T) {
+	// bazel knows nothing, the resolver knows org.a
+	fakeTools(t, "exit 0\n", "exit 0\n")
+	resolver := filepath.Join(t.TempDir(), "resolver")
+	err := ioutil.WriteFile(resolver, []byte("#!/bin/sh\n"+
+		`case "$1" in org.a.*) echo @artifacts//:a;; esac`+"\n"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := Healer{
+		Workspace: t.TempDir(),
+		Providers: []string{"srcs", "exec:" + resolver},
+	}
+	ss, err := h.heal(parser.BuildProblems{
+		BazelRule:    "//ui/web:web",
+		MissingClass: []index.JavaClass{{Name: "org.a.A"}, {Name: "org.b.B"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	edits := commands(ss)
+	want := edit.Edit{Command: "add deps @artifacts//:a", Target: "//ui/web:web"}
+	if len(edits) != 1 || want != edits[0] {
+		t.Fatalf("want %+v but got %+v\n", want, edits)
+	}
+}
+
+func TestProvidersUnknown(t *testing.T) {
+	h := Healer{Providers: []string{"index", "nexus"}}
+	if _, err := h.providers(nil, nil); err == nil {
+		t.Fatalf("want error for unknown provider\n")
+	}
+}
+
+func TestHealEvidence(t *testing.T) {
+	fakeTools(t, "exit 0\n", "exit 0\n")
+	resolver := filepath.Join(t.TempDir(), "resolver")
+	err := ioutil.WriteFile(resolver, []byte("#!/bin/sh\necho //:a\n"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := Healer{
+		Workspace: t.TempDir(),
+		Providers: []string{"exec:" + resolver},
+	}
+	logged := "Fx.java:3: error: package org.a does not exist"
+	ss, err := h.heal(parser.BuildProblems{
+		BazelRule: "//ui/web:web",
+		MissingClass: []index.JavaClass{{Name: "org.a.A",
+			Location: "Fx.java:3", Log: []string{logged}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ss) != 1 {
+		t.Fatalf("want 1 suggestion but got %+v\n", ss)
+	}
+	want := []string{logged, "command: " + resolver + " org.a.A"}
+	if fmt.Sprint(want) != fmt.Sprint(ss[0].Evidence) {
+		t.Fatalf("want %q but got %q\n", want, ss[0].Evidence)
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/bazel"
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+)
+
+// kinds of a dependency in a .jdeps file, see deps.proto of bazel
+const (
+	jdepsExplicit = 0
+	jdepsImplicit = 1
+)
+
+// classpath jars a compilation used according to its .jdeps file, a
+// serialized blaze_deps.Dependencies protobuf message
+func parseJdeps(buf []byte) ([]string, error) {
+	var used []string
+	err := protoFields(buf, func(field uint64, value []byte) error {
+		// repeated Dependency dependency = 1
+		if field != 1 {
+			return nil
+		}
+		var p string
+		var kind uint64
+		err := protoFields(value, func(field uint64, v []byte) error {
+			switch field {
+			case 1: // path
+				p = string(v)
+			case 2: // kind
+				kind, _ = binary.Uvarint(v)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if kind == jdepsExplicit || kind == jdepsImplicit {
+			used = append(used, p)
+		}
+		return nil
+	})
+	return used, err
+}
+
+// walk the fields of a protobuf message. Varints are passed encoded, length
+// delimited fields without their length.
+func protoFields(buf []byte, f func(field uint64, value []byte) error) error {
+	for len(buf) > 0 {
+		key, n := binary.Uvarint(buf)
+		if n <= 0 {
+			return fmt.Errorf("bad protobuf key")
+		}
+		buf = buf[n:]
+		var value []byte
+		switch key & 7 {
+		case 0: // varint
+			_, n = binary.Uvarint(buf)
+			if n <= 0 {
+				return fmt.Errorf("bad protobuf varint")
+			}
+			value, buf = buf[:n], buf[n:]
+		case 1: // 64 bit
+			if len(buf) < 8 {
+				return fmt.Errorf("short protobuf fixed64")
+			}
+			value, buf = buf[:8], buf[8:]
+		case 2: // length delimited
+			l, n := binary.Uvarint(buf)
+			if n <= 0 || uint64(len(buf)-n) < l {
+				return fmt.Errorf("bad protobuf length")
+			}
+			value, buf = buf[n:n+int(l)], buf[n+int(l):]
+		case 5: // 32 bit
+			if len(buf) < 4 {
+				return fmt.Errorf("short protobuf fixed32")
+			}
+			value, buf = buf[:4], buf[4:]
+		default:
+			return fmt.Errorf("unsupported protobuf wire type %d",
+				key&7)
+		}
+		if err := f(key>>3, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// label of a java_library jar on a compile classpath, such as
+// bazel-out/k8-fastbuild/bin/ui/web/libweb-hjar.jar for //ui/web:web
+func jdepsLabel(p string) (string, bool) {
+	p = filepath.ToSlash(p)
+	i := strings.Index(p, "/bin/")
+	if i < 0 {
+		return "", false
+	}
+	rel := p[i+len("/bin/"):]
+	if strings.HasPrefix(rel, "external/") {
+		return "", false
+	}
+	// compile classpaths carry header or interface jars
+	for _, suffix := range []string{"-hjar.jar", "-ijar.jar"} {
+		if strings.HasSuffix(rel, suffix) {
+			rel = strings.TrimSuffix(rel, suffix) + ".jar"
+		}
+	}
+	return jarLabel(rel)
+}
+
+// .jdeps file of a rule in bazel-bin, libname.jdeps for libraries and
+// name.jdeps for binaries and tests
+func jdepsFile(bin string, label string) (string, bool) {
+	s := strings.TrimPrefix(label, "//")
+	i := strings.Index(s, ":")
+	if i < 0 {
+		return "", false
+	}
+	for _, base := range []string{"lib" + s[i+1:], s[i+1:]} {
+		f := filepath.Join(bin, s[:i], base+".jdeps")
+		if _, err := os.Stat(f); err == nil {
+			return f, true
+		}
+	}
+	return "", false
+}
+
+// whether a compilation used a library, or anything it exports
+func usedLibrary(l string, libraries map[string]bazel.JavaRule,
+	used map[string]bool, seen map[string]bool) bool {
+	if used[l] {
+		return true
+	}
+	if seen[l] {
+		return false
+	}
+	seen[l] = true
+	for _, e := range libraries[l].Exports {
+		if usedLibrary(e, libraries, used, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// deps of Java rules their last compilation did not use, according to .jdeps
+// files. Only deps on java_library rules are judged, the jars of anything
+// else cannot be told apart on the classpath. Deps exporting a used library
+// are used, too.
+func jdepsUnused(rules []bazel.JavaRule, workspace string) ([]Analysis, error) {
+	bin, err := bazelBin(workspace)
+	if err != nil {
+		return nil, fmt.Errorf("no bazel-bin, build first: %v", err)
+	}
+	var declared []string
+	for _, r := range rules {
+		declared = append(declared, r.Deps...)
+	}
+	libraries := bazel.Libraries(declared, workspace)
+	var as []Analysis
+	for _, r := range rules {
+		if !strings.HasPrefix(r.Class, "java_") {
+			log.Printf("skipping %s %s, not a Java rule\n", r.Class,
+				r.Label)
+			continue
+		}
+		f, ok := jdepsFile(bin, r.Label)
+		if !ok {
+			log.Printf("%s: no .jdeps file, build it first\n",
+				r.Label)
+			continue
+		}
+		buf, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		jars, err := parseJdeps(buf)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", f, err)
+		}
+		used := make(map[string]bool)
+		for _, j := range jars {
+			if l, ok := jdepsLabel(j); ok {
+				used[l] = true
+			}
+		}
+		a := Analysis{Target: r.Label}
+		for _, d := range r.Deps {
+			_, library := libraries[d]
+			if library && !usedLibrary(d, libraries, used,
+				make(map[string]bool)) {
+				a.Superfluous = append(a.Superfluous, d)
+			}
+		}
+		sort.Strings(a.Superfluous)
+		as = append(as, a)
+	}
+	return as, nil
+}
+
+// edits removing superfluous deps
+func pruneEdits(as []Analysis) []edit.Edit {
+	var edits []edit.Edit
+	for _, a := range as {
+		if len(a.Superfluous) == 0 {
+			continue
+		}
+		for _, l := range a.Superfluous {
+			log.Printf("%s: unused dep %s\n", a.Target, l)
+		}
+		edits = append(edits, edit.Edit{
+			Command: "remove deps " + strings.Join(a.Superfluous, " "),
+			Target:  a.Target,
+		})
+	}
+	return edits
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/bazel"
+)
+
+// protobuf encoding of a dependency of a .jdeps file
+func jdepsEntry(path string, kind byte) []byte {
+	dep := append([]byte{0x0a, byte(len(path))}, path...)
+	dep = append(dep, 0x10, kind)
+	return append([]byte{0x0a, byte(len(dep))}, dep...)
+}
+
+func TestParseJdeps(t *testing.T) {
+	buf := append(jdepsEntry("bazel-out/k8-fastbuild/bin/a/liba-hjar.jar",
+		jdepsExplicit), jdepsEntry("bazel-out/k8-fastbuild/bin/b/libb.jar",
+		2)...)
+	// rule_label = 2
+	buf = append(buf, 0x12, 4, '/', '/', 'c', 'c')
+	jars, err := parseJdeps(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jars) != 1 {
+		t.Fatalf("want 1 used jar but got %q\n", jars)
+	}
+	want := "//a:a"
+	got, _ := jdepsLabel(jars[0])
+	if want != got {
+		t.Fatalf("want %s but got %s\n", want, got)
+	}
+	if _, err := parseJdeps([]byte{0x0a, 0x7f}); err == nil {
+		t.Fatalf("want error for truncated message\n")
+	}
+}
+
+func TestJdepsUnused(t *testing.T) {
+	ws := t.TempDir()
+	// //e:e is needed for the //x:x it exports
+	fakeTools(t, `cat <<EOF
+<?xml version="1.1" encoding="UTF-8" standalone="no"?>
+<query version="2">
+    <rule class="java_library" name="//a:a"/>
+    <rule class="java_library" name="//b:b"/>
+    <rule class="java_library" name="//e:e">
+        <list name="exports">
+            <label value="//x:x"/>
+        </list>
+    </rule>
+    <rule class="java_library" name="//x:x"/>
+</query>
+EOF
+`, "exit 0\n")
+	for _, lib := range []string{"web", "kt"} {
+		dir := filepath.Join(ws, "bazel-bin", "ui", lib)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		err := ioutil.WriteFile(filepath.Join(dir, "lib"+lib+".jdeps"),
+			append(jdepsEntry("bazel-out/k8-fastbuild/bin/a/liba-hjar.jar",
+				jdepsExplicit), jdepsEntry(
+				"bazel-out/k8-fastbuild/bin/x/libx-hjar.jar",
+				jdepsExplicit)...), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	as, err := jdepsUnused([]bazel.JavaRule{
+		{Class: "java_library", Label: "//ui/web:web",
+			Deps: []string{"//a:a", "//b:b", "//e:e", "@maven//:c"}},
+		{Class: "kt_jvm_library", Label: "//ui/kt:kt",
+			Deps: []string{"//b:b"}},
+	}, ws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edits := pruneEdits(as)
+	want := "buildozer 'remove deps //b:b' '//ui/web:web'"
+	if len(edits) != 1 || want != edits[0].String() {
+		t.Fatalf("want %s but got %v\n", want, edits)
+	}
+}
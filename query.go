@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+// Match is a class and the dependency providing it
+type Match struct {
+	Class    string
+	Provider string
+	Kind     index.Kind
+}
+
+// Term matches a field of a Match: exactly, by prefix ending in *, or by
+// regular expression starting with ~
+type Term struct {
+	Field string // class, package, or provider
+	exact string
+	re    *regexp.Regexp
+}
+
+// parse a term such as package:com.foo.*, provider:@maven//:guava, or
+// class:~Impl$
+func parseTerm(s string) (Term, error) {
+	kv := strings.SplitN(s, ":", 2)
+	if len(kv) != 2 || kv[1] == "" {
+		return Term{}, fmt.Errorf("want field:value but got %q", s)
+	}
+	t := Term{Field: kv[0]}
+	switch t.Field {
+	case "class", "package", "provider":
+	default:
+		return t, fmt.Errorf("unknown field %q, want class, package, "+
+			"or provider", t.Field)
+	}
+	var err error
+	switch v := kv[1]; {
+	case strings.HasPrefix(v, "~"):
+		t.re, err = regexp.Compile(v[1:])
+	case strings.HasSuffix(v, "*"):
+		t.re, err = regexp.Compile("^" +
+			regexp.QuoteMeta(strings.TrimSuffix(v, "*")))
+	default:
+		t.exact = v
+	}
+	return t, err
+}
+
+// terms of the command line
+func parseTerms(ss []string) ([]Term, error) {
+	var terms []Term
+	for _, s := range ss {
+		t, err := parseTerm(s)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, t)
+	}
+	return terms, nil
+}
+
+func (a Term) matches(m Match) bool {
+	var v string
+	switch a.Field {
+	case "class":
+		v = m.Class
+	case "package":
+		v = index.StripLast(m.Class)
+	case "provider":
+		v = m.Provider
+	}
+	if a.re != nil {
+		return a.re.MatchString(v)
+	}
+	return a.exact == v
+}
+
+// classes and their providers matching all terms, sorted by class
+func query(deps []index.Dependency, terms []Term) []Match {
+	var ms []Match
+	for _, d := range deps {
+		for _, c := range d.Named(index.Class) {
+			m := Match{c, d.Name, d.Kind}
+			ok := true
+			for _, t := range terms {
+				if !t.matches(m) {
+					ok = false
+					break
+				}
+			}
+			if ok {
+				ms = append(ms, m)
+			}
+		}
+	}
+	sort.SliceStable(ms, func(i, j int) bool {
+		return ms[i].Class < ms[j].Class
+	})
+	return ms
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+func TestQuery(t *testing.T) {
+	deps := []index.Dependency{
+		{Name: "@maven//:guava", Kind: index.RulesJvmExternal,
+			Resources: classes("com.google.common.base.Strings",
+				"com.google.common.collect.Lists")},
+		{Name: "framework", Kind: index.Source,
+			Resources: classes("org.company.framework.A",
+				"org.company.framework.AImpl")},
+	}
+	for _, tt := range []struct {
+		terms []string
+		want  int
+	}{
+		{[]string{"package:com.google.common.*"}, 2},
+		{[]string{"package:com.google.common"}, 0},
+		{[]string{"package:com.google.common.base"}, 1},
+		{[]string{"provider:@maven//:guava"}, 2},
+		{[]string{"class:~Impl$"}, 1},
+		{[]string{"provider:framework", "class:~^org.company.framework.A$"}, 1},
+		{[]string{"provider:@maven//*", "class:~Impl"}, 0},
+	} {
+		var terms []Term
+		for _, s := range tt.terms {
+			term, err := parseTerm(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			terms = append(terms, term)
+		}
+		if got := len(query(deps, terms)); tt.want != got {
+			t.Fatalf("%v: want %d matches but got %d\n", tt.terms,
+				tt.want, got)
+		}
+	}
+	for _, s := range []string{"guava", "module:a", "class:~(", "class:"} {
+		if _, err := parseTerm(s); err == nil {
+			t.Fatalf("%s: want error\n", s)
+		}
+	}
+}
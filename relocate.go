@@ -0,0 +1,53 @@
+package main
+
+import (
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/bazel"
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+// relocator finds the jars of external dependencies cached in an output_base
+// that is gone, after bazel clean --expunge, or a moved output_base
+type relocator struct {
+	base   string // current output_base, queried on first use
+	looked bool
+}
+
+// current jar of an external dependency. Jars below external/ of a former
+// output_base move to the current one. ok is false if the jar is gone for
+// good, such as for repositories not fetched again; its classes remain
+// known, the path stays as it was.
+func (a *relocator) relocate(workspace string,
+	d index.Dependency) (index.Dependency, bool) {
+	jar := d.ExternalReference
+	if !d.Kind.External() || !strings.HasSuffix(jar, ".jar") ||
+		canRead(jar) {
+		return d, true
+	}
+	if !a.looked {
+		a.looked = true
+		base, err := bazel.Info("output_base", workspace)
+		if err != nil {
+			log.Printf("cannot relocate jars: %v\n", err)
+		}
+		a.base = base
+	}
+	slashed := filepath.ToSlash(jar)
+	i := strings.LastIndex(slashed, "/external/")
+	if a.base != "" && i >= 0 {
+		moved := filepath.Join(a.base, filepath.FromSlash(slashed[i+1:]))
+		if canRead(moved) {
+			log.Printf("jar %s of %s moved to %s\n", jar, d.Name,
+				moved)
+			d.ExternalReference = moved
+			d.Stamp = index.Stamp([]string{moved})
+			return d, true
+		}
+	}
+	log.Printf("jar %s of %s is gone, run bazel fetch and -update\n",
+		jar, d.Name)
+	return d, false
+}
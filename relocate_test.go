@@ -0,0 +1,46 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+func TestRelocate(t *testing.T) {
+	base, calls := t.TempDir(), filepath.Join(t.TempDir(), "calls")
+	fakeTools(t, `echo "$@" >> `+calls+`
+case "$*" in *info*) echo `+base+`;; esac
+`, "exit 0\n")
+	fixtureFiles(t, base, map[string]string{
+		"external/guava/jar/guava-32.jar": "PK",
+	})
+	current := filepath.Join(base, "external/guava/jar/guava-32.jar")
+	var a relocator
+	for _, tt := range []struct {
+		jar, want string
+		ok        bool
+	}{
+		{"/expunged/external/guava/jar/guava-32.jar", current, true},
+		{current, current, true},
+		{"/expunged/external/gone/jar/gone-1.jar",
+			"/expunged/external/gone/jar/gone-1.jar", false},
+	} {
+		d, ok := a.relocate(t.TempDir(), index.Dependency{
+			Name: "//external:guava", Kind: index.MavenJar,
+			ExternalReference: tt.jar})
+		if tt.want != d.ExternalReference || tt.ok != ok {
+			t.Fatalf("%s: want %s %v but got %s %v\n", tt.jar,
+				tt.want, tt.ok, d.ExternalReference, ok)
+		}
+	}
+	buf, err := ioutil.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(buf), "\n"); n != 1 {
+		t.Fatalf("want a single bazel info but got %s\n", buf)
+	}
+}
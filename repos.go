@@ -0,0 +1,45 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+// external repository of a dependency, such as maven for @maven//:guava or
+// guava for //external:guava, "" for sources
+func repository(name string) string {
+	switch {
+	case strings.HasPrefix(name, "@"):
+		if i := strings.Index(name, "//"); i > 0 {
+			return name[1:i]
+		}
+		return name[1:]
+	case strings.HasPrefix(name, "//external:"):
+		return strings.TrimPrefix(name, "//external:")
+	}
+	return ""
+}
+
+// order dependencies so those of preferred repositories come first, in the
+// order of repos. Others keep their order behind them.
+func preferRepos(repos []string, deps []index.Dependency) []index.Dependency {
+	if len(repos) == 0 {
+		return deps
+	}
+	rank := func(d index.Dependency) int {
+		r := repository(d.Name)
+		for i, p := range repos {
+			if p == r {
+				return i
+			}
+		}
+		return len(repos)
+	}
+	sorted := append([]index.Dependency{}, deps...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return rank(sorted[i]) < rank(sorted[j])
+	})
+	return sorted
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+func TestPreferRepos(t *testing.T) {
+	deps := []index.Dependency{
+		{Name: "framework"},
+		{Name: "@maven//:guava"},
+		{Name: "//external:guava"},
+		{Name: "@internal//:guava"},
+	}
+	got := preferRepos([]string{"internal", "maven"}, deps)
+	want := []string{"@internal//:guava", "@maven//:guava", "framework",
+		"//external:guava"}
+	for i, d := range got {
+		if want[i] != d.Name {
+			t.Fatalf("want %v but got %+v\n", want, got)
+		}
+	}
+	if deps[0].Name != "framework" {
+		t.Fatalf("want dependencies unchanged but got %+v\n", deps)
+	}
+}
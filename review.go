@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/bazel"
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+// suggestions depending on other dependencies providing the class of a
+// suggestion instead, built as the index provider builds them, so that
+// rules missing for them are generated, too
+func (h Healer) alternatives(rules *Rules) func(Suggestion) ([]Suggestion,
+	error) {
+	return func(s Suggestion) ([]Suggestion, error) {
+		if s.Class == "" || s.Dep == "" {
+			return nil, nil
+		}
+		j := index.JavaClass{Name: s.Class, Location: s.Location}
+		deps := h.Deps
+		if h.Store != nil {
+			found, err := h.Store.Lookup(j)
+			if err == nil {
+				deps = append(found, deps...)
+			}
+		}
+		idx := &indexProvider{h: h, rule: s.Rule, rules: rules,
+			created: make(map[string]bool)}
+		seen := map[string]bool{s.Dep: true}
+		var as []Suggestion
+		for _, d := range deps {
+			if !d.Provides(index.Class, s.Class) {
+				continue
+			}
+			a, err := idx.suggestion(j, d, index.High)
+			if err != nil {
+				return nil, err
+			}
+			if a.Dep == "" || seen[a.Dep] {
+				continue
+			}
+			seen[a.Dep] = true
+			a.Rule, a.Class = s.Rule, s.Class
+			a.Location = s.Location
+			a.Action = AddDep
+			if len(a.Edits) > 0 {
+				a.Action = CreateRule
+			}
+			a.Edits = append(a.Edits, edit.AddDeps(s.Rule, a.Dep))
+			a.Reason = fmt.Sprintf("chosen on review instead of "+
+				"%s, %s", s.Dep, a.Reason)
+			a.Provider = "review"
+			as = append(as, a)
+		}
+		if len(as) == 0 {
+			return nil, nil
+		}
+		sort.Slice(as, func(i, j int) bool {
+			return as[i].Dep < as[j].Dep
+		})
+		return h.polish(as, s.Rule, bazel.RuleKind(s.Rule, h.Workspace))
+	}
+}
+
+// a suggestion depending on another label. Rules generated for the former
+// dep are dropped, the label must exist.
+func withDep(s Suggestion, label string) Suggestion {
+	var es []edit.Edit
+	for _, e := range s.Edits {
+		if strings.HasPrefix(e.Command, "add ") &&
+			strings.HasSuffix(e.Command, " "+s.Dep) {
+			e.Command = strings.TrimSuffix(e.Command, s.Dep) + label
+			es = append(es, e)
+		}
+	}
+	s.Reason = fmt.Sprintf("chosen on review instead of %s", s.Dep)
+	s.Dep, s.Edits, s.Action = label, es, AddDep
+	s.Provider, s.Confidence = "review", index.High
+	return s
+}
+
+// review suggestions one by one, as with -interactive: accept, reject, or
+// change the dep of each before anything is applied or printed. Answers are
+// read from in, suggestions and prompts written to out. Suggestions not
+// answered are rejected. A dep typed in must exist.
+func review(ss []Suggestion, in io.Reader, out io.Writer,
+	alternatives func(Suggestion) ([]Suggestion, error),
+	exists func(label string) bool) ([]Suggestion, error) {
+	scanner := bufio.NewScanner(in)
+	answer := func(prompt string) (string, bool) {
+		fmt.Fprint(out, prompt)
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return "", false
+		}
+		return strings.TrimSpace(scanner.Text()), true
+	}
+	var accepted []Suggestion
+	for i, s := range ss {
+		fmt.Fprintf(out, "[%d/%d] %s\n", i+1, len(ss), s)
+		for _, e := range s.Edits {
+			fmt.Fprintf(out, "  %s\n", e)
+		}
+		as, err := alternatives(s)
+		if err != nil {
+			return nil, err
+		}
+		for j, a := range as {
+			fmt.Fprintf(out, "  %d) %s\n", j+1, a.Dep)
+		}
+		prompt := "[y]es, [n]o, [a]ll, [q]uit"
+		if s.Dep != "" {
+			prompt += ", [e]dit dep"
+		}
+		if len(as) > 0 {
+			prompt += fmt.Sprintf(", [1-%d] other dep", len(as))
+		}
+		prompt += "? "
+		for decided := false; !decided; {
+			a, ok := answer(prompt)
+			if !ok {
+				return accepted, nil
+			}
+			n, err := strconv.Atoi(a)
+			decided = true
+			switch {
+			case a == "" || a == "y":
+				accepted = append(accepted, s)
+			case a == "n":
+			case a == "a":
+				return append(accepted, ss[i:]...), nil
+			case a == "q":
+				return accepted, nil
+			case a == "e" && s.Dep != "":
+				l, ok := answer("dep: ")
+				if !ok {
+					return accepted, nil
+				}
+				switch {
+				case !edit.ValidLabel(l):
+					fmt.Fprintf(out, "invalid label %q\n", l)
+					decided = false
+				case !exists(l):
+					fmt.Fprintf(out, "no such rule %s\n", l)
+					decided = false
+				default:
+					accepted = append(accepted, withDep(s, l))
+				}
+			case err == nil && n >= 1 && n <= len(as):
+				accepted = append(accepted, as[n-1])
+			default:
+				decided = false
+			}
+		}
+	}
+	return accepted, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+func TestReview(t *testing.T) {
+	suggestion := func(class, dep string) Suggestion {
+		return Suggestion{Rule: "//app:app", Action: CreateRule,
+			Class: class, Dep: dep, Edits: []edit.Edit{
+				{Command: "new java_library b", Target: "//:__pkg__"},
+				edit.AddDeps("//app:app", dep)}}
+	}
+	ss := []Suggestion{suggestion("org.a.A", "//:a"),
+		suggestion("org.b.B", "//:b"), suggestion("org.c.C", "//:c"),
+		suggestion("org.d.D", "//:d"), suggestion("org.e.E", "//:e")}
+	alternatives := func(s Suggestion) ([]Suggestion, error) {
+		if s.Class == "org.c.C" {
+			return []Suggestion{{Rule: s.Rule, Dep: "@maven//:c",
+				Edits: []edit.Edit{edit.AddDeps("//app:app",
+					"@maven//:c")}}}, nil
+		}
+		return nil, nil
+	}
+	exists := func(label string) bool {
+		return label != "//lib:gone"
+	}
+	// accept, reject, pick an alternative, edit after an invalid label,
+	// an unknown answer and a missing rule, accept all
+	answers := "y\nn\n1\nx\ne\nit's\ne\n//lib:gone\ne\n//lib:d\na\n"
+	var out bytes.Buffer
+	got, err := review(ss, strings.NewReader(answers), &out, alternatives,
+		exists)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var deps []string
+	for _, s := range got {
+		deps = append(deps, s.Dep)
+	}
+	want := []string{"//:a", "@maven//:c", "//lib:d", "//:e"}
+	if !reflect.DeepEqual(want, deps) {
+		t.Fatalf("want %v but got %v\n", want, deps)
+	}
+	wantEdits := []edit.Edit{edit.AddDeps("//app:app", "//lib:d")}
+	if !reflect.DeepEqual(wantEdits, got[2].Edits) ||
+		got[2].Confidence != index.High {
+		t.Fatalf("want %+v but got %+v\n", wantEdits, got[2])
+	}
+	if !strings.Contains(out.String(), "1) @maven//:c") ||
+		!strings.Contains(out.String(), "no such rule //lib:gone") {
+		t.Fatalf("want alternatives listed but got %s\n", out.String())
+	}
+
+	// unanswered suggestions are rejected
+	if got, _ := review(ss, strings.NewReader("y\n"), &out,
+		alternatives, exists); len(got) != 1 {
+		t.Fatalf("want 1 suggestion but got %+v\n", got)
+	}
+}
+
+// an alternative without a rule yet gets one generated
+func TestAlternatives(t *testing.T) {
+	fakeTools(t, "exit 0\n", "exit 0\n")
+	ws := t.TempDir()
+	h := Healer{Workspace: ws, Deps: []index.Dependency{
+		{Name: "a", Kind: index.Source, Resources: classes("org.a.A")},
+		{Name: "b", Kind: index.Source, ExternalReference: "b/src/",
+			Resources: classes("org.a.A")},
+		{Name: "c", Kind: index.Source, Resources: classes("org.c.C")},
+	}}
+	alternatives := h.alternatives(queryRules(ws, nil, nil))
+	got, err := alternatives(Suggestion{Rule: "//app:app",
+		Class: "org.a.A", Dep: "//:a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Dep != "//:b" ||
+		got[0].Action != CreateRule {
+		t.Fatalf("want //:b but got %+v\n", got)
+	}
+	want := append(edit.NewJavaLibrary(h.Deps[1]),
+		edit.AddDeps("//app:app", "//:b"))
+	if !reflect.DeepEqual(want, got[0].Edits) {
+		t.Fatalf("want %+v but got %+v\n", want, got[0].Edits)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/bazel"
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+// MavenInstall is the part of a rules_jvm_external lock file, such as
+// maven_install.json, listing artifacts and their Java packages. Version 2
+// lock files list packages per artifact, version 1 files per dependency.
+type MavenInstall struct {
+	Artifacts map[string]struct {
+		Version string `json:"version"`
+	} `json:"artifacts"`
+	Packages       map[string][]string `json:"packages"`
+	DependencyTree struct {
+		Dependencies []struct {
+			Coord    string   `json:"coord"`
+			File     string   `json:"file"`
+			Packages []string `json:"packages"`
+		} `json:"dependencies"`
+	} `json:"dependency_tree"`
+}
+
+// classifier of group:artifact:type:classifier:version coordinates, such as
+// tests, "" for none
+func classifier(coordinates string) string {
+	parts := strings.Split(coordinates, ":")
+	if len(parts) != 5 {
+		return ""
+	}
+	return parts[3]
+}
+
+// label of an artifact in a maven_install repository, such as
+// @maven//:com_google_guava_guava, or @maven//:org_a_a_tests for the tests
+// classifier
+func repoLabel(repo string, coordinates string) string {
+	r := strings.NewReplacer(":", "_", ".", "_", "-", "_")
+	name := groupArtifact(coordinates)
+	if c := classifier(coordinates); c != "" {
+		name += ":" + c
+	}
+	return "@" + repo + "//:" + r.Replace(name)
+}
+
+// dependencies of a maven_install lock file of repository repo. Resources are
+// the Java packages of each artifact, the jar, if any, is relative to the
+// repository directory. Artifacts of the tests classifier are test only, and
+// skipped unless tests is set.
+func parseMavenInstall(repo string, r io.Reader,
+	tests bool) ([]index.Dependency, error) {
+	var mi MavenInstall
+	if err := json.NewDecoder(r).Decode(&mi); err != nil {
+		return nil, err
+	}
+	var deps []index.Dependency
+	add := func(coord string, file string, pkgs []string) {
+		// classifiers, such as sources, carry no classes of their own
+		c := classifier(coord)
+		if c != "" && (c != "tests" || !tests) {
+			return
+		}
+		var rs []index.Resource
+		for _, p := range pkgs {
+			rs = append(rs, index.Resource{Type: index.Package, Name: p})
+		}
+		deps = append(deps, index.Dependency{
+			Name:              repoLabel(repo, coord),
+			ExternalReference: file,
+			Resources:         rs,
+			Artifact:          coord,
+			Kind:              index.RulesJvmExternal,
+			TestOnly:          c != "",
+		})
+	}
+	for _, d := range mi.DependencyTree.Dependencies {
+		add(d.Coord, d.File, d.Packages)
+	}
+	for ga, a := range mi.Artifacts {
+		// group:artifact, or group:artifact:type:classifier
+		if n := strings.Count(ga, ":"); n != 1 && n != 3 {
+			continue
+		}
+		add(ga+":"+a.Version, "", mi.Packages[ga])
+	}
+	return deps, nil
+}
+
+// jar of a version 2 artifact below the repository directory, such as
+// v1/https/repo1.maven.org/maven2/com/google/guava/guava/31.1-jre/
+// guava-31.1-jre.jar
+func artifactJar(repodir string, coordinates string) string {
+	parts := strings.Split(coordinates, ":")
+	if len(parts) != 3 && len(parts) != 5 {
+		return ""
+	}
+	g, a, v := parts[0], parts[1], parts[len(parts)-1]
+	base := a + "-" + v
+	if c := classifier(coordinates); c != "" {
+		base += "-" + c
+	}
+	rel := path.Join(strings.Replace(g, ".", "/", -1), a, v, base+".jar")
+	// protocol and an unknown number of repository URL segments
+	for _, prefix := range []string{"v1/*/*", "v1/*/*/*", "v1/*/*/*/*"} {
+		jars, _ := filepath.Glob(filepath.Join(repodir, prefix, rel))
+		if len(jars) > 0 {
+			return jars[0]
+		}
+	}
+	return ""
+}
+
+// dependencies of all maven_install lock files of the workspace, see
+// lockFiles. Classes are indexed from fetched jars, unfetched artifacts are
+// known by their packages only.
+func mavenInstallDependencies(workspace string, percent int,
+	tests bool, prev index.Previous, jobs int) ([]index.Dependency, error) {
+	locks := lockFiles(workspace)
+	for _, repo := range unpinnedRepos(workspace, locks) {
+		log.Printf("maven.install of %s has no lock_file, its artifacts "+
+			"are not indexed until pinned by bazel run "+
+			"@%s//:pin\n", repo, repo)
+	}
+	if len(locks) == 0 {
+		return nil, nil
+	}
+	base, err := bazel.OutputBase(workspace)
+	if err != nil {
+		return nil, err
+	}
+	var deps []index.Dependency
+	for _, l := range locks {
+		repo, lock := l.Repo, l.File
+		f, err := os.Open(lock)
+		if err != nil {
+			log.Printf("skip %s: %v\n", lock, err)
+			continue
+		}
+		ds, err := parseMavenInstall(repo, f, tests)
+		f.Close()
+		if err != nil {
+			log.Printf("skip %s: %v\n", lock, err)
+			continue
+		}
+		repodir := repoDir(base, repo)
+		var sampledDeps []index.Dependency
+		for _, d := range ds {
+			if index.Sampled(d.Name, percent) {
+				sampledDeps = append(sampledDeps, d)
+			}
+		}
+		index.Parallel(len(sampledDeps), jobs, func(i int) {
+			sampledDeps[i] = artifactDependency(repodir,
+				sampledDeps[i], prev)
+		})
+		deps = append(deps, sampledDeps...)
+		log.Printf("found %d artifacts in %s\n", len(ds), lock)
+	}
+	return deps, nil
+}
+
+// artifact of a lock file with the classes of its jar, if fetched
+func artifactDependency(repodir string, d index.Dependency,
+	prev index.Previous) index.Dependency {
+	jar := filepath.Join(repodir, d.ExternalReference)
+	if d.ExternalReference == "" {
+		jar = artifactJar(repodir, d.Artifact)
+	}
+	if jar == "" || !canRead(jar) {
+		return d
+	}
+	indexed := d
+	indexed.ExternalReference = jar
+	indexed.Stamp = index.Stamp([]string{jar})
+	if old, ok := prev.Unchanged(indexed); ok {
+		indexed.Resources = old.Resources
+		return indexed
+	}
+	rs, err := index.Content(jar)
+	if err != nil {
+		log.Printf("warning: skip unreadable jar %s: %v\n", jar, err)
+		return d
+	}
+	indexed.Resources = rs
+	return indexed
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+func TestParseMavenInstall(t *testing.T) {
+	v2 := `{
+  "artifacts": {
+    "com.google.guava:guava": {"shasums": {"jar": "x"}, "version": "31.1-jre"},
+    "com.google.guava:guava:jar:sources": {"version": "31.1-jre"}
+  },
+  "packages": {
+    "com.google.guava:guava": ["com.google.common.base"]
+  },
+  "version": "2"
+}`
+	deps, err := parseMavenInstall("maven", strings.NewReader(v2), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deps) != 1 {
+		t.Fatalf("want 1 artifact but got %+v\n", deps)
+	}
+	d := deps[0]
+	want := "@maven//:com_google_guava_guava"
+	if want != d.Name || d.Kind != index.RulesJvmExternal ||
+		d.Artifact != "com.google.guava:guava:31.1-jre" {
+		t.Fatalf("want %s but got %+v\n", want, d)
+	}
+	if !d.Provides(index.Package, "com.google.common.base") {
+		t.Fatalf("want package com.google.common.base but got %+v\n",
+			d.Resources)
+	}
+
+	v1 := `{"dependency_tree": {"dependencies": [{
+  "coord": "junit:junit:4.13",
+  "file": "v1/https/repo1.maven.org/maven2/junit/junit/4.13/junit-4.13.jar",
+  "packages": ["org.junit"]
+}]}}`
+	deps, err = parseMavenInstall("test_maven", strings.NewReader(v1), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "@test_maven//:junit_junit"
+	if len(deps) != 1 || deps[0].Name != want {
+		t.Fatalf("want %s but got %+v\n", want, deps)
+	}
+}
+
+func TestArtifactJar(t *testing.T) {
+	dir := t.TempDir()
+	want := filepath.Join(dir, "v1/https/repo1.maven.org/maven2/com/"+
+		"google/guava/guava/31.1-jre/guava-31.1-jre.jar")
+	fixtureJar(t, want, "com.google.common.base.Optional")
+	got := artifactJar(dir, "com.google.guava:guava:31.1-jre")
+	if want != got {
+		t.Fatalf("want %s but got %s\n", want, got)
+	}
+}
+
+func TestParseMavenInstallTests(t *testing.T) {
+	v2 := `{
+  "artifacts": {
+    "org.a:a": {"version": "1.0"},
+    "org.a:a:jar:tests": {"version": "1.0"}
+  },
+  "version": "2"
+}`
+	deps, err := parseMavenInstall("maven", strings.NewReader(v2), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "@maven//:org_a_a_tests"
+	for _, d := range deps {
+		if d.TestOnly != (d.Name == want) {
+			t.Fatalf("want only %s test only but got %+v\n", want,
+				deps)
+		}
+	}
+	if len(deps) != 2 {
+		t.Fatalf("want artifact and its tests but got %+v\n", deps)
+	}
+	dir := t.TempDir()
+	jar := filepath.Join(dir, "v1/https/repo1.maven.org/maven2/org/a/a/"+
+		"1.0/a-1.0-tests.jar")
+	fixtureJar(t, jar, "org.a.ATest")
+	if got := artifactJar(dir, "org.a:a:jar:tests:1.0"); jar != got {
+		t.Fatalf("want %s but got %s\n", jar, got)
+	}
+}
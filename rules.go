@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/bazel"
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+// Rules of the workspace from a single bazel query: all rules of the root
+// package, where kaizen generates its rules, the genrules of all packages,
+// the rules of any package having a missing class in their srcs, and labels
+// known up front. Resolving many classes costs one bazel round trip instead
+// of several per class. Should the query fail, every lookup queries bazel on
+// its own.
+type Rules struct {
+	workspace string
+	ok        bool
+	kinds     map[string]string   // label -> kind, such as java_library
+	srcs      map[string][]string // label -> labels of srcs
+	asked     map[string]bool     // labels queried, existing or not
+	// kinds declaring rules by name besides native ones, such as macros
+	// of -deps-attributes, depsAttributes if nil
+	attributes map[string]string
+}
+
+// rules of a query --output=xml
+type xmlQuery struct {
+	Rules []struct {
+		Class string `xml:"class,attr"`
+		Name  string `xml:"name,attr"`
+		Lists []struct {
+			Name   string `xml:"name,attr"`
+			Labels []struct {
+				Value string `xml:"value,attr"`
+			} `xml:"label"`
+		} `xml:"list"`
+	} `xml:"rule"`
+}
+
+// query the rules of the root package, genrules, rules having classes in
+// their srcs, and whether labels exist
+func queryRules(workspace string, labels []string,
+	js []index.JavaClass) *Rules {
+	a := &Rules{workspace: workspace, kinds: make(map[string]string),
+		srcs: make(map[string][]string), asked: make(map[string]bool)}
+	q := ":all + kind(genrule, //...)"
+	if len(js) > 0 {
+		var names []string
+		for _, j := range js {
+			names = append(names, j.Name)
+		}
+		q += " + " + bazel.SrcsQuery(strings.Join(names, "|"))
+	}
+	for _, l := range labels {
+		q += " + " + l
+		a.asked[ruleLabel(l)] = true
+	}
+	prms := []string{"bazel", "query", q, "--output=xml"}
+	buf, err := bazel.Query(prms, workspace)
+	if err != nil {
+		log.Printf("cannot query rules, querying one by one: %v\n", err)
+		return a
+	}
+	// bazel writes XML 1.1, which encoding/xml refuses
+	buf = bytes.Replace(buf, []byte(`version="1.1"`),
+		[]byte(`version="1.0"`), 1)
+	var x xmlQuery
+	if err := xml.Unmarshal(buf, &x); err != nil {
+		log.Printf("cannot parse rules, querying one by one: %v\n", err)
+		return a
+	}
+	for _, r := range x.Rules {
+		a.kinds[r.Name] = r.Class
+		for _, l := range r.Lists {
+			if l.Name != "srcs" {
+				continue
+			}
+			for _, v := range l.Labels {
+				a.srcs[r.Name] = append(a.srcs[r.Name], v.Value)
+			}
+		}
+	}
+	a.ok = true
+	log.Printf("queried %d rules\n", len(a.kinds))
+	return a
+}
+
+// absolute label of a rule, a plain name is a rule of the root package
+func ruleLabel(rule string) string {
+	switch {
+	case strings.HasPrefix(rule, ":"):
+		return "//" + rule
+	case strings.Contains(rule, "//") || strings.Contains(rule, ":"):
+		return rule
+	}
+	return "//:" + rule
+}
+
+// Exists reports whether bazel knows a rule. Rules outside of the root
+// package not asked for up front are looked up in their BUILD file, or
+// queried, and remembered.
+func (a *Rules) Exists(rule string) bool {
+	l := ruleLabel(rule)
+	if a.ok && (strings.HasPrefix(l, "//:") || a.asked[l]) {
+		_, ok := a.kinds[l]
+		return ok
+	}
+	attributes := a.attributes
+	if attributes == nil {
+		attributes = depsAttributes
+	}
+	ok := declared(a.workspace, l, attributes)
+	if !ok {
+		var err error
+		ok, err = bazel.RuleExists(rule, a.workspace)
+		if err != nil {
+			log.Printf("cannot query %s: %v\n", rule, err)
+			return false
+		}
+	}
+	if ok {
+		a.kinds[l] = ""
+	}
+	a.asked[l] = true
+	return ok
+}
+
+// the single rule of the workspace having a class in its srcs, see
+// bazel.SrcsQuery
+func (a *Rules) withSrcs(j index.JavaClass) *string {
+	if !a.ok {
+		return bazel.FindSrcs(j.Name, a.workspace)
+	}
+	re, err := regexp.Compile(j.Name)
+	if err != nil {
+		return nil
+	}
+	var found []string
+	for r, srcs := range a.srcs {
+		if re.MatchString(strings.Join(srcs, ", ")) {
+			found = append(found, r)
+		}
+	}
+	if len(found) == 1 {
+		return &found[0]
+	}
+	return nil
+}
+
+// the genrule generating a Java package, see bazel.FindGenrule. The root
+// package wins over a single genrule of the name in another package.
+func (a *Rules) genrule(javaPackage string) *string {
+	if !a.ok {
+		r, err := bazel.FindGenrule(javaPackage, a.workspace)
+		if err != nil {
+			log.Printf("cannot query genrule of %s: %v\n",
+				javaPackage, err)
+		}
+		return r
+	}
+	rule := strings.Replace(javaPackage, ".", "_", -1)
+	root := "//:" + rule
+	if a.kinds[root] == "genrule" {
+		return &root
+	}
+	var found []string
+	for l, kind := range a.kinds {
+		if kind == "genrule" && strings.HasSuffix(l, ":"+rule) {
+			found = append(found, l)
+		}
+	}
+	if len(found) == 1 {
+		return &found[0]
+	}
+	return nil
+}
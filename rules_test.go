@@ -0,0 +1,119 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+func TestQueryRules(t *testing.T) {
+	calls := filepath.Join(t.TempDir(), "calls")
+	fakeTools(t, `echo "$@" >> `+calls+`
+case "$*" in *--output=xml*) cat <<EOF
+<?xml version="1.1" encoding="UTF-8" standalone="no"?>
+<query version="2">
+    <rule class="java_library" location="/ws/BUILD:1:13" name="//:ui_web">
+        <string name="name" value="ui_web"/>
+        <list name="srcs">
+            <label value="//:ui/web/src/main/java/org/a/A.java"/>
+        </list>
+    </rule>
+    <rule class="genrule" location="/ws/BUILD:9:8" name="//:org_b">
+        <string name="name" value="org_b"/>
+    </rule>
+    <rule class="genrule" location="/ws/rest/BUILD:1:8" name="//rest:client">
+        <string name="name" value="client"/>
+    </rule>
+    <rule class="genrule" location="/ws/wsdl/BUILD:1:8" name="//wsdl:org_c">
+        <string name="name" value="org_c"/>
+    </rule>
+    <rule class="java_library" location="/ws/ui/BUILD:1:13" name="//ui/app:app">
+        <string name="name" value="app"/>
+        <list name="srcs">
+            <label value="//ui/app:src/main/java/org/d/D.java"/>
+        </list>
+    </rule>
+</query>
+EOF
+esac
+`, "exit 0\n")
+	rules := queryRules(t.TempDir(), []string{"//rest:client", "//rest:gone"},
+		[]index.JavaClass{{Name: "org.a.A"}, {Name: "org.d.D"}})
+	for class, want := range map[string]string{
+		"org.a.A": "//:ui_web",
+		"org.d.D": "//ui/app:app",
+	} {
+		r := rules.withSrcs(index.JavaClass{Name: class})
+		if r == nil || *r != want {
+			t.Fatalf("want %s providing %s but got %v\n", want,
+				class, r)
+		}
+	}
+	for pkg, want := range map[string]string{
+		"org.b": "//:org_b",
+		"org.c": "//wsdl:org_c",
+	} {
+		if r := rules.genrule(pkg); r == nil || *r != want {
+			t.Fatalf("want genrule %s but got %v\n", want, r)
+		}
+	}
+	buf, err := ioutil.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "attr('srcs', 'org.a.A|org.d.D', //...)"
+	if !strings.Contains(string(buf), want) {
+		t.Fatalf("want query for %s but got %s\n", want, buf)
+	}
+	for _, tt := range []struct {
+		rule string
+		want bool
+	}{
+		{"ui_web", true},
+		{"//:ui_web", true},
+		{"missing", false},
+		{"//rest:client", true},
+		{"//rest:gone", false},
+	} {
+		if got := rules.Exists(tt.rule); tt.want != got {
+			t.Fatalf("%s: want %v but got %v\n", tt.rule, tt.want,
+				got)
+		}
+	}
+	count := func() int {
+		buf, err := ioutil.ReadFile(calls)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Count(string(buf), "\n")
+	}
+	if n := count(); n != 1 {
+		t.Fatalf("want a single bazel query but got %d\n", n)
+	}
+	// anything else needs a query of its own
+	rules.Exists("//other:other")
+	if n := count(); n != 2 {
+		t.Fatalf("want 2 bazel queries but got %d\n", n)
+	}
+}
+
+func TestFindNested(t *testing.T) {
+	// the single query fails, lookups query bazel one by one
+	fakeTools(t, `case "$*" in
+*--output=xml*) exit 1;;
+*kind\(genrule*) echo "genrule rule //wsdl:org_c";;
+*attr\(\'srcs\'*) echo "//ui/app:app";;
+esac
+`, "exit 0\n")
+	rules := queryRules(t.TempDir(), nil, nil)
+	if r := rules.withSrcs(index.JavaClass{Name: "org.d.D"}); r == nil ||
+		*r != "//ui/app:app" {
+		t.Fatalf("want //ui/app:app but got %v\n", r)
+	}
+	if r := rules.genrule("org.c"); r == nil || *r != "//wsdl:org_c" {
+		t.Fatalf("want //wsdl:org_c but got %v\n", r)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"log"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+	"github.com/jhinrichsen/bazel-kaizen/parser"
+)
+
+// locate a runfile in the workspace, returning its owning package and the
+// path relative to that package. Runfiles paths start with the workspace
+// name, which is dropped if necessary.
+func runfileOwner(workspace string, runfile string) (string, string, bool) {
+	candidates := []string{runfile}
+	if i := strings.Index(runfile, "/"); i >= 0 {
+		candidates = append(candidates, runfile[i+1:])
+	}
+	for _, c := range candidates {
+		if !canRead(filepath.Join(workspace, c)) {
+			continue
+		}
+		// the nearest directory containing a BUILD file owns the file
+		for dir := path.Dir(c); ; dir = path.Dir(dir) {
+			if dir == "." {
+				dir = ""
+			}
+			if canRead(filepath.Join(workspace, dir, "BUILD")) ||
+				canRead(filepath.Join(workspace, dir,
+					"BUILD.bazel")) {
+				rel := strings.TrimPrefix(c[len(dir):], "/")
+				return dir, rel, true
+			}
+			if dir == "" {
+				break
+			}
+		}
+	}
+	return "", "", false
+}
+
+// fixes for data files missing at test runtime
+func healRunfiles(rs []parser.Runfile, workspace string,
+	exists func(label string) bool) []edit.Edit {
+	var edits []edit.Edit
+	for _, r := range rs {
+		if r.Test == "" {
+			log.Printf("cannot attribute missing runfile %s to a "+
+				"test\n", r.Path)
+			continue
+		}
+		owner, file, ok := runfileOwner(workspace, r.Path)
+		if !ok {
+			log.Printf("runfile %s of %s not found in workspace\n",
+				r.Path, r.Test)
+			continue
+		}
+		log.Printf("runfile %s of %s is owned by //%s\n", file,
+			r.Test, owner)
+		edits = append(edits,
+			edit.AddData(r.Test, owner, file, exists)...)
+	}
+	return edits
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+	"github.com/jhinrichsen/bazel-kaizen/parser"
+)
+
+func TestProblemsRunfile(t *testing.T) {
+	lines := `==================== Test output for //ui/web:web_test:
+java.io.IOException: Cannot find runfile: __main__/data/users.csv
+`
+	ps := parser.Problems(*bufio.NewScanner(strings.NewReader(lines)))
+	want := parser.Runfile{Test: "//ui/web:web_test",
+		Path: "__main__/data/users.csv",
+		Line: "java.io.IOException: Cannot find runfile: " +
+			"__main__/data/users.csv"}
+	if len(ps.MissingRunfile) != 1 || ps.MissingRunfile[0] != want {
+		t.Fatalf("want %+v but got %+v\n", want, ps.MissingRunfile)
+	}
+}
+
+func TestHealRunfiles(t *testing.T) {
+	dir := t.TempDir()
+	fixtureFiles(t, dir, map[string]string{"data/BUILD": "",
+		"data/csv/users.csv": "", "ui/web/BUILD": "",
+		"ui/web/testdata/a.txt": ""})
+	rs := []parser.Runfile{
+		{Test: "//ui/web:web_test", Path: "__main__/ui/web/testdata/a.txt"},
+		{Test: "//ui/web:web_test", Path: "__main__/data/csv/users.csv"},
+	}
+	want := []edit.Edit{
+		{Command: "add data testdata/a.txt", Target: "//ui/web:web_test"},
+		{Command: "new filegroup csv_users_csv", Target: "//data:__pkg__"},
+		{Command: "add srcs csv/users.csv", Target: "//data:csv_users_csv"},
+		{Command: "add visibility //ui/web:__pkg__", Target: "//data:csv_users_csv"},
+		{Command: "add data //data:csv_users_csv", Target: "//ui/web:web_test"},
+	}
+	for _, exists := range []bool{false, true} {
+		edits := healRunfiles(rs, dir, func(string) bool {
+			return exists
+		})
+		// a second run finds the filegroup of the first
+		w := want
+		if exists {
+			w = append([]edit.Edit{want[0]}, want[2:]...)
+		}
+		if len(edits) != len(w) {
+			t.Fatalf("want %+v but got %+v\n", w, edits)
+		}
+		for i := range w {
+			if w[i] != edits[i] {
+				t.Fatalf("want %s but got %s\n", w[i], edits[i])
+			}
+			if err := edit.Validate(edits[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
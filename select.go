@@ -0,0 +1,69 @@
+package main
+
+import (
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+)
+
+var REDepsAttribute = regexp.MustCompile(`(?m)^\s*deps = `)
+
+// form of the deps attribute in a rule definition as printed by bazel query
+// --output=build: "" if missing, "list" for a plain list, "concat" for a list
+// concatenated with select(), or "select" if there is no plain list at all
+func depsForm(build string) string {
+	loc := REDepsAttribute.FindStringIndex(build)
+	if loc == nil {
+		return ""
+	}
+	value := build[loc[1]:]
+	if !strings.HasPrefix(value, "select(") {
+		return "list"
+	}
+	// skip the select() expression
+	depth := 0
+	quoted := false
+	for i, r := range value {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case quoted:
+		case r == '(' || r == '[' || r == '{':
+			depth++
+		case r == ')' || r == ']' || r == '}':
+			depth--
+			if depth == 0 {
+				rest := strings.TrimSpace(value[i+1:])
+				if strings.HasPrefix(rest, "+ [") {
+					return "concat"
+				}
+				return "select"
+			}
+		}
+	}
+	return "select"
+}
+
+// buildozer appends deps to a plain list, including a list concatenated
+// with select(). Deps declared by select() only would end up outside of any
+// branch, so these edits are turned into manual instructions.
+func selectAware(edits []edit.Edit, rule string, form string) []edit.Edit {
+	if form != "select" {
+		return edits
+	}
+	var es []edit.Edit
+	for _, e := range edits {
+		if e.Target == rule && strings.HasPrefix(e.Command, "add deps ") {
+			log.Printf("%s declares deps using select() only, "+
+				"manually add %s to the branch of the failing "+
+				"configuration, or change deps into "+
+				"[...] + select(...)\n", rule,
+				strings.TrimPrefix(e.Command, "add deps "))
+			continue
+		}
+		es = append(es, e)
+	}
+	return es
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+)
+
+func TestDepsForm(t *testing.T) {
+	for _, tt := range []struct {
+		build, want string
+	}{
+		{`java_library(
+  name = "a",
+  srcs = ["A.java"],
+)`, ""},
+		{`java_library(
+  name = "a",
+  deps = ["//:b"],
+)`, "list"},
+		{`java_library(
+  name = "a",
+  deps = select({"//conditions:default": ["//:b"]}),
+)`, "select"},
+		{`java_library(
+  name = "a",
+  deps = select({"//:x": ["//:b"], "//conditions:default": []}) + ["//:c"],
+)`, "concat"},
+	} {
+		got := depsForm(tt.build)
+		if tt.want != got {
+			t.Fatalf("want %q but got %q for %s\n", tt.want, got,
+				tt.build)
+		}
+	}
+}
+
+func TestSelectAware(t *testing.T) {
+	edits := []edit.Edit{
+		{Command: "new java_library b", Target: "__pkg__"},
+		{Command: "add deps //:b", Target: "a"},
+	}
+	if got := selectAware(edits, "a", "list"); len(got) != 2 {
+		t.Fatalf("want edits unchanged but got %+v\n", got)
+	}
+	got := selectAware(edits, "a", "select")
+	if len(got) != 1 || got[0] != edits[0] {
+		t.Fatalf("want add deps removed but got %+v\n", got)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/bazel"
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+	"github.com/jhinrichsen/bazel-kaizen/index"
+	"github.com/jhinrichsen/bazel-kaizen/parser"
+)
+
+// bazel query --output=build prints label lists on a single line
+var (
+	REListAttribute = regexp.MustCompile(`^\s*(\w+) = \[(.*)\],?$`)
+	RELabel         = regexp.MustCompile(`"([^"]*//[^"]+)"`)
+)
+
+// StaleDep is a dep on a missing package or target
+type StaleDep struct {
+	Attribute string // such as deps or runtime_deps
+	Label     string
+}
+
+// deps on a missing label in a rule definition as printed by bazel query
+// --output=build. A missing package matches all labels within it.
+func staleDeps(m parser.MissingTarget, build string) []StaleDep {
+	var ds []StaleDep
+	for _, line := range strings.Split(build, "\n") {
+		matches := REListAttribute.FindStringSubmatch(line)
+		if len(matches) == 0 {
+			continue
+		}
+		for _, l := range RELabel.FindAllStringSubmatch(matches[2], -1) {
+			if l[1] == m.Label || m.Package &&
+				strings.HasPrefix(l[1], m.Label+":") {
+				ds = append(ds, StaleDep{matches[1], l[1]})
+			}
+		}
+	}
+	return ds
+}
+
+// name of the rule a label refers to, //a/b is short for //a/b:b
+func labelName(label string) string {
+	if i := strings.LastIndex(label, ":"); i >= 0 {
+		return label[i+1:]
+	}
+	return label[strings.LastIndex(label, "/")+1:]
+}
+
+// source dependency whose module is package pkg, production ones first
+func (h Healer) sourceModule(pkg string) (index.Dependency, bool) {
+	for _, deps := range [][]index.Dependency{index.Production(h.Deps),
+		h.Deps} {
+		for _, d := range deps {
+			switch d.Kind {
+			case index.Source, index.KotlinSource, index.ScalaSource:
+			default:
+				continue
+			}
+			if p, ok := modulePackage(h.Workspace, d); ok && p == pkg {
+				return d, true
+			}
+		}
+	}
+	return index.Dependency{}, false
+}
+
+// edits generating the missing rule of a stale dep from the sources of a
+// module
+func (h Healer) missingRule(label string, d index.Dependency) []edit.Edit {
+	pkg := edit.LabelPackage(label)
+	d.Name = labelName(label)
+	var rule func(index.Dependency) []edit.Edit
+	switch d.Kind {
+	case index.KotlinSource:
+		rule = edit.NewKotlinLibrary
+	case index.ScalaSource:
+		rule = edit.NewScalaLibrary
+	default:
+		rule = edit.NewJavaLibrary
+	}
+	return append(inModule(rule, d, pkg, h.DefaultAttributes),
+		setAttributes("//"+pkg+":"+d.Name, h.DefaultAttributes)...)
+}
+
+// library rules of an existing package
+func packageLibraries(pkg string, workspace string) []string {
+	return bazel.QueryLabels("kind('java_library|kt_jvm_library|"+
+		"scala_library', //"+pkg+":all)", workspace)
+}
+
+// the rule building the module of package pkg: one generated within this
+// run, or the single library of the package, preferring the one named after
+// the package, such as //ui/web:web. Empty if there is none, or several.
+func (h Healer) moduleRule(pkg string, exists bool,
+	created map[string]bool) string {
+	var found []string
+	for l := range created {
+		if strings.HasPrefix(l, "//"+pkg+":") {
+			found = append(found, l)
+		}
+	}
+	if len(found) == 0 && exists {
+		found = packageLibraries(pkg, h.Workspace)
+	}
+	if len(found) == 1 {
+		return found[0]
+	}
+	for _, l := range found {
+		if l == "//"+pkg+":"+labelName("//"+pkg) {
+			return l
+		}
+	}
+	if len(found) > 1 {
+		log.Printf("package %s has several libraries %v\n", pkg, found)
+	}
+	return ""
+}
+
+// suggestions for a dep on a missing package or target: point the dep at
+// the rule building the module of its package, generate that rule if there
+// is none, otherwise remove the dep. Each module gets one rule at most,
+// rules generated within this run are in created.
+func (h Healer) healMissingTarget(m parser.MissingTarget,
+	created map[string]bool) []Suggestion {
+	if m.From == "" {
+		log.Printf("%s does not exist, and the rule depending on it "+
+			"is unknown\n", m.Label)
+		return nil
+	}
+	ds := staleDeps(m, bazel.RuleDefinition(m.From, h.Workspace))
+	if len(ds) == 0 && !m.Package {
+		ds = []StaleDep{{"deps", m.Label}}
+	}
+	what := "target"
+	if m.Package {
+		what = "package"
+	}
+	var ss []Suggestion
+	for _, s := range ds {
+		pkg := edit.LabelPackage(s.Label)
+		d, ok := h.sourceModule(pkg)
+		if !ok || !strings.HasPrefix(s.Label, "//") {
+			ss = append(ss, Suggestion{Rule: m.From,
+				Action: RemoveDep, Dep: s.Label, Provider: "stale",
+				Reason: fmt.Sprintf("no such %s %s, and no sources "+
+					"to build it from", what, m.Label),
+				Confidence: index.Medium,
+				Evidence:   []string{m.Line},
+				Edits: []edit.Edit{{Command: "remove " +
+					s.Attribute + " " + s.Label,
+					Target: m.From}}})
+			continue
+		}
+		// a missing package has no rules, a missing target may have
+		// siblings building the module
+		exists := !m.Package && edit.LabelPackage(m.Label) == pkg
+		if r := h.moduleRule(pkg, exists, created); r != "" {
+			ss = append(ss, Suggestion{Rule: m.From,
+				Action: ReplaceDep, Dep: r, Provider: "stale",
+				Reason: fmt.Sprintf("no such %s %s, %s builds the "+
+					"sources of %s", what, m.Label, r, d.Name),
+				Confidence: index.Medium,
+				Evidence:   []string{m.Line},
+				Edits: []edit.Edit{{Command: "replace " +
+					s.Attribute + " " + s.Label + " " + r,
+					Target: m.From}}})
+			continue
+		}
+		created[s.Label] = true
+		ss = append(ss, Suggestion{Rule: m.From, Action: CreateRule,
+			Dep: s.Label, Provider: "index",
+			Reason: fmt.Sprintf("no such %s %s, package %s holds the "+
+				"sources of %s", what, m.Label, pkg, d.Name),
+			Confidence: index.Medium, Evidence: []string{m.Line},
+			Edits: h.missingRule(s.Label, d)})
+	}
+	if len(ss) == 0 {
+		log.Printf("%s depends on missing %s %s, but not directly\n",
+			m.From, what, m.Label)
+	}
+	return ss
+}
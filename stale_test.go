@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bufio"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+	"github.com/jhinrichsen/bazel-kaizen/index"
+	"github.com/jhinrichsen/bazel-kaizen/parser"
+)
+
+func TestProblemsMissingTarget(t *testing.T) {
+	lines := `ERROR: /ws/app/BUILD:3:13: no such package 'ui/web': BUILD file not found in any of the following directories. Add a BUILD file to a directory to mark it as a package.
+ - /ws/ui/web and referenced by '//app:app'
+ERROR: /ws/app/BUILD:3:13: no such target '//lib:gone': target 'gone' not declared in package 'lib' defined by /ws/lib/BUILD and referenced by '//app:app'
+ERROR: Analysis of target '//app:app' failed; build aborted: no such package 'ui/web': BUILD file not found in any of the following directories.
+`
+	ps := parser.Problems(*bufio.NewScanner(strings.NewReader(lines)))
+	var got [][2]string
+	for _, m := range ps.MissingTarget {
+		got = append(got, [2]string{m.Label, m.From})
+	}
+	want := [][2]string{{"//ui/web", "//app:app"}, {"//lib:gone", "//app:app"}}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("want %v but got %v\n", want, got)
+	}
+	if !ps.MissingTarget[0].Package || ps.MissingTarget[1].Package {
+		t.Fatalf("want a missing package and target but got %+v\n",
+			ps.MissingTarget)
+	}
+}
+
+func TestHealMissingTarget(t *testing.T) {
+	fakeTools(t, `case "$*" in
+*//app:app*) cat <<EOF
+java_library(
+  name = "app",
+  deps = ["//ui/web:web", "//lib:gone", "//core:gone"],
+  runtime_deps = ["//ui/web:web_runtime"],
+)
+EOF
+;;
+*//core:all*) echo //core:core
+;;
+esac
+exit 0
+`, "exit 0\n")
+	ws := t.TempDir()
+	module := filepath.Join(ws, "ui/web")
+	core := filepath.Join(ws, "core")
+	h := Healer{
+		Workspace: ws,
+		Deps: []index.Dependency{{Name: "ui_web", Kind: index.Source,
+			ExternalReference: module + "/src/main/java/",
+			Module:            module,
+			Resources:         classes("org.a.A")},
+			{Name: "core", Kind: index.Source,
+				ExternalReference: core + "/src/main/java/",
+				Module:            core,
+				Resources:         classes("org.c.C")}},
+	}
+	ss, err := h.heal(parser.BuildProblems{
+		MissingTarget: []parser.MissingTarget{
+			{Label: "//ui/web", Package: true, From: "//app:app"},
+			{Label: "//lib:gone", From: "//app:app"},
+			{Label: "//core:gone", From: "//app:app"},
+		}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []edit.Edit
+	for _, s := range ss {
+		got = append(got, s.Edits...)
+	}
+	want := []edit.Edit{
+		{Command: "new java_library web", Target: "//ui/web:__pkg__"},
+		{Command: `set srcs glob(["src/main/java/**/*.java"])`,
+			Target: "//ui/web:web"},
+		{Command: "set visibility //visibility:public",
+			Target: "//ui/web:web"},
+		// one rule per module
+		{Command: "replace runtime_deps //ui/web:web_runtime " +
+			"//ui/web:web", Target: "//app:app"},
+		{Command: "remove deps //lib:gone", Target: "//app:app"},
+		// the existing rule of the module
+		{Command: "replace deps //core:gone //core:core",
+			Target: "//app:app"},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("want %+v but got %+v\n", want, got)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+// update the -store directory, owning all of its files
+func updateStore(dir string, c index.Cache) error {
+	st, err := index.OpenStore(dir)
+	if err != nil {
+		return err
+	}
+	n, err := st.Update(c)
+	if err != nil {
+		return err
+	}
+	log.Printf("updated %d of %d dependencies in store %s\n", n,
+		len(c.Dependencies), dir)
+	return filepath.Walk(dir, func(p string, _ os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		return own(p)
+	})
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+
+	"github.com/jhinrichsen/bazel-kaizen/parser"
+)
+
+// problems of a build log passed through to w unmodified while it is read,
+// as with -stream. The rest of the log is passed through even if the parser
+// stops early, so the build never blocks on a full pipe.
+func streamProblems(r io.Reader, w io.Writer) parser.BuildProblems {
+	tee := io.TeeReader(r, w)
+	ps := parser.Problems(*bufio.NewScanner(tee))
+	io.Copy(ioutil.Discard, tee)
+	return ps
+}
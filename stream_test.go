@@ -0,0 +1,27 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestStreamProblems(t *testing.T) {
+	// the scanner gives up on lines longer than 64 KiB
+	lines := `INFO: Analysed target //:ui_web.
+ERROR: /ws/BUILD:1:1: Building libui_web.jar (1 source file) failed
+ui/web/src/main/java/ui/Fx.java:3: error: package org.a does not exist
+import org.a.A;
+` + strings.Repeat("x", 100000) + `
+INFO: Build completed, 1 failed
+`
+	var out bytes.Buffer
+	ps := streamProblems(strings.NewReader(lines), &out)
+	if out.String() != lines {
+		t.Fatalf("want log passed through unmodified but got %d of %d "+
+			"bytes\n", out.Len(), len(lines))
+	}
+	if len(ps.MissingClass) != 1 || ps.MissingClass[0].Name != "org.a.A" {
+		t.Fatalf("want org.a.A but got %+v\n", ps.MissingClass)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"log"
+	"sort"
+
+	"github.com/jhinrichsen/bazel-kaizen/bazel"
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+)
+
+// rules whose strict deps javacopts control
+var javaRuleClasses = map[string]bool{
+	"java_library": true,
+	"java_binary":  true,
+	"java_test":    true,
+}
+
+// next stage of a strict deps migration: for the first packages with rules
+// not carrying javacopt yet, add all missing direct deps, then the javacopt.
+// Migrating whole packages keeps each stage a reviewable change, rules that
+// carry the javacopt are done.
+func strictStage(rules []bazel.JavaRule, as []Analysis, javacopt string,
+	packages int) []edit.Edit {
+	analyses := make(map[string]Analysis)
+	for _, a := range as {
+		analyses[a.Target] = a
+	}
+	pending := make(map[string][]bazel.JavaRule)
+	total, strict := 0, 0
+	for _, r := range rules {
+		if !javaRuleClasses[r.Class] {
+			continue
+		}
+		total++
+		if contains(r.Javacopts, javacopt) {
+			strict++
+			continue
+		}
+		pkg := edit.LabelPackage(r.Label)
+		pending[pkg] = append(pending[pkg], r)
+	}
+	var pkgs []string
+	for p := range pending {
+		pkgs = append(pkgs, p)
+	}
+	sort.Strings(pkgs)
+	if packages > 0 && len(pkgs) > packages {
+		pkgs = pkgs[:packages]
+	}
+	log.Printf("%d of %d java rules use strict deps, migrating %d of "+
+		"%d remaining packages\n", strict, total, len(pkgs), len(pending))
+	var edits []edit.Edit
+	for _, p := range pkgs {
+		for _, r := range pending[p] {
+			if a := analyses[r.Label]; len(a.Missing) > 0 {
+				edits = append(edits, edit.AddDeps(r.Label, a.Missing...))
+			}
+			edits = append(edits, edit.Edit{Command: "add javacopts " + javacopt,
+				Target: r.Label})
+		}
+	}
+	return edits
+}
+
+func contains(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/bazel"
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+)
+
+func TestStrictStage(t *testing.T) {
+	opt := "--strict_java_deps=ERROR"
+	rules := []bazel.JavaRule{
+		{Class: "java_library", Label: "//core:core",
+			Javacopts: []string{opt}},
+		{Class: "java_library", Label: "//ui/web:web"},
+		{Class: "java_test", Label: "//ui/web:test"},
+		{Class: "kt_jvm_library", Label: "//ui/app:app"},
+		{Class: "java_library", Label: "//util:util"},
+	}
+	as := []Analysis{
+		{Target: "//ui/web:web", Missing: []string{"//core:core"}},
+	}
+	edits := strictStage(rules, as, opt, 1)
+	want := []edit.Edit{
+		{Command: "add deps //core:core", Target: "//ui/web:web"},
+		{Command: "add javacopts " + opt, Target: "//ui/web:web"},
+		{Command: "add javacopts " + opt, Target: "//ui/web:test"},
+	}
+	if len(want) != len(edits) {
+		t.Fatalf("want %+v but got %+v\n", want, edits)
+	}
+	for i := range want {
+		if want[i] != edits[i] {
+			t.Fatalf("want %+v but got %+v\n", want[i], edits[i])
+		}
+	}
+	if n := len(strictStage(rules, as, opt, 0)); n != 4 {
+		t.Fatalf("want 4 edits for all packages but got %d\n", n)
+	}
+}
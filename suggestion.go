@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+// Action is the kind of fix a Suggestion proposes
+type Action string
+
+// Actions
+const (
+	AddDep     Action = "add_dep"     // add Dep to the deps of Rule
+	CreateRule Action = "create_rule" // generate a rule, and depend on it
+	AddPlugin  Action = "add_plugin"  // add an annotation processor plugin
+	AddData    Action = "add_data"    // add a data file to a test
+	Bazel      Action = "bazel"       // bazel's own buildozer command
+	// fix the main_class attribute of a binary
+	SetMainClass Action = "set_main_class"
+	// let a rule see its dep
+	AddVisibility Action = "add_visibility"
+	// remove a dep on a package or target that does not exist
+	RemoveDep Action = "remove_dep"
+	// point a dep on a missing target at the rule building its module
+	ReplaceDep Action = "replace_dep"
+	// correct the label of a .bzl file a BUILD file loads
+	FixLoad Action = "fix_load"
+	// declare a new external artifact, and depend on it
+	AddArtifact Action = "add_artifact"
+	// no fix, a missing class no provider resolves
+	Unresolved Action = "unresolved"
+)
+
+// Suggestion is a fix for a single build problem. All output formats derive
+// from it, the buildozer commands to run are in Edits.
+type Suggestion struct {
+	Rule   string `json:"rule"` // failing rule
+	Action Action `json:"action"`
+	Dep    string `json:"dep,omitempty"`   // label to add, if any
+	Class  string `json:"class,omitempty"` // missing class, if any
+	// source file and line of the problem, if known
+	Location   string           `json:"location,omitempty"`
+	Provider   string           `json:"provider"` // such as srcs or index
+	Reason     string           `json:"reason"`
+	Confidence index.Confidence `json:"confidence"`
+	Evidence   []string         `json:"evidence"`
+	Edits      []edit.Edit      `json:"edits"`
+}
+
+func (a Suggestion) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s confidence %s", a.Confidence, a.Action)
+	if a.Dep != "" {
+		fmt.Fprintf(&sb, " %s", a.Dep)
+	}
+	if a.Rule != "" {
+		fmt.Fprintf(&sb, " to %s", a.Rule)
+	}
+	if a.Location != "" {
+		fmt.Fprintf(&sb, " (%s)", a.Location)
+	}
+	if a.Reason != "" {
+		fmt.Fprintf(&sb, ": %s", a.Reason)
+	}
+	return sb.String()
+}
+
+// print suggestions as JSON, one document per line
+func emitJSON(ss []Suggestion) error {
+	enc := json.NewEncoder(edit.Stdout)
+	for _, s := range ss {
+		if err := enc.Encode(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildozer commands of suggestions, valid and free of duplicates
+func commands(ss []Suggestion) []edit.Edit {
+	var edits []edit.Edit
+	for _, s := range ss {
+		edits = append(edits, s.Edits...)
+	}
+	return edit.Valid(edit.Dedupe(edits))
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+func TestSuggestionString(t *testing.T) {
+	s := Suggestion{Rule: "//ui/web:web", Action: AddDep, Dep: "//:a",
+		Location:   "ui/web/src/main/java/ui/Fx.java:3",
+		Reason:     "class org.a.A is in the srcs of //:a",
+		Confidence: index.High}
+	want := "high confidence add_dep //:a to //ui/web:web " +
+		"(ui/web/src/main/java/ui/Fx.java:3): class org.a.A is in the " +
+		"srcs of //:a"
+	if want != s.String() {
+		t.Fatalf("want %s but got %s\n", want, s)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	add := edit.AddDeps("//ui/web:web", "//:a")
+	ss := []Suggestion{
+		{Action: CreateRule, Edits: append(edit.NewAlias("a", "@a//jar"),
+			add)},
+		{Action: AddDep, Edits: []edit.Edit{add}},
+	}
+	want := len(ss[0].Edits)
+	edits := commands(ss)
+	if len(edits) != want || edits[want-1] != add {
+		t.Fatalf("want alias and %v once but got %v\n", add, edits)
+	}
+}
+
+func TestEmitJSON(t *testing.T) {
+	var out bytes.Buffer
+	edit.Stdout = &out
+	defer func() { edit.Stdout = os.Stdout }()
+	add := edit.AddDeps("//ui/web:web", "//:a")
+	err := emitJSON([]Suggestion{{Rule: "//ui/web:web", Action: AddDep,
+		Dep: "//:a", Class: "org.a.A", Provider: "srcs",
+		Confidence: index.High, Edits: []edit.Edit{add}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Suggestion
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Confidence != index.High || got.Provider != "srcs" ||
+		len(got.Edits) != 1 || got.Edits[0] != add {
+		t.Fatalf("want %v from srcs but got %+v\n", add, got)
+	}
+	want := `"confidence":"high"`
+	if !strings.Contains(out.String(), want) {
+		t.Fatalf("want %s but got %s\n", want, out.String())
+	}
+}
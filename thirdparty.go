@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"path"
+	"strings"
+	"text/template"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+// ThirdParty describes an external artifact for wrapper templates
+type ThirdParty struct {
+	Name     string // maven_jar name, such as com_google_guava_guava
+	Artifact string // group:artifact, such as com.google.guava:guava
+	Actual   string // label of the artifact, such as @guava//jar
+}
+
+func thirdParty(d index.Dependency) ThirdParty {
+	if d.Kind == index.RulesJvmExternal {
+		// @maven//:com_google_guava_guava
+		return ThirdParty{
+			Name:     d.Name[strings.LastIndex(d.Name, ":")+1:],
+			Artifact: groupArtifact(d.Artifact),
+			Actual:   d.Name,
+		}
+	}
+	n := strings.TrimPrefix(d.Name, "//external:")
+	return ThirdParty{
+		Name:     n,
+		Artifact: groupArtifact(d.Artifact),
+		Actual:   "@" + n + "//jar",
+	}
+}
+
+// parse a -third-party template, and try it on an artifact, so that unknown
+// fields fail up front
+func parseWrapper(s string) (*template.Template, error) {
+	tmpl, err := template.New("third-party").Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, ThirdParty{Name: "com_google_guava_guava",
+		Artifact: "com.google.guava:guava", Actual: "@maven//:guava"})
+	if err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// label of the third_party wrapper of an external dependency, the template
+// yields the package, such as third_party/java/{{.Name}}
+func wrapperLabel(tmpl *template.Template, t ThirdParty) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, t); err != nil {
+		return "", err
+	}
+	pkg := strings.Trim(buf.String(), "/")
+	return "//" + pkg + ":" + path.Base(pkg), nil
+}
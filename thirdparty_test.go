@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+	"text/template"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+	"github.com/jhinrichsen/bazel-kaizen/index"
+	"github.com/jhinrichsen/bazel-kaizen/parser"
+)
+
+func TestWrapper(t *testing.T) {
+	d := index.Dependency{
+		Name:     "//external:com_google_guava_guava",
+		Artifact: "com.google.guava:guava:20.0",
+	}
+	tmpl := template.Must(template.New("").Parse(
+		"third_party/java/{{.Name}}"))
+	tp := thirdParty(d)
+	label, err := wrapperLabel(tmpl, tp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "//third_party/java/com_google_guava_guava:" +
+		"com_google_guava_guava"
+	if want != label {
+		t.Fatalf("want %s but got %s\n", want, label)
+	}
+	edits := edit.NewWrapper(label, tp.Actual)
+	for _, e := range edits {
+		if err := edit.Validate(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gen, rest := edit.Phases(edits)
+	if len(gen) != 3 || len(rest) != 0 {
+		t.Fatalf("want wrapper generation only but got %+v %+v\n",
+			gen, rest)
+	}
+	if gen[1].Command != "add exports @com_google_guava_guava//jar" {
+		t.Fatalf("want exports on artifact but got %s\n", gen[1])
+	}
+}
+
+func TestThirdPartyMavenInstall(t *testing.T) {
+	tp := thirdParty(index.Dependency{
+		Name:     "@maven//:com_google_guava_guava",
+		Artifact: "com.google.guava:guava:31.1-jre",
+		Kind:     index.RulesJvmExternal,
+	})
+	want := ThirdParty{
+		Name:     "com_google_guava_guava",
+		Artifact: "com.google.guava:guava",
+		Actual:   "@maven//:com_google_guava_guava",
+	}
+	if want != tp {
+		t.Fatalf("want %+v but got %+v\n", want, tp)
+	}
+}
+
+// templates failing on some artifacts only end healing with an error
+func TestWrapperError(t *testing.T) {
+	fakeTools(t, "exit 0\n", "exit 0\n")
+	tmpl, err := parseWrapper(`third_party/{{if eq .Name "a"}}` +
+		`{{slice .Name 0 9}}{{end}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := Healer{Workspace: t.TempDir(), Wrapper: tmpl,
+		Deps: []index.Dependency{{Name: "@maven//:a",
+			Artifact: "org.a:a:1.0", Kind: index.RulesJvmExternal,
+			Resources: classes("org.a.A")}},
+		Providers: []string{"index"},
+	}
+	_, err = h.heal(parser.BuildProblems{BazelRule: "//app:app",
+		MissingClass: []index.JavaClass{{Name: "org.a.A"}}})
+	if err == nil {
+		t.Fatalf("want error of -third-party template\n")
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+// Cause is the probable reason a class does not resolve
+type Cause string
+
+// Causes, in order of report
+const (
+	Generated       Cause = "likely generated code"
+	Typo            Cause = "likely typo"
+	Banned          Cause = "provider exists but is banned"
+	MissingArtifact Cause = "likely missing artifact"
+)
+
+var causes = []Cause{Generated, Typo, Banned, MissingArtifact}
+
+// classes listed per cause, the rest is counted only
+const examples = 5
+
+// names of classes generated by annotation processors and code generators,
+// such as AutoValue_User, DaggerAppComponent, or UserServiceGrpc
+var (
+	generatedPrefixes = []string{"AutoValue_", "Dagger"}
+	generatedSuffixes = []string{"_", "_Factory", "_MembersInjector",
+		"_Impl", "MapperImpl", "Grpc", "OuterClass"}
+	generatedPackages = []string{"generated", "gen", "codegen"}
+)
+
+// generated reports whether a class looks generated
+func (a *indexProvider) generated(j index.JavaClass) (string, bool) {
+	if l, ok := codegenLabel(a.h.Generators, j.Package()); ok {
+		return fmt.Sprintf("-codegen maps it to %s, which does not "+
+			"exist", l), true
+	}
+	simple := j.Name[strings.LastIndex(j.Name, ".")+1:]
+	for _, p := range generatedPrefixes {
+		if strings.HasPrefix(simple, p) && len(simple) > len(p) {
+			return "named like generated code", true
+		}
+	}
+	for _, s := range generatedSuffixes {
+		if strings.HasSuffix(simple, s) && len(simple) > len(s) {
+			return "named like generated code", true
+		}
+	}
+	for _, segment := range strings.Split(j.Package(), ".") {
+		for _, p := range generatedPackages {
+			if segment == p {
+				return "in a package of generated code", true
+			}
+		}
+	}
+	return "", false
+}
+
+// banned reports a dependency providing a class nonetheless
+func (a *indexProvider) banned(j index.JavaClass) (string, bool) {
+	for _, d := range a.candidates(j) {
+		if !d.Provides(index.Class, j.Name) {
+			continue
+		}
+		why := "index is not in -providers"
+		if f, ok := a.h.PackageFilters[d.Name]; ok &&
+			!f.allows(j.Package()) {
+			why = "-package-filter"
+		} else if d.TestOnly {
+			why = "test only"
+		}
+		return fmt.Sprintf("%s provides it, %s", d.Name, why), true
+	}
+	return "", false
+}
+
+// typo reports an indexed class of a similar name
+func (a *indexProvider) typo(j index.JavaClass) (string, bool) {
+	for _, c := range a.similar(j) {
+		if c != j.Name && distance(c, j.Name) <= 2 {
+			return "did you mean " + c, true
+		}
+	}
+	return "", false
+}
+
+// classes a typo may stand for: those of the same simple name, and those of
+// the same package. -store is searched below the parent package, which
+// holds packages of a similar name.
+func (a *indexProvider) similar(j index.JavaClass) []string {
+	if a.bySimpleName == nil {
+		a.bySimpleName = make(map[string][]string)
+		a.byPackage = make(map[string][]string)
+		for _, d := range a.h.Deps {
+			for _, c := range d.Named(index.Class) {
+				s := c[strings.LastIndex(c, ".")+1:]
+				a.bySimpleName[s] = append(a.bySimpleName[s], c)
+				p := index.StripLast(c)
+				a.byPackage[p] = append(a.byPackage[p], c)
+			}
+		}
+	}
+	simple := j.Name[strings.LastIndex(j.Name, ".")+1:]
+	cs := append(append([]string{}, a.bySimpleName[simple]...),
+		a.byPackage[j.Package()]...)
+	if a.h.Store != nil && j.Package() != "" {
+		prefix := j.Package() + "."
+		if parent := index.StripLast(j.Package()); parent != "" {
+			prefix = parent + "."
+		}
+		found, err := a.h.Store.Classes(prefix)
+		if err != nil {
+			log.Printf("cannot look up classes of %s: %v\n",
+				prefix, err)
+		}
+		cs = append(cs, found...)
+	}
+	return cs
+}
+
+// probable cause of an unresolved class, and why
+func (a *indexProvider) cause(j index.JavaClass) (Cause, string) {
+	if why, ok := a.banned(j); ok {
+		return Banned, why
+	}
+	if why, ok := a.generated(j); ok {
+		return Generated, why
+	}
+	if why, ok := a.typo(j); ok {
+		return Typo, why
+	}
+	return MissingArtifact, "known to no provider"
+}
+
+// log unresolved classes grouped by their probable cause, and append them
+// to the unresolved suggestions of the healer, if any
+func (a *indexProvider) report(js []index.JavaClass) {
+	if len(js) == 0 {
+		return
+	}
+	byCause := make(map[Cause][]string)
+	for _, j := range js {
+		c, why := a.cause(j)
+		byCause[c] = append(byCause[c], j.Name+" ("+why+")")
+		if a.h.Unresolved != nil {
+			*a.h.Unresolved = append(*a.h.Unresolved, Suggestion{
+				Rule: a.rule, Action: Unresolved, Class: j.Name,
+				Location: j.Location, Provider: "none",
+				Reason:   string(c) + ", " + why,
+				Evidence: j.Log, Edits: []edit.Edit{}})
+		}
+	}
+	log.Printf("*sniff* cannot resolve %d classes\n", len(js))
+	for _, c := range causes {
+		if len(byCause[c]) == 0 {
+			continue
+		}
+		ss := byCause[c]
+		log.Printf("  %s: %d\n", c, len(ss))
+		for i, s := range ss {
+			if i == examples {
+				log.Printf("    and %d more\n", len(ss)-examples)
+				break
+			}
+			log.Printf("    %s\n", s)
+		}
+	}
+}
+
+// Levenshtein distance of two strings. Strings differing in length by more
+// than 2 are not compared, their difference in length is returned.
+func distance(a, b string) int {
+	if d := len(a) - len(b); d > 2 || d < -2 {
+		return abs(d)
+	}
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+func TestCause(t *testing.T) {
+	fakeTools(t, "exit 0\n", "exit 0\n")
+	h := Healer{
+		Deps: []index.Dependency{
+			{Name: "//:framework", Resources: classes(
+				"org.company.framework.A")},
+			{Name: "@maven//:fat", Resources: classes(
+				"com.fat.shaded.B")},
+			{Name: "@maven//:a_tests", TestOnly: true,
+				Resources: classes("org.a.ATest")},
+		},
+		PackageFilters: map[string]PackageFilter{
+			"@maven//:fat": {Exclude: []string{"com.fat.shaded"}},
+		},
+	}
+	idx := &indexProvider{h: h}
+	for _, tt := range []struct {
+		class string
+		want  Cause
+	}{
+		{"org.company.framwork.A", Typo},
+		{"com.fat.shaded.B", Banned},
+		{"org.a.ATest", Banned},
+		{"ui.web.DaggerAppComponent", Generated},
+		{"ui.web.generated.Api", Generated},
+		{"org.apache.commons.lang3.StringUtils", MissingArtifact},
+	} {
+		got, why := idx.cause(index.JavaClass{Name: tt.class})
+		if tt.want != got {
+			t.Fatalf("%s: want %s but got %s (%s)\n", tt.class,
+				tt.want, got, why)
+		}
+	}
+}
+
+func TestDistance(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want int
+	}{
+		{"org.a.A", "org.a.A", 0},
+		{"org.compnay.A", "org.company.A", 2},
+		{"org.a.A", "org.a.AB", 1},
+		{"a", "abcdef", 5},
+	} {
+		if got := distance(tt.a, tt.b); tt.want != got {
+			t.Fatalf("%s %s: want %d but got %d\n", tt.a, tt.b,
+				tt.want, got)
+		}
+	}
+}
+
+func TestReportUnresolved(t *testing.T) {
+	fakeTools(t, "exit 0\n", "exit 0\n")
+	var unresolved []Suggestion
+	idx := &indexProvider{h: Healer{Unresolved: &unresolved},
+		rule: "//ui/web:web"}
+	idx.report([]index.JavaClass{{Name: "ui.web.DaggerAppComponent",
+		Location: "ui/web/src/main/java/ui/web/App.java:3"}})
+	if len(unresolved) != 1 || unresolved[0].Action != Unresolved ||
+		unresolved[0].Rule != "//ui/web:web" ||
+		unresolved[0].Class != "ui.web.DaggerAppComponent" ||
+		len(unresolved[0].Edits) != 0 {
+		t.Fatalf("want 1 unresolved class but got %+v\n", unresolved)
+	}
+}
+
+func TestTypoStore(t *testing.T) {
+	st, err := index.OpenStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = st.Update(index.Cache{Dependencies: []index.Dependency{
+		{Name: "//:framework", Resources: classes(
+			"org.company.framework.Widget")},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx := &indexProvider{h: Healer{Store: st}}
+	for _, class := range []string{
+		"org.company.framework.Widgit",
+		"org.company.framwork.Widget",
+	} {
+		why, ok := idx.typo(index.JavaClass{Name: class})
+		if !ok || why != "did you mean org.company.framework.Widget" {
+			t.Fatalf("%s: want typo but got %q\n", class, why)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/jhinrichsen/bazel-kaizen/parser"
+)
+
+// Usage of one run, as appended to the -stats file. Counts only, nothing
+// names the workspace, its rules, or its user, and nothing leaves the
+// machine unless collected.
+type Usage struct {
+	Date        string `json:"date"`
+	Command     string `json:"command"` // heal, or log for a given log
+	Missing     int    `json:"missing_classes"`
+	Unresolved  int    `json:"unresolved_classes"`
+	Other       int    `json:"other_problems"` // runfiles, visibility, ...
+	Suggestions int    `json:"suggestions"`
+	Commands    int    `json:"commands"`
+	Applied     bool   `json:"applied"`
+}
+
+// usage of a run healing problems. A class is resolved if a suggestion
+// resolves its package, as kaizen resolves one class per package.
+func usage(command string, ps parser.BuildProblems, ss []Suggestion,
+	commands int, applied bool, now time.Time) Usage {
+	resolved := make(map[string]bool)
+	for _, s := range ss {
+		if s.Class != "" {
+			resolved[s.Class] = true
+		}
+	}
+	packages := make(map[string]bool)
+	for _, j := range ps.MissingClass {
+		if resolved[j.Name] {
+			packages[j.Package()] = true
+		}
+	}
+	u := Usage{Date: now.Format("2006-01-02"), Command: command,
+		Missing: len(ps.MissingClass), Suggestions: len(ss),
+		Commands: commands, Applied: applied,
+		Other: len(ps.MissingRunfile) + len(ps.MissingMain) +
+			len(ps.Invisible) + len(ps.MissingTarget) +
+			len(ps.Unloadable)}
+	for _, j := range ps.MissingClass {
+		if !packages[j.Package()] {
+			u.Unresolved++
+		}
+	}
+	return u
+}
+
+// append usage to a statistics file, one JSON document per line
+func appendUsage(filename string, u Usage) error {
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY,
+		0644)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(u); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return own(filename)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+	"github.com/jhinrichsen/bazel-kaizen/parser"
+)
+
+func TestUsage(t *testing.T) {
+	ps := parser.BuildProblems{
+		MissingClass: []index.JavaClass{{Name: "org.a.A"},
+			{Name: "org.a.B"}, {Name: "org.x.X"}},
+		Invisible: []parser.Visibility{{Target: "//a:b", From: "//c:d"}},
+	}
+	ss := []Suggestion{{Class: "org.a.A", Dep: "//:a"},
+		{Rule: "//a:b", Action: AddVisibility}}
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	want := Usage{Date: "2026-10-16", Command: "heal", Missing: 3,
+		Unresolved: 1, Other: 1, Suggestions: 2, Commands: 3}
+	u := usage("heal", ps, ss, 3, false, now)
+	if want != u {
+		t.Fatalf("want %+v but got %+v\n", want, u)
+	}
+
+	filename := filepath.Join(t.TempDir(), ".healdb.stats")
+	for i := 0; i < 2; i++ {
+		if err := appendUsage(filename, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	n := 0
+	for scanner := bufio.NewScanner(f); scanner.Scan(); n++ {
+		var got Usage
+		if err := json.Unmarshal(scanner.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if want != got {
+			t.Fatalf("want %+v but got %+v\n", want, got)
+		}
+	}
+	if n != 2 {
+		t.Fatalf("want 2 runs but got %d\n", n)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/bazel"
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+	"github.com/jhinrichsen/bazel-kaizen/index"
+	"github.com/jhinrichsen/bazel-kaizen/parser"
+)
+
+var (
+	// default_visibility of a package() call
+	REDefaultVisibility = regexp.MustCompile(
+		`\bdefault_visibility\s*=\s*\[([^\]]*)\]`)
+	// string literals of a list
+	REString = regexp.MustCompile(`"([^"]*)"|'([^']*)'`)
+)
+
+// visibility of a rule without a visibility attribute, the
+// default_visibility of its package, //visibility:private if none
+func defaultVisibility(workspace string, pkg string) []string {
+	buf, err := ioutil.ReadFile(filepath.Join(workspace,
+		buildFile(workspace, pkg)))
+	if err != nil {
+		log.Printf("cannot read default visibility of %s: %v\n", pkg,
+			err)
+		return nil
+	}
+	build := REComment.ReplaceAllString(string(buf), "")
+	m := REDefaultVisibility.FindStringSubmatch(build)
+	if m == nil {
+		return []string{"//visibility:private"}
+	}
+	var vs []string
+	for _, l := range REString.FindAllStringSubmatch(m[1], -1) {
+		vs = append(vs, l[1]+l[2])
+	}
+	return vs
+}
+
+// edits spelling out the effective visibility of a rule before more is
+// added. A rule without a visibility attribute sees its package's
+// default_visibility, which the first added label would replace.
+func seedVisibility(target string, workspace string) []edit.Edit {
+	for _, line := range strings.Split(bazel.RuleDefinition(target,
+		workspace), "\n") {
+		m := REListAttribute.FindStringSubmatch(line)
+		if m != nil && m[1] == "visibility" {
+			return nil
+		}
+	}
+	vs := defaultVisibility(workspace, edit.LabelPackage(target))
+	if len(vs) == 0 || len(vs) == 1 && vs[0] == "//visibility:private" {
+		// the package of a rule always sees it
+		return nil
+	}
+	return []edit.Edit{{Command: "set visibility " +
+		strings.Join(vs, " "), Target: target}}
+}
+
+// visibility policies of -visibility, group=<package_group> adds the
+// package of the depending rule to a package_group instead
+var visibilityPolicies = []string{"package", "subpackages", "public", "none"}
+
+// check a -visibility policy
+func parseVisibility(policy string) error {
+	if contains(visibilityPolicies, policy) {
+		return nil
+	}
+	if g := strings.TrimPrefix(policy, "group="); g != policy &&
+		edit.ValidLabel(g) && strings.HasPrefix(g, "//") {
+		return nil
+	}
+	return fmt.Errorf("unknown -visibility %q, want %s, or "+
+		"group=//pkg:name", policy, strings.Join(visibilityPolicies,
+		", "))
+}
+
+// edits letting a rule see a dep, according to a policy
+func visibilityEdits(v parser.Visibility, policy string) []edit.Edit {
+	pkg := "//" + edit.LabelPackage(v.From)
+	switch {
+	case policy == "package":
+		return []edit.Edit{{Command: "add visibility " + pkg +
+			":__pkg__", Target: v.Target}}
+	case policy == "subpackages":
+		return []edit.Edit{{Command: "add visibility " + pkg +
+			":__subpackages__", Target: v.Target}}
+	case policy == "public":
+		return []edit.Edit{{Command: "set visibility " +
+			"//visibility:public", Target: v.Target}}
+	case strings.HasPrefix(policy, "group="):
+		g := strings.TrimPrefix(policy, "group=")
+		return []edit.Edit{
+			{Command: "add packages " + pkg, Target: g},
+			{Command: "add visibility " + g, Target: v.Target},
+		}
+	}
+	return nil
+}
+
+// suggestions fixing a visibility error. Rules of external repositories
+// cannot be changed.
+func healVisibility(v parser.Visibility, policy string,
+	workspace string) []Suggestion {
+	if !strings.HasPrefix(v.Target, "//") {
+		log.Printf("%s may not see %s of another repository, use a "+
+			"visible target instead\n", v.From, v.Target)
+		return nil
+	}
+	es := visibilityEdits(v, policy)
+	if len(es) == 0 {
+		log.Printf("%s may not see %s, -visibility %s\n", v.From,
+			v.Target, policy)
+		return nil
+	}
+	if policy != "public" {
+		es = append(seedVisibility(v.Target, workspace), es...)
+	}
+	return []Suggestion{{Rule: v.From, Action: AddVisibility,
+		Dep: v.Target, Provider: "visibility",
+		Reason: fmt.Sprintf("%s is not visible from %s, -visibility "+
+			"%s", v.Target, v.From, policy),
+		Confidence: index.Medium, Evidence: []string{v.Line},
+		Edits: es}}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+	"github.com/jhinrichsen/bazel-kaizen/parser"
+)
+
+func TestProblemsVisibility(t *testing.T) {
+	lines := `ERROR: /ws/c/BUILD:3:13: in java_library rule //c:d: target '//a:b' is not visible from target '//c:d'. Check the visibility declaration of the former target if you think the dependency is legitimate
+ERROR: /ws/e/BUILD:1:13: Visibility error:
+target '//a:b' is not visible from
+target '//e/f:g'
+Recommendation: modify the visibility declaration if you think the dependency is legitimate.
+`
+	ps := parser.Problems(*bufio.NewScanner(strings.NewReader(lines)))
+	var got [][2]string
+	for _, v := range ps.Invisible {
+		got = append(got, [2]string{v.Target, v.From})
+	}
+	want := [][2]string{{"//a:b", "//c:d"}, {"//a:b", "//e/f:g"}}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("want %v but got %v\n", want, got)
+	}
+}
+
+func TestHealVisibility(t *testing.T) {
+	fakeTools(t, "exit 0\n", "exit 0\n")
+	ws := t.TempDir()
+	v := parser.Visibility{Target: "//a:b", From: "//c/d:e"}
+	for _, tt := range []struct {
+		policy string
+		want   []edit.Edit
+	}{
+		{"package", []edit.Edit{{Command: "add visibility //c/d:__pkg__",
+			Target: "//a:b"}}},
+		{"subpackages", []edit.Edit{{
+			Command: "add visibility //c/d:__subpackages__",
+			Target:  "//a:b"}}},
+		{"public", []edit.Edit{{
+			Command: "set visibility //visibility:public",
+			Target:  "//a:b"}}},
+		{"group=//tools:friends", []edit.Edit{
+			{Command: "add packages //c/d", Target: "//tools:friends"},
+			{Command: "add visibility //tools:friends",
+				Target: "//a:b"}}},
+		{"none", nil},
+	} {
+		if err := parseVisibility(tt.policy); err != nil {
+			t.Fatal(err)
+		}
+		var got []edit.Edit
+		for _, s := range healVisibility(v, tt.policy, ws) {
+			got = append(got, edit.Valid(s.Edits)...)
+		}
+		if !reflect.DeepEqual(tt.want, got) {
+			t.Fatalf("%s: want %+v but got %+v\n", tt.policy, tt.want,
+				got)
+		}
+	}
+	external := parser.Visibility{Target: "@maven//:a", From: "//c:d"}
+	if ss := healVisibility(external, "package", ws); len(ss) != 0 {
+		t.Fatalf("want no fix for external target but got %+v\n", ss)
+	}
+	for _, p := range []string{"private", "group=", "group=friends"} {
+		if err := parseVisibility(p); err == nil {
+			t.Fatalf("%s: want error\n", p)
+		}
+	}
+}
+
+func TestHealVisibilityDefault(t *testing.T) {
+	fakeTools(t, `case "$*" in
+*//a:c*) printf 'java_library(\n  name = "c",\n'
+	printf '  visibility = ["//x:__pkg__"],\n)\n';;
+*) printf 'java_library(\n  name = "b",\n)\n';;
+esac
+`, "exit 0\n")
+	ws := t.TempDir()
+	fixtureFiles(t, ws, map[string]string{"a/BUILD": `package(
+    default_visibility = ["//tools:__subpackages__", '//y:__pkg__'],
+)
+`})
+	for _, tt := range []struct {
+		target string
+		want   []edit.Edit
+	}{
+		// the package default survives
+		{"//a:b", []edit.Edit{
+			{Command: "set visibility //tools:__subpackages__ " +
+				"//y:__pkg__", Target: "//a:b"},
+			{Command: "add visibility //c/d:__pkg__",
+				Target: "//a:b"}}},
+		// an explicit visibility is added to
+		{"//a:c", []edit.Edit{{Command: "add visibility //c/d:__pkg__",
+			Target: "//a:c"}}},
+	} {
+		v := parser.Visibility{Target: tt.target, From: "//c/d:e"}
+		var got []edit.Edit
+		for _, s := range healVisibility(v, "package", ws) {
+			got = append(got, edit.Valid(s.Edits)...)
+		}
+		if !reflect.DeepEqual(tt.want, got) {
+			t.Fatalf("%s: want %+v but got %+v\n", tt.target,
+				tt.want, got)
+		}
+	}
+}
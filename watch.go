@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jhinrichsen/bazel-kaizen/bazel"
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+	"github.com/jhinrichsen/bazel-kaizen/index"
+	"github.com/jhinrichsen/bazel-kaizen/parser"
+)
+
+// stamps of the BUILD files and of the sources of a workspace, outside of
+// bazel's output trees and hidden directories
+func workspaceStamps(workspace string) (string, string) {
+	var builds, sources []string
+	f := func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if path != workspace &&
+				(strings.HasPrefix(info.Name(), ".") ||
+					strings.HasPrefix(info.Name(), "bazel-")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch filepath.Ext(info.Name()) {
+		case ".bzl", ".bazel":
+			builds = append(builds, path)
+		case ".java", ".kt", ".scala":
+			sources = append(sources, path)
+		default:
+			if info.Name() == "BUILD" || info.Name() == "WORKSPACE" {
+				builds = append(builds, path)
+			}
+		}
+		return nil
+	}
+	filepath.Walk(workspace, f)
+	return index.Stamp(builds), index.Stamp(sources)
+}
+
+// heal a target whenever the workspace changes, as the watch command does:
+// build it, and print or apply fixes. Changed BUILD files invalidate the
+// queries cached in between. rounds limits the builds, 0 builds forever.
+func (h Healer) watch(target string, interval time.Duration, rounds int,
+	apply bool, journal string) error {
+	bazel.CacheQueries(true)
+	defer bazel.CacheQueries(false)
+	if bazel.QueryOutputBase != "" {
+		log.Printf("warming up query server in %s\n",
+			bazel.QueryOutputBase)
+		bazel.QueryLabels(target, h.Workspace)
+	}
+	var builds, sources string
+	for n := 0; rounds == 0 || n < rounds; {
+		b, s := workspaceStamps(h.Workspace)
+		if n > 0 && b == builds && s == sources {
+			time.Sleep(interval)
+			continue
+		}
+		if n > 0 && b != builds {
+			log.Printf("BUILD files changed, forgetting queries\n")
+			bazel.ForgetQueries()
+		}
+		builds, sources = b, s
+		n++
+		if err := h.watched(target, apply, journal); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// build and heal a target once while watching
+func (h Healer) watched(target string, apply bool, journal string) error {
+	buf, ok, err := bazel.Build(target, h.Workspace)
+	if err != nil {
+		return err
+	}
+	if ok {
+		log.Printf("%s builds, watching\n", target)
+		return nil
+	}
+	ps := parser.Problems(*bufio.NewScanner(bytes.NewReader(buf)))
+	ss, err := h.healAll(ps)
+	if err != nil {
+		return err
+	}
+	edits := commands(ss)
+	log.Printf("%s fails, %d commands\n", target, len(edits))
+	if !apply {
+		gen, rest := edit.Phases(edits)
+		for _, e := range append(gen, rest...) {
+			edit.Emit(e.String())
+		}
+		return nil
+	}
+	if err := applyAll(edits, h.Workspace, journal); err != nil {
+		return err
+	}
+	h.Fixes.record(ss)
+	return h.Fixes.write()
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jhinrichsen/bazel-kaizen/edit"
+)
+
+func TestWorkspaceStamps(t *testing.T) {
+	ws := t.TempDir()
+	fixtureFiles(t, ws, map[string]string{
+		"app/BUILD":                    "",
+		"app/src/main/java/app/A.java": "",
+	})
+	builds, sources := workspaceStamps(ws)
+	touch := func(name string) {
+		later := time.Now().Add(time.Minute)
+		if err := os.Chtimes(filepath.Join(ws, name), later,
+			later); err != nil {
+			t.Fatal(err)
+		}
+	}
+	touch("app/src/main/java/app/A.java")
+	b, s := workspaceStamps(ws)
+	if b != builds || s == sources {
+		t.Fatalf("want changed sources only\n")
+	}
+	touch("app/BUILD")
+	if b, _ = workspaceStamps(ws); b == builds {
+		t.Fatalf("want changed BUILD files\n")
+	}
+	// outputs of bazel are no change
+	fixtureFiles(t, ws, map[string]string{"bazel-out/app/BUILD": ""})
+	if b2, _ := workspaceStamps(ws); b2 != b {
+		t.Fatalf("want bazel-out ignored\n")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	fakeTools(t, `case "$1" in
+build) echo "buildozer 'add deps //:a' //app:app"; exit 1;;
+esac
+exit 0
+`, "exit 0\n")
+	var out bytes.Buffer
+	edit.Stdout = &out
+	defer func() { edit.Stdout = os.Stdout }()
+	h := Healer{Workspace: t.TempDir()}
+	if err := h.watch("//app:app", time.Millisecond, 1, false,
+		""); err != nil {
+		t.Fatal(err)
+	}
+	want := "buildozer 'add deps //:a' '//app:app'\n"
+	if want != out.String() {
+		t.Fatalf("want %s but got %s\n", want, out.String())
+	}
+}
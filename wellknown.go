@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+// WellKnown is a package or class of a famous library and the artifact
+// providing it
+type WellKnown struct {
+	Prefix string // package or class, such as com.google.common
+	// Maven coordinates, group:artifact:version, empty for packages no
+	// single artifact provides
+	Artifact string
+}
+
+// packages of famous libraries by their canonical artifact, shipped with
+// kaizen for classes no dependency of the workspace provides. The longest
+// prefix wins, so packages and classes of other artifacts below a package
+// are listed, too. Versions are a starting point, not a recommendation.
+var wellKnown = []WellKnown{
+	{"ch.qos.logback.classic", "ch.qos.logback:logback-classic:1.5.6"},
+	{"com.fasterxml.jackson.annotation",
+		"com.fasterxml.jackson.core:jackson-annotations:2.17.1"},
+	{"com.fasterxml.jackson.core",
+		"com.fasterxml.jackson.core:jackson-core:2.17.1"},
+	{"com.fasterxml.jackson.databind",
+		"com.fasterxml.jackson.core:jackson-databind:2.17.1"},
+	{"com.google.auto.value",
+		"com.google.auto.value:auto-value-annotations:1.10.4"},
+	{"com.google.common", "com.google.guava:guava:33.2.1-jre"},
+	{"com.google.errorprone.annotations",
+		"com.google.errorprone:error_prone_annotations:2.28.0"},
+	{"com.google.gson", "com.google.code.gson:gson:2.11.0"},
+	{"com.google.protobuf", "com.google.protobuf:protobuf-java:3.25.3"},
+	{"com.squareup.moshi", "com.squareup.moshi:moshi:1.15.1"},
+	{"dagger", "com.google.dagger:dagger:2.51.1"},
+	{"jakarta.annotation",
+		"jakarta.annotation:jakarta.annotation-api:3.0.0"},
+	{"jakarta.inject", "jakarta.inject:jakarta.inject-api:2.0.1"},
+	{"jakarta.persistence",
+		"jakarta.persistence:jakarta.persistence-api:3.1.0"},
+	{"jakarta.servlet", "jakarta.servlet:jakarta.servlet-api:6.1.0"},
+	{"jakarta.validation",
+		"jakarta.validation:jakarta.validation-api:3.1.0"},
+	{"jakarta.ws.rs", "jakarta.ws.rs:jakarta.ws.rs-api:4.0.0"},
+	{"jakarta.xml.bind", "jakarta.xml.bind:jakarta.xml.bind-api:4.0.2"},
+	{"javax.annotation", "com.google.code.findbugs:jsr305:3.0.2"},
+	{"javax.annotation.Generated",
+		"javax.annotation:javax.annotation-api:1.3.2"},
+	{"javax.annotation.ManagedBean",
+		"javax.annotation:javax.annotation-api:1.3.2"},
+	{"javax.annotation.PostConstruct",
+		"javax.annotation:javax.annotation-api:1.3.2"},
+	{"javax.annotation.PreDestroy",
+		"javax.annotation:javax.annotation-api:1.3.2"},
+	{"javax.annotation.Priority",
+		"javax.annotation:javax.annotation-api:1.3.2"},
+	{"javax.annotation.Resource",
+		"javax.annotation:javax.annotation-api:1.3.2"},
+	{"javax.annotation.Resources",
+		"javax.annotation:javax.annotation-api:1.3.2"},
+	// part of the JDK
+	{"javax.annotation.processing", ""},
+	{"javax.annotation.security",
+		"javax.annotation:javax.annotation-api:1.3.2"},
+	{"javax.annotation.sql", "javax.annotation:javax.annotation-api:1.3.2"},
+	{"javax.inject", "javax.inject:javax.inject:1"},
+	{"javax.servlet", "javax.servlet:javax.servlet-api:4.0.1"},
+	{"junit", "junit:junit:4.13.2"},
+	{"lombok", "org.projectlombok:lombok:1.18.32"},
+	{"okhttp3", "com.squareup.okhttp3:okhttp:4.12.0"},
+	{"org.apache.commons.codec", "commons-codec:commons-codec:1.17.0"},
+	{"org.apache.commons.collections4",
+		"org.apache.commons:commons-collections4:4.4"},
+	{"org.apache.commons.io", "commons-io:commons-io:2.16.1"},
+	{"org.apache.commons.lang3", "org.apache.commons:commons-lang3:3.14.0"},
+	{"org.apache.logging.log4j",
+		"org.apache.logging.log4j:log4j-api:2.23.1"},
+	{"org.assertj.core", "org.assertj:assertj-core:3.26.0"},
+	{"org.checkerframework.checker",
+		"org.checkerframework:checker-qual:3.44.0"},
+	{"org.hamcrest", "org.hamcrest:hamcrest:2.2"},
+	{"org.jetbrains.annotations", "org.jetbrains:annotations:24.1.0"},
+	{"org.junit", "junit:junit:4.13.2"},
+	// JUnit 5 spreads over many artifacts
+	{"org.junit.jupiter", ""},
+	{"org.junit.jupiter.api",
+		"org.junit.jupiter:junit-jupiter-api:5.10.2"},
+	{"org.junit.jupiter.engine",
+		"org.junit.jupiter:junit-jupiter-engine:5.10.2"},
+	{"org.junit.jupiter.params",
+		"org.junit.jupiter:junit-jupiter-params:5.10.2"},
+	{"org.junit.platform", ""},
+	{"org.junit.platform.commons",
+		"org.junit.platform:junit-platform-commons:1.10.2"},
+	{"org.junit.platform.engine",
+		"org.junit.platform:junit-platform-engine:1.10.2"},
+	{"org.junit.platform.launcher",
+		"org.junit.platform:junit-platform-launcher:1.10.2"},
+	{"org.junit.platform.suite.api",
+		"org.junit.platform:junit-platform-suite-api:1.10.2"},
+	{"org.junit.vintage", "org.junit.vintage:junit-vintage-engine:5.10.2"},
+	{"org.mockito", "org.mockito:mockito-core:5.12.0"},
+	{"org.slf4j", "org.slf4j:slf4j-api:2.0.13"},
+	{"org.yaml.snakeyaml", "org.yaml:snakeyaml:2.2"},
+}
+
+// well known artifact of a class, by the longest matching package or class
+func wellKnownArtifact(class string) (string, bool) {
+	best := WellKnown{}
+	for _, w := range wellKnown {
+		match := class == w.Prefix ||
+			strings.HasPrefix(class, w.Prefix+".")
+		if match && len(w.Prefix) > len(best.Prefix) {
+			best = w
+		}
+	}
+	return best.Artifact, best.Artifact != ""
+}
+
+// wellKnownProvider suggests artifacts of famous libraries no dependency of
+// the workspace provides yet. They need declaring before the dep resolves.
+type wellKnownProvider struct {
+	workspace string
+}
+
+func (a wellKnownProvider) Lookup(j index.JavaClass) []Suggestion {
+	artifact, ok := wellKnownArtifact(j.Name)
+	if !ok {
+		return nil
+	}
+	label, stanza, es, ok := declare(a.workspace, artifact)
+	if !ok {
+		log.Printf("class %s is in well known %s, which buildozer "+
+			"cannot declare, add %s\n", j.Name, artifact, stanza)
+		return nil
+	}
+	log.Printf("class %s is in well known %s, declare it in %s\n",
+		j.Name, artifact, stanza)
+	return []Suggestion{{Dep: label, Provider: "wellknown",
+		Action: AddArtifact, Confidence: index.Low, Edits: es,
+		Reason: fmt.Sprintf("class %s is in %s, which the workspace "+
+			"does not declare yet", j.Name, artifact),
+		Evidence: []string{stanza}}}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jhinrichsen/bazel-kaizen/index"
+)
+
+func TestWellKnownArtifact(t *testing.T) {
+	for _, tt := range []struct {
+		class, want string
+	}{
+		{"org.junit.Test", "junit:junit:4.13.2"},
+		{"org.junit.jupiter.api.Test",
+			"org.junit.jupiter:junit-jupiter-api:5.10.2"},
+		{"com.google.common.collect.ImmutableList",
+			"com.google.guava:guava:33.2.1-jre"},
+		{"org.slf4j.Logger", "org.slf4j:slf4j-api:2.0.13"},
+		{"org.junit.platform.suite.api.Suite",
+			"org.junit.platform:junit-platform-suite-api:1.10.2"},
+		{"org.junit.platform.launcher.Launcher",
+			"org.junit.platform:junit-platform-launcher:1.10.2"},
+		{"org.junit.jupiter.engine.JupiterTestEngine",
+			"org.junit.jupiter:junit-jupiter-engine:5.10.2"},
+		{"org.junit.vintage.engine.VintageTestEngine",
+			"org.junit.vintage:junit-vintage-engine:5.10.2"},
+		// no single artifact
+		{"org.junit.jupiter.migrationsupport.rules." +
+			"ExternalResourceSupport", ""},
+		{"org.junit.platform.reporting.legacy.LegacyReportingUtils",
+			""},
+		{"javax.annotation.Nonnull",
+			"com.google.code.findbugs:jsr305:3.0.2"},
+		{"javax.annotation.Generated",
+			"javax.annotation:javax.annotation-api:1.3.2"},
+		{"javax.annotation.PostConstruct",
+			"javax.annotation:javax.annotation-api:1.3.2"},
+		{"javax.annotation.Resource",
+			"javax.annotation:javax.annotation-api:1.3.2"},
+		{"javax.annotation.Resources",
+			"javax.annotation:javax.annotation-api:1.3.2"},
+		{"javax.annotation.processing.Processor", ""},
+		// packages match whole segments
+		{"org.slf4jx.Logger", ""},
+		{"com.company.A", ""},
+	} {
+		got, _ := wellKnownArtifact(tt.class)
+		if tt.want != got {
+			t.Fatalf("%s: want %q but got %q\n", tt.class, tt.want, got)
+		}
+	}
+}
+
+func TestWellKnownProvider(t *testing.T) {
+	ws := t.TempDir()
+	fixtureFiles(t, ws, map[string]string{"WORKSPACE": "maven_install(\n"})
+	ss := wellKnownProvider{ws}.Lookup(
+		index.JavaClass{Name: "org.slf4j.Logger"})
+	if len(ss) != 1 || ss[0].Dep != "@maven//:org_slf4j_slf4j_api" ||
+		ss[0].Action != AddArtifact || ss[0].Confidence != index.Low ||
+		len(ss[0].Edits) != 1 {
+		t.Fatalf("want @maven//:org_slf4j_slf4j_api but got %+v\n", ss)
+	}
+	if ss := (wellKnownProvider{ws}).Lookup(
+		index.JavaClass{Name: "com.company.A"}); len(ss) != 0 {
+		t.Fatalf("want no suggestion but got %+v\n", ss)
+	}
+	// maven_jar is gone, maven_install is up to the user
+	fixtureFiles(t, ws, map[string]string{"WORKSPACE": ""})
+	if ss := (wellKnownProvider{ws}).Lookup(
+		index.JavaClass{Name: "org.slf4j.Logger"}); len(ss) != 0 {
+		t.Fatalf("want no suggestion but got %+v\n", ss)
+	}
+}